@@ -0,0 +1,131 @@
+// Package analyzer provides go/analysis analyzers for code using rlog and log/slog.
+//
+// Analyzer reports string keys in log/slog calls which are spelled like a declared rlog.Key,
+// so the typed key is used instead, and keys declared twice with different value types.
+// CheckAnalyzer reports common mistakes in log/slog calls.
+//
+// It lives in its own module to keep golang.org/x/tools out of the rlog dependencies.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"sort"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const (
+	rlogPath = "github.com/wytools/rlog"
+	slogPath = "log/slog"
+)
+
+// Analyzer checks the use of rlog typed keys.
+var Analyzer = &analysis.Analyzer{
+	Name:      "rlogkey",
+	Doc:       "check that declared rlog.Key names are not logged as plain strings",
+	Requires:  []*analysis.Analyzer{inspect.Analyzer},
+	Run:       run,
+	FactTypes: []analysis.Fact{new(keysFact)},
+}
+
+// keysFact holds the rlog.Key names declared by a package, with the type of their values.
+type keysFact struct {
+	Keys map[string]string
+}
+
+func (*keysFact) AFact() {}
+
+func (f *keysFact) String() string {
+	names := make([]string, 0, len(f.Keys))
+	for k := range f.Keys {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return fmt.Sprint("rlog keys ", names)
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// keys declared by this package
+	declared := map[string]string{}
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		name, typ, ok := keyConversion(pass, call)
+		if !ok {
+			return
+		}
+		if prev, ok := declared[name]; ok && prev != typ {
+			pass.Reportf(call.Pos(), "rlog key %q is declared with value types %s and %s", name, prev, typ)
+			return
+		}
+		declared[name] = typ
+	})
+	if len(declared) > 0 {
+		pass.ExportPackageFact(&keysFact{Keys: declared})
+	}
+
+	// keys declared by this package and its dependencies
+	known := map[string]string{}
+	for _, pf := range pass.AllPackageFacts() {
+		if f, ok := pf.Fact.(*keysFact); ok {
+			for k, v := range f.Keys {
+				known[k] = v
+			}
+		}
+	}
+	for k, v := range declared {
+		known[k] = v
+	}
+	if len(known) == 0 {
+		return nil, nil
+	}
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		for _, key := range slogKeys(pass.TypesInfo, call) {
+			name, ok := constString(pass.TypesInfo, key)
+			if !ok {
+				continue
+			}
+			if typ, ok := known[name]; ok {
+				pass.Reportf(key.Pos(), "use the rlog.Key[%s] declared for %q instead of the string key", typ, name)
+			}
+		}
+	})
+	return nil, nil
+}
+
+// keyConversion reports whether call is a conversion rlog.Key[T]("name"), returning the name and T.
+func keyConversion(pass *analysis.Pass, call *ast.CallExpr) (string, string, bool) {
+	if len(call.Args) != 1 {
+		return "", "", false
+	}
+	tv, ok := pass.TypesInfo.Types[call.Fun]
+	if !ok || !tv.IsType() {
+		return "", "", false
+	}
+	named, ok := tv.Type.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != rlogPath || named.Obj().Name() != "Key" {
+		return "", "", false
+	}
+	name, ok := constString(pass.TypesInfo, call.Args[0])
+	if !ok || named.TypeArgs().Len() != 1 {
+		return "", "", false
+	}
+	return name, types.TypeString(named.TypeArgs().At(0), types.RelativeTo(pass.Pkg)), true
+}
+
+// constString returns the value of e if it is a constant string.
+func constString(info *types.Info, e ast.Expr) (string, bool) {
+	tv, ok := info.Types[e]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
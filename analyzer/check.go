@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// CheckAnalyzer reports common mistakes in log/slog calls:
+//   - a key without a value, which slog logs as !BADKEY
+//   - a key which is not a string or not a constant
+//   - debug logging in a loop without an Enabled guard, which pays for the arguments even when disabled
+//   - keys listed in the -forbidden flag, such as password
+var CheckAnalyzer = &analysis.Analyzer{
+	Name:     "rlogcheck",
+	Doc:      "check for common mistakes in log/slog calls",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      runCheck,
+}
+
+var forbiddenKeys string
+
+func init() {
+	CheckAnalyzer.Flags.StringVar(&forbiddenKeys, "forbidden", "", "comma-separated list of keys which must not be logged")
+}
+
+func runCheck(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	forbidden := map[string]bool{}
+	for _, k := range strings.Split(forbiddenKeys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			forbidden[k] = true
+		}
+	}
+
+	insp.WithStack([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		call := n.(*ast.CallExpr)
+		if pairs, ok := slogPairs(pass.TypesInfo, call); ok {
+			checkPairs(pass, pairs)
+			if isDebugCall(pass.TypesInfo, call) && len(pairs) > 0 && inLoop(stack) && !guarded(pass.TypesInfo, stack) {
+				pass.Reportf(call.Pos(), "debug logging in a loop without an Enabled guard")
+			}
+		}
+		for _, key := range slogKeys(pass.TypesInfo, call) {
+			if name, ok := constString(pass.TypesInfo, key); ok && forbidden[name] {
+				pass.Reportf(key.Pos(), "key %q must not be logged", name)
+			}
+		}
+		return true
+	})
+	return nil, nil
+}
+
+// checkPairs reports malformed key-value arguments.
+func checkPairs(pass *analysis.Pass, pairs []ast.Expr) {
+	for i := 0; i < len(pairs); {
+		key := pairs[i]
+		if isAttr(pass.TypesInfo, key) {
+			i++
+			continue
+		}
+		t := pass.TypesInfo.TypeOf(key)
+		if b, ok := t.Underlying().(*types.Basic); !ok || b.Info()&types.IsString == 0 {
+			pass.Reportf(key.Pos(), "key of type %s is not a string nor a slog.Attr", t)
+		} else if _, ok := constString(pass.TypesInfo, key); !ok {
+			pass.Reportf(key.Pos(), "key is not a constant")
+		}
+		if i+1 == len(pairs) {
+			pass.Reportf(key.Pos(), "key without a value, there is an odd number of key-value arguments")
+		}
+		i += 2
+	}
+}
+
+// isDebugCall reports whether call logs at the debug level.
+func isDebugCall(info *types.Info, call *ast.CallExpr) bool {
+	fn := slogFunc(info, call)
+	switch fn.Name() {
+	case "Debug", "DebugContext":
+		return true
+	case "Log":
+		if len(call.Args) > 1 {
+			if sel, ok := ast.Unparen(call.Args[1]).(*ast.SelectorExpr); ok {
+				return sel.Sel.Name == "LevelDebug"
+			}
+		}
+	}
+	return false
+}
+
+// inLoop reports whether the innermost function of the stack contains the node in a loop.
+func inLoop(stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch stack[i].(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			return true
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		}
+	}
+	return false
+}
+
+// guarded reports whether the node is inside an if statement whose condition calls Enabled.
+func guarded(info *types.Info, stack []ast.Node) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		switch s := stack[i].(type) {
+		case *ast.IfStmt:
+			found := false
+			ast.Inspect(s.Cond, func(n ast.Node) bool {
+				if c, ok := n.(*ast.CallExpr); ok {
+					if sel, ok := c.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Enabled" {
+						found = true
+					}
+				}
+				return !found
+			})
+			if found {
+				return true
+			}
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		}
+	}
+	return false
+}
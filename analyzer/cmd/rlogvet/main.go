@@ -0,0 +1,13 @@
+// Command rlogvet runs the rlog analyzers, standalone or through go vet:
+//
+//	go vet -vettool=$(which rlogvet) ./...
+package main
+
+import (
+	"github.com/wytools/rlog/analyzer"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	multichecker.Main(analyzer.Analyzer, analyzer.CheckAnalyzer)
+}
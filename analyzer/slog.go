@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// kvFuncs maps the log/slog functions and methods taking key-value pairs to the index of the
+// first pair argument.
+var kvFuncs = map[string]int{
+	"Debug":        1,
+	"Info":         1,
+	"Warn":         1,
+	"Error":        1,
+	"DebugContext": 2,
+	"InfoContext":  2,
+	"WarnContext":  2,
+	"ErrorContext": 2,
+	"Log":          3,
+	"With":         0,
+	"Group":        1,
+}
+
+// attrFuncs are the log/slog Attr constructors, which take the key as the first argument.
+var attrFuncs = map[string]bool{
+	"String":   true,
+	"Int":      true,
+	"Int64":    true,
+	"Uint64":   true,
+	"Float64":  true,
+	"Bool":     true,
+	"Time":     true,
+	"Duration": true,
+	"Any":      true,
+	"Group":    true,
+}
+
+// slogFunc returns the called log/slog function or *slog.Logger method, or nil.
+func slogFunc(info *types.Info, call *ast.CallExpr) *types.Func {
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != slogPath {
+		return nil
+	}
+	return fn
+}
+
+// slogPairs returns the key-value arguments of a log/slog call, and false if call isn't one.
+// A call with a spread slice (args...) has no visible pairs.
+func slogPairs(info *types.Info, call *ast.CallExpr) ([]ast.Expr, bool) {
+	fn := slogFunc(info, call)
+	if fn == nil {
+		return nil, false
+	}
+	first, ok := kvFuncs[fn.Name()]
+	if !ok || call.Ellipsis.IsValid() || len(call.Args) < first {
+		return nil, false
+	}
+	return call.Args[first:], true
+}
+
+// isAttr reports whether e is a slog.Attr, which takes a single argument in the pairs.
+func isAttr(info *types.Info, e ast.Expr) bool {
+	t := info.TypeOf(e)
+	if t == nil {
+		return false
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == slogPath && named.Obj().Name() == "Attr"
+}
+
+// slogKeys returns the key expressions of a log/slog call: the keys of its key-value pairs,
+// or the key of an Attr constructor.
+func slogKeys(info *types.Info, call *ast.CallExpr) []ast.Expr {
+	var keys []ast.Expr
+	if fn := slogFunc(info, call); fn != nil && attrFuncs[fn.Name()] && fn.Type().(*types.Signature).Recv() == nil && len(call.Args) > 0 {
+		keys = append(keys, call.Args[0])
+	}
+	pairs, ok := slogPairs(info, call)
+	if !ok {
+		return keys
+	}
+	for i := 0; i < len(pairs); {
+		if isAttr(info, pairs[i]) {
+			i++
+			continue
+		}
+		keys = append(keys, pairs[i])
+		i += 2
+	}
+	return keys
+}
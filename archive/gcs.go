@@ -0,0 +1,55 @@
+package archive
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/wytools/rlog/rotation"
+)
+
+var _ rotation.Archiver = (*GCS)(nil)
+
+// GCS uploads log files to a Google Cloud Storage bucket with the JSON API.
+type GCS struct {
+	Bucket string // the bucket name
+	Prefix string // prepended to the file base names to make the object names, e.g. "logs/host1/"
+
+	// Token returns an OAuth2 access token for the requests, e.g. from the metadata server
+	// or golang.org/x/oauth2/google. No Authorization header is sent if nil.
+	Token func(ctx context.Context) (string, error)
+
+	Client *http.Client // http.DefaultClient if nil
+}
+
+// Archive implements rotation.Archiver.
+func (g *GCS) Archive(ctx context.Context, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fInfo, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	u := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(g.Bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(g.Prefix+filepath.Base(filename))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = fInfo.Size()
+	req.Header.Set("Content-Type", "text/plain")
+	if g.Token != nil {
+		token, err := g.Token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return do(g.Client, req)
+}
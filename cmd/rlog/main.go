@@ -0,0 +1,213 @@
+// Command rlog provides maintenance tools for the log files written by rlog.
+//
+// Usage:
+//
+//	rlog repair [-trim] [-quarantine] file...
+//	rlog parquet file...
+//	rlog erase [-hash -keyfile f] -subject id file...
+//	rlog verify [-keyfile f] file...
+//
+// repair reports the truncated final records left by crashes. With -trim they are removed,
+// with -quarantine they are moved to file.torn.
+//
+// parquet converts the JSON log files to Parquet files named after them, such as
+// app.log to app.parquet, inferring the columns from the records. The .gz files are read
+// uncompressed.
+//
+// erase removes the records mentioning the subject id from completed log files, for a data
+// deletion request. With -hash the id is replaced by its HMAC keyed by the key read from the
+// -keyfile file instead.
+//
+// verify checks the chain of the log files written with rotation.WithAuditChain, by the key
+// read from the -keyfile file, or without a key. The .gz files are read uncompressed.
+//
+// The .zst files can't be read, they fail with an error asking to decompress them by zstd -d
+// first.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wytools/rlog/parquet"
+	"github.com/wytools/rlog/rotation"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "repair":
+		err = repair(os.Args[2:])
+	case "parquet":
+		err = toParquet(os.Args[2:])
+	case "erase":
+		err = erase(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: rlog repair [-trim] [-quarantine] file...")
+	fmt.Fprintln(os.Stderr, "       rlog parquet file...")
+	fmt.Fprintln(os.Stderr, "       rlog erase [-hash -keyfile f] -subject id file...")
+	fmt.Fprintln(os.Stderr, "       rlog verify [-keyfile f] file...")
+	os.Exit(2)
+}
+
+func repair(args []string) error {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	trim := fs.Bool("trim", false, "remove the truncated final records")
+	quarantine := fs.Bool("quarantine", false, "move the truncated final records to file.torn")
+	fs.Parse(args)
+
+	for _, fn := range fs.Args() {
+		var t *rotation.TornRecord
+		var err error
+		if *trim || *quarantine {
+			t, err = rotation.RepairTornRecord(fn, *quarantine)
+		} else {
+			t, err = rotation.FindTornRecord(fn)
+		}
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			fmt.Printf("%s: ok\n", fn)
+			continue
+		}
+		action := "found"
+		if *quarantine {
+			action = "quarantined"
+		} else if *trim {
+			action = "trimmed"
+		}
+		fmt.Printf("%s: %s truncated record of %d bytes at offset %d\n", fn, action, t.Size, t.Offset)
+	}
+	return nil
+}
+
+func toParquet(args []string) error {
+	fs := flag.NewFlagSet("parquet", flag.ExitOnError)
+	fs.Parse(args)
+
+	for _, fn := range fs.Args() {
+		name := strings.TrimSuffix(fn, ".gz")
+		out := strings.TrimSuffix(name, filepath.Ext(name)) + ".parquet"
+		n, err := convertParquet(fn, out)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d records written to %s\n", fn, n, out)
+	}
+	return nil
+}
+
+func convertParquet(fn, out string) (int, error) {
+	in, err := rotation.OpenLogFile(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	f, err := os.Create(out)
+	if err != nil {
+		return 0, err
+	}
+	n, err := parquet.Export(f, in, nil)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+func erase(args []string) error {
+	fs := flag.NewFlagSet("erase", flag.ExitOnError)
+	subject := fs.String("subject", "", "the identifier of the subject to erase")
+	hash := fs.Bool("hash", false, "replace the subject by its hash instead of removing the records")
+	keyfile := fs.String("keyfile", "", "the file of the key of the hashes")
+	fs.Parse(args)
+	if *subject == "" || *hash && *keyfile == "" {
+		usage()
+	}
+	key, err := readKey(*keyfile)
+	if err != nil {
+		return err
+	}
+
+	mode := rotation.EraseRecords
+	if *hash {
+		mode = rotation.HashSubject
+	}
+	for _, fn := range fs.Args() {
+		n, err := rotation.EraseSubject(fn, *subject, mode, key)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d records\n", fn, n)
+	}
+	return nil
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	keyfile := fs.String("keyfile", "", "the file of the key of the chain")
+	fs.Parse(args)
+
+	key, err := readKey(*keyfile)
+	if err != nil {
+		return err
+	}
+	broken := 0
+	for _, fn := range fs.Args() {
+		n, err := verifyChain(fn, key)
+		var ce *rotation.ChainError
+		switch {
+		case errors.As(err, &ce):
+			fmt.Printf("%s: %v\n", fn, err)
+			broken++
+		case err != nil:
+			return err
+		default:
+			fmt.Printf("%s: ok (%d lines)\n", fn, n)
+		}
+	}
+	if broken > 0 {
+		return fmt.Errorf("%d of %d files broken", broken, len(fs.Args()))
+	}
+	return nil
+}
+
+// readKey reads the key of the keyfile, without its final newline, nil if keyfile is empty.
+func readKey(keyfile string) ([]byte, error) {
+	if keyfile == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(keyfile)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b, "\r\n"), nil
+}
+
+func verifyChain(fn string, key []byte) (int, error) {
+	r, err := rotation.OpenLogFile(fn)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return rotation.VerifyChain(r, key)
+}
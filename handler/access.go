@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The attr keys read by the AccessHandler.
+const (
+	AccessRemoteKey  = "remote"
+	AccessUserKey    = "user"
+	AccessMethodKey  = "method"
+	AccessPathKey    = "path"
+	AccessProtoKey   = "proto"
+	AccessStatusKey  = "status"
+	AccessBytesKey   = "bytes"
+	AccessRefererKey = "referer"
+	AccessUAKey      = "ua"
+)
+
+// AccessHandler writes every record in the Apache/Nginx Combined Log Format:
+//
+//	remote - user [10/Oct/2000:13:55:36 -0700] "GET /path HTTP/1.1" 200 2326 "referer" "ua"
+//
+// The fields are taken from the attrs with the Access*Key names, missing fields and zero bytes
+// are written as "-". As Apache does, the quotes and the backslashes of the fields are escaped by
+// a backslash, and the control bytes as \xhh, so a request can't forge a line or a field.
+// Groups are ignored, the message of the record is not written.
+type AccessHandler struct {
+	level slog.Leveler
+	attrs []slog.Attr
+	mu    *sync.Mutex
+	w     io.Writer
+}
+
+// NewAccessHandler creates an AccessHandler writing to w, usually a rotation.Logger.
+func NewAccessHandler(w io.Writer, opts *slog.HandlerOptions) *AccessHandler {
+	h := &AccessHandler{
+		w:  w,
+		mu: &sync.Mutex{},
+	}
+	if opts != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+func (h *AccessHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.level != nil {
+		minLevel = h.level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *AccessHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make(map[string]string, 9)
+	add := func(a slog.Attr) bool {
+		fields[a.Key] = a.Value.Resolve().String()
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+
+	buf := NewBuffer()
+	defer buf.Free()
+	field := func(key string) {
+		v := fields[key]
+		if v == "" || key == AccessBytesKey && v == "0" {
+			buf.WriteByte('-')
+			return
+		}
+		appendAccessEscaped(buf, v)
+	}
+	field(AccessRemoteKey)
+	buf.WriteString(" - ")
+	field(AccessUserKey)
+	buf.WriteString(" [")
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	*buf = t.AppendFormat(*buf, "02/Jan/2006:15:04:05 -0700")
+	buf.WriteString("] \"")
+	field(AccessMethodKey)
+	buf.WriteByte(' ')
+	field(AccessPathKey)
+	buf.WriteByte(' ')
+	field(AccessProtoKey)
+	buf.WriteString("\" ")
+	field(AccessStatusKey)
+	buf.WriteByte(' ')
+	field(AccessBytesKey)
+	buf.WriteString(" \"")
+	field(AccessRefererKey)
+	buf.WriteString("\" \"")
+	field(AccessUAKey)
+	buf.WriteString("\"\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(*buf)
+	return err
+}
+
+// appendAccessEscaped appends the field v to buf, escaped as the fields of Apache.
+func appendAccessEscaped(buf *Buffer, v string) {
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < 0x20 || c == 0x7f:
+			buf.WriteString(`\x`)
+			buf.WriteByte(hex[c>>4])
+			buf.WriteByte(hex[c&0xF])
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func (h *AccessHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append(slices.Clip(h.attrs), as...)
+	return &h2
+}
+
+func (h *AccessHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// AccessLog is an http middleware which logs every request to logger with the attr keys
+// understood by the AccessHandler. The remote is the address of the client without its port,
+// and the status is the first one sent, or 200 if the body was written first.
+func AccessLog(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rw := &accessResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, req)
+
+		user := "-"
+		if req.URL.User != nil {
+			user = req.URL.User.Username()
+		} else if u, _, ok := req.BasicAuth(); ok {
+			user = u
+		}
+		remote := req.RemoteAddr
+		if host, _, err := net.SplitHostPort(remote); err == nil {
+			remote = host
+		}
+		logger.LogAttrs(req.Context(), slog.LevelInfo, "access",
+			slog.String(AccessRemoteKey, remote),
+			slog.String(AccessUserKey, user),
+			slog.String(AccessMethodKey, req.Method),
+			slog.String(AccessPathKey, req.RequestURI),
+			slog.String(AccessProtoKey, req.Proto),
+			slog.String(AccessStatusKey, strconv.Itoa(rw.status)),
+			slog.String(AccessBytesKey, strconv.FormatInt(rw.bytes, 10)),
+			slog.String(AccessRefererKey, req.Referer()),
+			slog.String(AccessUAKey, req.UserAgent()),
+		)
+	})
+}
+
+// accessResponseWriter records the status and the body size of a response.
+type accessResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool // the status is sent, the later ones are superfluous
+	bytes       int64
+}
+
+func (w *accessResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		// the informational statuses are followed by the final one
+		w.wroteHeader = status < 100 || status >= 200 || status == http.StatusSwitchingProtocols
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = http.StatusOK, true
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped ResponseWriter, so http.ResponseController reaches its Flush,
+// Hijack and deadlines.
+func (w *accessResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
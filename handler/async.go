@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AsyncPolicy decides what an AsyncHandler does with a record when its buffer is full.
+type AsyncPolicy int
+
+const (
+	AsyncDropOldest AsyncPolicy = iota // drop the oldest buffered record to make room
+	AsyncDropNewest                    // drop the new record
+	AsyncBlock                         // wait for room, Handle blocks as a synchronous handler
+)
+
+// AsyncOptions configures an AsyncHandler.
+type AsyncOptions struct {
+	Size   int         // the max number of buffered records, 1024 if 0
+	Policy AsyncPolicy // what to do when the buffer is full
+
+	// ReportInterval is the min interval between the warnings logged to the inner handler with
+	// the number of records dropped since the last one, 10s if 0, no warnings if negative.
+	ReportInterval time.Duration
+}
+
+// AsyncHandler passes the records to the inner handler from a worker goroutine, so Handle
+// never waits for the disk: the records are put in a bounded buffer, and written in order by
+// the worker. When the buffer is full, the records are dropped or Handle blocks, as set by
+// the policy, and the drops are counted and reported by a warning record.
+//
+// As BatchHandler, it encodes the records at once if the inner handler is a DefaultHandler.
+// The errors of the inner handler are returned by the next Handle. Handlers derived by
+// WithAttrs and WithGroup share the buffer, Close writes the buffered records.
+type AsyncHandler struct {
+	inner slog.Handler
+	q     *asyncQueue
+}
+
+type asyncQueue struct {
+	inner  slog.Handler // the handler of the drop reports
+	policy AsyncPolicy
+	every  time.Duration
+
+	mu         sync.Mutex
+	notEmpty   sync.Cond // signaled when a record is buffered, a report is due or closed
+	notFull    sync.Cond // signaled when the worker took the buffered records
+	idle       sync.Cond // signaled when the worker wrote the records it took
+	ring       []batchRecord
+	head, n    int
+	busy       bool   // the worker is writing
+	unreported uint64 // the records dropped since the last report
+	lastReport time.Time
+	err        error // the error of the last write by the worker, returned by the next Handle
+	closed     bool
+	done       chan struct{}
+
+	dropped atomic.Uint64
+}
+
+// NewAsyncHandler creates an AsyncHandler passing the records to inner, and starts its worker.
+// It's registered to be closed by Shutdown.
+func NewAsyncHandler(inner slog.Handler, opts AsyncOptions) *AsyncHandler {
+	if opts.Size <= 0 {
+		opts.Size = 1024
+	}
+	if opts.ReportInterval == 0 {
+		opts.ReportInterval = 10 * time.Second
+	}
+	q := &asyncQueue{
+		inner:      inner,
+		policy:     opts.Policy,
+		every:      opts.ReportInterval,
+		ring:       make([]batchRecord, opts.Size),
+		lastReport: time.Now(),
+		done:       make(chan struct{}),
+	}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+	q.idle.L = &q.mu
+	go q.loop()
+	OnShutdown(q)
+	return &AsyncHandler{inner: inner, q: q}
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	br := batchRecord{h: h.inner}
+	if dh, ok := h.inner.(*DefaultHandler); ok {
+		br.buf = dh.encode(r)
+	} else {
+		br.ctx, br.r = ctx, r.Clone()
+	}
+
+	q := h.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		if br.buf != nil {
+			br.buf.Free()
+		}
+		return errors.Join(q.takeErr(), h.inner.Handle(ctx, r))
+	}
+	for q.n == len(q.ring) {
+		switch q.policy {
+		case AsyncDropOldest:
+			q.drop(q.ring[q.head])
+			q.ring[q.head] = batchRecord{}
+			q.head = (q.head + 1) % len(q.ring)
+			q.n--
+		case AsyncDropNewest:
+			q.drop(br)
+			return q.takeErr()
+		default:
+			q.notFull.Wait()
+			if q.closed {
+				if br.buf != nil {
+					br.buf.Free()
+				}
+				return errors.Join(q.takeErr(), h.inner.Handle(ctx, r))
+			}
+		}
+	}
+	q.ring[(q.head+q.n)%len(q.ring)] = br
+	q.n++
+	q.notEmpty.Signal()
+	return q.takeErr()
+}
+
+func (h *AsyncHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &AsyncHandler{inner: h.inner.WithAttrs(as), q: h.q}
+}
+
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{inner: h.inner.WithGroup(name), q: h.q}
+}
+
+// Dropped returns the number of records dropped since the handler was created.
+func (h *AsyncHandler) Dropped() uint64 {
+	return h.q.dropped.Load()
+}
+
+// Flush waits until the records buffered before the call are written, and returns the error
+// of the last write.
+func (h *AsyncHandler) Flush() error {
+	q := h.q
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for (q.n > 0 || q.busy) && !q.closed {
+		q.idle.Wait()
+	}
+	return q.takeErr()
+}
+
+// Close writes the buffered records and the report of the last drops, and stops the worker.
+// The records handled after Close are passed to the inner handler synchronously.
+func (h *AsyncHandler) Close() error {
+	return h.q.Close()
+}
+
+// Close closes the queue of the handlers, and unregisters it from Shutdown.
+func (q *asyncQueue) Close() error {
+	Unregister(q)
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.notEmpty.Signal()
+	q.notFull.Broadcast()
+	q.idle.Broadcast()
+	q.mu.Unlock()
+
+	<-q.done
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.takeErr()
+}
+
+// drop counts the dropped record br and releases it. It's called with q.mu held.
+func (q *asyncQueue) drop(br batchRecord) {
+	if br.buf != nil {
+		br.buf.Free()
+	}
+	q.dropped.Add(1)
+	if q.unreported == 0 && q.every > 0 {
+		// wake the worker when the report is due, it may be idle then
+		time.AfterFunc(time.Until(q.lastReport.Add(q.every)), func() {
+			q.mu.Lock()
+			q.notEmpty.Signal()
+			q.mu.Unlock()
+		})
+	}
+	q.unreported++
+}
+
+// reportDue reports whether the drops must be reported. It's called with q.mu held.
+func (q *asyncQueue) reportDue() bool {
+	return q.unreported > 0 && q.every > 0 && (q.closed || time.Since(q.lastReport) >= q.every)
+}
+
+func (q *asyncQueue) loop() {
+	defer close(q.done)
+	var records []batchRecord
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		for q.n == 0 && !q.closed && !q.reportDue() {
+			q.notEmpty.Wait()
+		}
+		if q.n == 0 && q.closed && !q.reportDue() {
+			return
+		}
+		records = records[:0]
+		for ; q.n > 0; q.n-- {
+			records = append(records, q.ring[q.head])
+			q.ring[q.head] = batchRecord{}
+			q.head = (q.head + 1) % len(q.ring)
+		}
+		var dropped uint64
+		if q.reportDue() {
+			dropped, q.unreported, q.lastReport = q.unreported, 0, time.Now()
+		}
+		q.busy = true
+		q.notFull.Broadcast()
+		q.mu.Unlock()
+
+		err := q.write(records, dropped)
+		clear(records) // release the records for GC
+
+		q.mu.Lock()
+		q.busy = false
+		if err != nil {
+			q.err = err
+		}
+		q.idle.Broadcast()
+	}
+}
+
+// write passes the records to their handlers, then reports the dropped records if any.
+func (q *asyncQueue) write(records []batchRecord, dropped uint64) error {
+	var errs []error
+	for _, br := range records {
+		var err error
+		if br.buf != nil {
+			err = br.h.(*DefaultHandler).write(*br.buf)
+			br.buf.Free()
+		} else {
+			err = br.h.Handle(br.ctx, br.r)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if dropped > 0 && q.inner.Enabled(context.Background(), slog.LevelWarn) {
+		r := slog.NewRecord(time.Now(), slog.LevelWarn, "rlog: dropped log records", 0)
+		r.AddAttrs(slog.Uint64("dropped", dropped))
+		if err := q.inner.Handle(context.Background(), r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (q *asyncQueue) takeErr() error {
+	err := q.err
+	q.err = nil
+	return err
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// BatchHandler keeps records in memory and passes them to the inner handler in batches,
+// when size records are kept, when interval has passed since the last flush, or when
+// Flush is called. The interval is checked when a record arrives, there is no timer.
+//
+// It is meant to be owned by a single goroutine running a hot loop, so it takes no lock.
+// Handlers derived by WithAttrs and WithGroup share the batch of their parent, and must be
+// used by the same goroutine. Don't forget to Flush before the goroutine ends.
+type BatchHandler struct {
+	inner slog.Handler
+	b     *batch
+}
+
+type batch struct {
+	records  []batchRecord
+	size     int
+	interval time.Duration
+	last     time.Time
+}
+
+type batchRecord struct {
+	h   slog.Handler
+	ctx context.Context
+	r   slog.Record
+	buf *Buffer // the record already encoded by a DefaultHandler, r is unset then
+}
+
+// NewBatchHandler creates a BatchHandler flushing to inner.
+func NewBatchHandler(inner slog.Handler, size int, interval time.Duration) *BatchHandler {
+	if size < 1 {
+		size = 1
+	}
+	return &BatchHandler{
+		inner: inner,
+		b: &batch{
+			records:  make([]batchRecord, 0, size),
+			size:     size,
+			interval: interval,
+			last:     time.Now(),
+		},
+	}
+}
+
+func (h *BatchHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+// Handle keeps r until the next flush. If the inner handler is a DefaultHandler, r is encoded
+// at once and only its bytes are kept, saving the Clone of r.
+func (h *BatchHandler) Handle(ctx context.Context, r slog.Record) error {
+	br := batchRecord{h: h.inner}
+	if dh, ok := h.inner.(*DefaultHandler); ok {
+		br.buf = dh.encode(r)
+	} else {
+		br.ctx, br.r = ctx, r.Clone()
+	}
+	h.b.records = append(h.b.records, br)
+	if len(h.b.records) >= h.b.size || (h.b.interval > 0 && time.Since(h.b.last) >= h.b.interval) {
+		return h.Flush()
+	}
+	return nil
+}
+
+// Flush passes all the kept records to the inner handler.
+func (h *BatchHandler) Flush() error {
+	var errs []error
+	for i, br := range h.b.records {
+		var err error
+		if br.buf != nil {
+			err = br.h.(*DefaultHandler).write(*br.buf)
+			br.buf.Free()
+		} else {
+			err = br.h.Handle(br.ctx, br.r)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		h.b.records[i] = batchRecord{} // release the record for GC
+	}
+	h.b.records = h.b.records[:0]
+	h.b.last = time.Now()
+	return errors.Join(errs...)
+}
+
+func (h *BatchHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &BatchHandler{inner: h.inner.WithAttrs(as), b: h.b}
+}
+
+func (h *BatchHandler) WithGroup(name string) slog.Handler {
+	return &BatchHandler{inner: h.inner.WithGroup(name), b: h.b}
+}
@@ -79,6 +79,14 @@ func (b *Buffer) WritePosIntWidth(i, width int) {
 	b.Write(bb[bp:])
 }
 
+func (b *Buffer) Len() int {
+	return len(*b)
+}
+
+func (b *Buffer) SetLen(n int) {
+	*b = (*b)[:n]
+}
+
 func (b *Buffer) String() string {
 	return string(*b)
 }
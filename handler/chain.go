@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+)
+
+// HandleFunc handles a record, like slog.Handler.Handle.
+type HandleFunc func(ctx context.Context, r slog.Record) error
+
+// Middleware wraps the handling of the records. It can inspect the record, modify it, after a
+// Clone if it adds attrs, or drop it by returning without calling next.
+type Middleware func(next HandleFunc) HandleFunc
+
+// ChainHandler passes the records through its middlewares before the terminal handler.
+type ChainHandler struct {
+	inner  slog.Handler
+	mw     []Middleware
+	handle HandleFunc // the middlewares composed over inner.Handle
+}
+
+// Chain creates a ChainHandler running the middlewares mw in order, the first one being the
+// outermost, before h. Handlers derived by WithAttrs and WithGroup run the same middlewares.
+func Chain(h slog.Handler, mw ...Middleware) *ChainHandler {
+	c := &ChainHandler{inner: h, mw: mw}
+	c.handle = h.Handle
+	for i := len(mw) - 1; i >= 0; i-- {
+		c.handle = mw[i](c.handle)
+	}
+	return c
+}
+
+func (c *ChainHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return c.inner.Enabled(ctx, l)
+}
+
+func (c *ChainHandler) Handle(ctx context.Context, r slog.Record) error {
+	return c.handle(ctx, r)
+}
+
+func (c *ChainHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return Chain(c.inner.WithAttrs(as), c.mw...)
+}
+
+func (c *ChainHandler) WithGroup(name string) slog.Handler {
+	return Chain(c.inner.WithGroup(name), c.mw...)
+}
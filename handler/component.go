@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ComponentHandler adds the package path of the caller to every record, so records are
+// attributable by component without every logger being created with With("component", ...).
+// The package path is resolved once per call site and cached. A component set explicitly
+// with WithAttrs takes precedence.
+type ComponentHandler struct {
+	inner    slog.Handler
+	key      string
+	trim     string // the prefix trimmed from the package paths, such as the module path
+	explicit bool   // the component was set by WithAttrs
+	cache    *sync.Map
+}
+
+// NewComponentHandler creates a ComponentHandler adding the attr key, "component" if empty,
+// to the records passed to inner. The prefix trim, usually the module path, is removed from
+// the package paths.
+func NewComponentHandler(inner slog.Handler, key, trim string) *ComponentHandler {
+	if key == "" {
+		key = "component"
+	}
+	if trim != "" && !strings.HasSuffix(trim, "/") {
+		trim += "/"
+	}
+	return &ComponentHandler{inner: inner, key: key, trim: trim, cache: &sync.Map{}}
+}
+
+func (h *ComponentHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *ComponentHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.explicit && r.PC != 0 {
+		if c := h.component(r.PC); c != "" {
+			r = r.Clone()
+			r.AddAttrs(slog.String(h.key, c))
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *ComponentHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithAttrs(as)
+	for _, a := range as {
+		if a.Key == h.key {
+			h2.explicit = true
+		}
+	}
+	return &h2
+}
+
+func (h *ComponentHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithGroup(name)
+	return &h2
+}
+
+func (h *ComponentHandler) component(pc uintptr) string {
+	if c, ok := h.cache.Load(pc); ok {
+		return c.(string)
+	}
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	c := strings.TrimPrefix(packagePath(f.Function), h.trim)
+	h.cache.Store(pc, c)
+	return c
+}
+
+// packagePath returns the package path of a function name as reported by runtime.Frame,
+// such as "github.com/x/y/pkg" for "github.com/x/y/pkg.(*T).Method".
+func packagePath(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	dot := strings.IndexByte(function[slash+1:], '.')
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
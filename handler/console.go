@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"unicode/utf8"
+)
+
+// ANSI escape sequences used by the console handler.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+)
+
+// ColorMode decides whether the console handler writes colors.
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = iota // colors if the writer is a terminal and NO_COLOR is not set
+	ColorAlways                  // always colors
+	ColorNever                   // never colors
+)
+
+// Theme customizes the output of the console handler.
+type Theme struct {
+	Color       ColorMode             // when to write colors
+	LevelColors map[slog.Level]string // ANSI sequences for the levels, e.g. "\x1b[31m"
+	LevelIcons  map[slog.Level]string // icons written before the level names, e.g. "❌"
+	TimeFormat  string                // the time layout, empty for the default UTC milliseconds format
+	DimKeys     bool                  // dim the attr keys so the values stand out
+
+	// Aligned lines up the level, logger name and message columns, and wraps the attrs
+	// onto indented continuation lines when a line grows over MaxWidth.
+	Aligned      bool
+	NameKey      string // the attr key of the logger name column, "logger" if empty
+	NameWidth    int    // the width of the logger name column, no column if 0
+	MessageWidth int    // the width of the message column, 40 if 0
+	MaxWidth     int    // the max width of a line before wrapping the attrs, 120 if 0
+}
+
+// DefaultTheme returns the theme used when NewConsoleHandler is given a nil theme.
+func DefaultTheme() *Theme {
+	return &Theme{
+		Color: ColorAuto,
+		LevelColors: map[slog.Level]string{
+			slog.LevelDebug: ansiMagenta,
+			slog.LevelInfo:  ansiGreen,
+			slog.LevelWarn:  ansiYellow,
+			slog.LevelError: ansiRed,
+		},
+		TimeFormat: "15:04:05.000",
+		DimKeys:    true,
+	}
+}
+
+// NewConsoleHandler creates a handler for human readable terminal output, it writes the
+// same text format as the DefaultHandler, styled by theme.
+func NewConsoleHandler(w io.Writer, opts *slog.HandlerOptions, theme *Theme, options ...Option) *DefaultHandler {
+	if theme == nil {
+		theme = DefaultTheme()
+	}
+	h := NewDefaultHandler(w, opts, options...)
+	h.theme = theme
+	switch theme.Color {
+	case ColorAlways:
+		h.color = true
+	case ColorAuto:
+		h.color = isColorTerminal(w)
+	}
+	return h
+}
+
+// isColorTerminal reports whether w is a terminal which should get colors,
+// following the NO_COLOR (https://no-color.org) convention.
+func isColorTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fInfo, err := f.Stat()
+	if err != nil || fInfo.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	return enableVirtualTerminal(f)
+}
+
+// themeStyle returns the style for level l from m, which is the one of the nearest level not above l.
+func themeStyle(m map[slog.Level]string, l slog.Level) string {
+	style, found := "", false
+	var best slog.Level
+	for k, v := range m {
+		if k <= l && (!found || k > best) {
+			style, best, found = v, k, true
+		}
+	}
+	return style
+}
+
+// appendThemedLevel appends the level with the icon and the color of the theme.
+func (s *handleState) appendThemedLevel(l slog.Level) {
+	color := ""
+	if s.h.color {
+		color = themeStyle(s.h.theme.LevelColors, l)
+	}
+	if color != "" {
+		s.buf.WriteString(color)
+	}
+	if icon := themeStyle(s.h.theme.LevelIcons, l); icon != "" {
+		s.buf.WriteString(icon)
+		s.buf.WriteByte(' ')
+	}
+	s.buf.WriteString(l.String())
+	if color != "" {
+		s.buf.WriteString(ansiReset)
+	}
+}
+
+func (t *Theme) nameKey() string {
+	if t.NameKey == "" {
+		return "logger"
+	}
+	return t.NameKey
+}
+
+func (t *Theme) messageWidth() int {
+	if t.MessageWidth <= 0 {
+		return 40
+	}
+	return t.MessageWidth
+}
+
+func (t *Theme) maxWidth() int {
+	if t.MaxWidth <= 0 {
+		return 120
+	}
+	return t.MaxWidth
+}
+
+// isNameAttr reports whether a is the top level attr holding the logger name column.
+func (h *DefaultHandler) isNameAttr(a slog.Attr) bool {
+	t := h.theme
+	return t != nil && t.Aligned && t.NameWidth > 0 && len(h.groups) == 0 && a.Key == t.nameKey()
+}
+
+// alignLevelAndName pads the level column which starts at levelStart, then appends the
+// logger name column, taken from the record or else from the handler.
+func (s *handleState) alignLevelAndName(levelStart int, r slog.Record) {
+	s.padTo(levelStart, len("[ERROR]"))
+	t := s.h.theme
+	if t.NameWidth <= 0 {
+		return
+	}
+	name := s.h.name
+	if len(s.h.groups) == 0 {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == t.nameKey() {
+				name = a.Value.String()
+				return false
+			}
+			return true
+		})
+	}
+	s.buf.WriteByte(' ')
+	nameStart := s.buf.Len()
+	if len(name) > t.NameWidth {
+		name = name[:t.NameWidth]
+	}
+	s.buf.WriteString(name)
+	s.padTo(nameStart, t.NameWidth)
+}
+
+// padTo appends spaces until the column starting at start is width wide.
+func (s *handleState) padTo(start, width int) {
+	for n := visibleWidth((*s.buf)[start:]); n < width; n++ {
+		s.buf.WriteByte(' ')
+	}
+}
+
+// wrapAttrs moves the attrs which don't fit in the max width to continuation lines,
+// indented to the message column starting at msgStart.
+func (s *handleState) wrapAttrs(msgStart int) {
+	if len(s.attrStarts) == 0 || visibleWidth(*s.buf) <= s.h.theme.maxWidth() {
+		return
+	}
+	indent := visibleWidth((*s.buf)[:msgStart])
+	attrs := NewBuffer()
+	defer attrs.Free()
+	attrs.Write((*s.buf)[s.attrStarts[0]:])
+	width := visibleWidth((*s.buf)[:s.attrStarts[0]])
+	s.buf.SetLen(s.attrStarts[0])
+
+	base := s.attrStarts[0]
+	for i, start := range s.attrStarts {
+		end := len(*attrs) + base
+		if i+1 < len(s.attrStarts) {
+			end = s.attrStarts[i+1]
+		}
+		chunk := (*attrs)[start-base : end-base]
+		w := visibleWidth(chunk)
+		if width+w > s.h.theme.maxWidth() && width > indent {
+			s.buf.WriteByte('\n')
+			for j := 0; j < indent; j++ {
+				s.buf.WriteByte(' ')
+			}
+			// the separator is replaced by the indent
+			chunk = chunk[len(s.h.attrSep()):]
+			width = indent
+			w = visibleWidth(chunk)
+		}
+		s.buf.Write(chunk)
+		width += w
+	}
+}
+
+// visibleWidth returns the number of runes in b, excluding ANSI escape sequences.
+func visibleWidth(b []byte) int {
+	n := 0
+	for i := 0; i < len(b); {
+		if b[i] == 0x1b {
+			// skip until the final byte of the sequence
+			for i++; i < len(b) && (b[i] < 0x40 || b[i] > 0x7e || b[i] == '['); i++ {
+			}
+			i++
+			continue
+		}
+		_, size := utf8.DecodeRune(b[i:])
+		i += size
+		n++
+	}
+	return n
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package handler
+
+import "os"
+
+// enableVirtualTerminal reports whether the terminal supports ANSI sequences, they are always
+// supported out of Windows.
+func enableVirtualTerminal(f *os.File) bool {
+	return true
+}
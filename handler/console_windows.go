@@ -0,0 +1,27 @@
+//go:build windows
+
+package handler
+
+import (
+	"os"
+	"syscall"
+)
+
+const enableVirtualTerminalProcessing = 0x0004
+
+var procSetConsoleMode = syscall.NewLazyDLL("kernel32.dll").NewProc("SetConsoleMode")
+
+// enableVirtualTerminal turns on the ANSI sequences support of the Windows console,
+// it reports false if the console doesn't support them.
+func enableVirtualTerminal(f *os.File) bool {
+	var mode uint32
+	h := syscall.Handle(f.Fd())
+	if err := syscall.GetConsoleMode(h, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	r, _, _ := procSetConsoleMode.Call(uintptr(h), uintptr(mode|enableVirtualTerminalProcessing))
+	return r != 0
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// DockerWriter wraps an io.Writer and converts every line written to it into
+// Docker's json-file log format: {"log":"...\n","stream":"stdout","time":"..."}
+type DockerWriter struct {
+	w      io.Writer
+	stream string
+	mu     sync.Mutex
+}
+
+// NewDockerWriter returns a DockerWriter writing to w, stream is usually "stdout" or "stderr".
+func NewDockerWriter(w io.Writer, stream string) *DockerWriter {
+	return &DockerWriter{w: w, stream: stream}
+}
+
+type dockerEntry struct {
+	Log    string `json:"log"`
+	Stream string `json:"stream"`
+	Time   string `json:"time"`
+}
+
+// Write implements io.Writer.
+func (d *DockerWriter) Write(p []byte) (int, error) {
+	buf := NewBuffer()
+	defer buf.Free()
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, line := range splitLines(p) {
+		data, err := json.Marshal(dockerEntry{Log: string(line), Stream: d.stream, Time: now})
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, err := d.w.Write(*buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// splitLines splits p into lines, every line keeps its trailing newline.
+func splitLines(p []byte) [][]byte {
+	var lines [][]byte
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			lines = append(lines, p)
+			break
+		}
+		lines = append(lines, p[:i+1])
+		p = p[i+1:]
+	}
+	return lines
+}
+
+// CRIWriter wraps an io.Writer and converts every line written to it into the
+// Kubernetes CRI log format: "<RFC3339Nano time> <stream> <F|P> <message>".
+// A line without a trailing newline is tagged P (partial).
+type CRIWriter struct {
+	w      io.Writer
+	stream string
+	mu     sync.Mutex
+}
+
+// NewCRIWriter returns a CRIWriter writing to w, stream is usually "stdout" or "stderr".
+func NewCRIWriter(w io.Writer, stream string) *CRIWriter {
+	return &CRIWriter{w: w, stream: stream}
+}
+
+// Write implements io.Writer.
+func (c *CRIWriter) Write(p []byte) (int, error) {
+	buf := NewBuffer()
+	defer buf.Free()
+	now := time.Now().UTC()
+	for _, line := range splitLines(p) {
+		*buf = now.AppendFormat(*buf, time.RFC3339Nano)
+		buf.WriteByte(' ')
+		buf.WriteString(c.stream)
+		if line[len(line)-1] == '\n' {
+			buf.WriteString(" F ")
+			buf.Write(line)
+		} else {
+			buf.WriteString(" P ")
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := c.w.Write(*buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ContextExtractor returns the attrs to add to a record from its context, such as a request
+// ID or the IDs of an OpenTelemetry span.
+type ContextExtractor func(ctx context.Context) []slog.Attr
+
+// ContextHandler adds the attrs extracted from the context of every record, so values set
+// once per request don't have to be passed to every log call. The context of the record is
+// the one passed to the *Context methods of slog.Logger, such as InfoContext.
+type ContextHandler struct {
+	inner      slog.Handler
+	extractors []ContextExtractor
+}
+
+// NewContextHandler creates a ContextHandler passing the records to inner. The attrs set by
+// ContextWithAttrs are always extracted, before the ones of the extractors.
+func NewContextHandler(inner slog.Handler, extractors ...ContextExtractor) *ContextHandler {
+	return &ContextHandler{inner: inner, extractors: extractors}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		as, _ := ctx.Value(ctxAttrsKey{}).([]slog.Attr)
+		for _, e := range h.extractors {
+			as = append(as[:len(as):len(as)], e(ctx)...)
+		}
+		if len(as) > 0 {
+			r = r.Clone()
+			r.AddAttrs(as...)
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *ContextHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &ContextHandler{inner: h.inner.WithAttrs(as), extractors: h.extractors}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{inner: h.inner.WithGroup(name), extractors: h.extractors}
+}
+
+type ctxAttrsKey struct{}
+
+// ContextWithAttrs returns a copy of ctx carrying the attrs as after the ones already in ctx,
+// a ContextHandler adds them to the records logged with the context.
+func ContextWithAttrs(ctx context.Context, as ...slog.Attr) context.Context {
+	old, _ := ctx.Value(ctxAttrsKey{}).([]slog.Attr)
+	return context.WithValue(ctx, ctxAttrsKey{}, append(old[:len(old):len(old)], as...))
+}
+
+// ContextValue returns a ContextExtractor adding the value of the context key ctxKey as the
+// attr key, if it is set. It suits the keys set by existing middlewares, such as a request ID.
+func ContextValue(ctxKey any, key string) ContextExtractor {
+	return func(ctx context.Context) []slog.Attr {
+		v := ctx.Value(ctxKey)
+		if v == nil {
+			return nil
+		}
+		return []slog.Attr{slog.Any(key, v)}
+	}
+}
+
+// ContextCancellation returns a ContextExtractor annotating the records logged with a context
+// already canceled, by ctx_err=canceled or ctx_err=deadline_exceeded and the ctx_cause set by
+// context.WithCancelCause if any, or with a deadline less than near away, by the remaining
+// time as deadline_remaining, negative past the deadline. It helps tracing from the logs the
+// requests abandoned by their clients or timing out.
+func ContextCancellation(near time.Duration) ContextExtractor {
+	return func(ctx context.Context) []slog.Attr {
+		var as []slog.Attr
+		err := ctx.Err()
+		switch err {
+		case context.Canceled:
+			as = append(as, slog.String("ctx_err", "canceled"))
+		case context.DeadlineExceeded:
+			as = append(as, slog.String("ctx_err", "deadline_exceeded"))
+		}
+		if cause := context.Cause(ctx); cause != nil && cause != err {
+			as = append(as, slog.String("ctx_cause", cause.Error()))
+		}
+		if d, ok := ctx.Deadline(); ok {
+			if remaining := time.Until(d); err != nil || remaining < near {
+				as = append(as, slog.Duration("deadline_remaining", remaining))
+			}
+		}
+		return as
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupHandler suppresses the repeats of a record, like the "last message repeated N times" of
+// syslog: a record with the level, the message and the attrs of the record before it, logged
+// by the same handler within the window, isn't passed to the inner handler, but counted. The
+// count is logged once the repeats stop, by a record "rlog: last message repeated" with the
+// attrs message and repeated, at the level of the repeated record. It shrinks the files while
+// a condition flaps.
+//
+// The records are passed to the inner handler one at a time, holding a lock shared by the
+// handlers derived by WithAttrs and WithGroup, so the counts precede the next records.
+type DedupHandler struct {
+	inner slog.Handler
+	d     *dedupState
+}
+
+type dedupState struct {
+	window time.Duration
+
+	mu    sync.Mutex
+	last  *DedupHandler // the handler of the last record, nil before the first one
+	key   string        // the level, message and attrs of the last record
+	level slog.Level
+	msg   string
+	start time.Time // when the repeats of the last record started to be counted
+	n     uint64    // the repeats of the last record suppressed
+	timer *time.Timer
+}
+
+// NewDedupHandler creates a DedupHandler passing the records to inner, suppressing their repeats
+// within window, 1 minute if 0. Once window has passed, the repeats are reported and counted
+// again. It's registered to be closed by Shutdown, which reports the last repeats.
+func NewDedupHandler(inner slog.Handler, window time.Duration) *DedupHandler {
+	if window <= 0 {
+		window = time.Minute
+	}
+	h := &DedupHandler{inner: inner, d: &dedupState{window: window}}
+	OnShutdown(h.d)
+	return h
+}
+
+func (h *DedupHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *DedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	key := dedupKey(r)
+	now := time.Now()
+
+	d := h.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.last == h && d.key == key && now.Sub(d.start) < d.window {
+		if d.n == 0 {
+			d.timer = time.AfterFunc(d.window-now.Sub(d.start), d.expire)
+		}
+		d.n++
+		return nil
+	}
+	err := d.report()
+	d.last, d.key, d.level, d.msg, d.start = h, key, r.Level, r.Message, now
+	if err2 := h.inner.Handle(ctx, r); err2 != nil {
+		err = err2
+	}
+	return err
+}
+
+func (h *DedupHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &DedupHandler{inner: h.inner.WithAttrs(as), d: h.d}
+}
+
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	return &DedupHandler{inner: h.inner.WithGroup(name), d: h.d}
+}
+
+// Close reports the repeats of the last record, if any.
+func (h *DedupHandler) Close() error {
+	return h.d.Close()
+}
+
+// Close reports the last repeats, and unregisters d from Shutdown.
+func (d *dedupState) Close() error {
+	Unregister(d)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.report()
+}
+
+// expire reports the repeats when the window has passed, the next repeat is logged then.
+func (d *dedupState) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.report()
+	d.last = nil
+}
+
+// report logs the number of repeats of the last record and resets it. It's called with d.mu
+// held.
+func (d *dedupState) report() error {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	if d.n == 0 {
+		return nil
+	}
+	n := d.n
+	d.n = 0
+	// the level is enabled, as it was for the repeated record
+	r := slog.NewRecord(time.Now(), d.level, "rlog: last message repeated", 0)
+	r.AddAttrs(slog.String("message", d.msg), slog.Uint64("repeated", n))
+	return d.last.inner.Handle(context.Background(), r)
+}
+
+// dedupKey returns the level, the message and the attrs of r, which identify its repeats.
+func dedupKey(r slog.Record) string {
+	var b strings.Builder
+	b.WriteString(r.Level.String())
+	b.WriteByte(0)
+	b.WriteString(r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		b.WriteByte(0)
+		a.Value = a.Value.Resolve()
+		b.WriteString(a.String())
+		return true
+	})
+	return b.String()
+}
@@ -6,13 +6,33 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
 type DefaultHandler struct {
+	json              bool           // true for the JSON handler, false for the text one
+	theme             *Theme         // the theme of the console handler, nil for others
+	color             bool           // write ANSI colors, only for the console handler
+	name              string         // the logger name column of the aligned console handler
+	sep               string         // the separator between the attrs of the text handler
+	kvDelim           string         // the delimiter between the keys and values of the text handler
+	sourceLevels      []slog.Level   // the levels with the source, if AddSource
+	sourceFrom        *slog.Level    // the min level with the source, besides sourceLevels
+	timeLayout        string         // the layout of times, empty for the default format
+	timeLoc           *time.Location // the location of times, UTC if nil
+	redact            *Redaction     // the redaction of the attrs, nil for none
+	keys              *KeyFilter     // the filter of the attr keys, nil for none
+	limits            *Limits        // the size limits of the records, nil for none
+	stackFrom         *slog.Level    // the min level with the stack trace, nil for none
+	stackDepth        int            // the max number of frames of the stack traces
+	stackSkip         int            // the frames skipped from the caller of the stack traces
+	errFormat         ErrorFormat    // the details written after the errors
+	registry          *Registry      // drop the records once it's shut down, nil if never
 	opts              slog.HandlerOptions
 	preformattedAttrs []byte
 	// groupPrefix is for the text handler only.
@@ -26,15 +46,28 @@ type DefaultHandler struct {
 	w           io.Writer
 }
 
-func NewDefaultHandler(w io.Writer, opts *slog.HandlerOptions) *DefaultHandler {
-	return &DefaultHandler{
-		w:    w,
-		opts: *opts,
-		mu:   &sync.Mutex{},
+func NewDefaultHandler(w io.Writer, opts *slog.HandlerOptions, options ...Option) *DefaultHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	h := &DefaultHandler{
+		w:            w,
+		opts:         *opts,
+		sep:          " ",
+		kvDelim:      "=",
+		sourceLevels: []slog.Level{slog.LevelDebug},
+		mu:           &sync.Mutex{},
+	}
+	for _, o := range options {
+		o(h)
 	}
+	return h
 }
 
 func (h *DefaultHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.registry != nil && h.registry.Done() {
+		return false
+	}
 	minLevel := slog.LevelInfo
 	if h.opts.Level != nil {
 		minLevel = h.opts.Level.Level()
@@ -43,23 +76,87 @@ func (h *DefaultHandler) Enabled(ctx context.Context, l slog.Level) bool {
 }
 
 func (h *DefaultHandler) Handle(ctx context.Context, r slog.Record) error {
-	state := h.newHandleState(NewBuffer(), true, " ")
+	buf := h.encode(r)
+	defer buf.Free()
+	return h.write(*buf)
+}
+
+// encode formats r into a new Buffer, the caller owns it and must Free it.
+// encode doesn't keep r nor modify it, so r needs no Clone.
+func (h *DefaultHandler) encode(r slog.Record) *Buffer {
+	buf := NewBuffer()
+	if h.json {
+		h.encodeJSON(buf, r)
+	} else {
+		h.encodeText(buf, r)
+	}
+	return buf
+}
+
+// write writes a formatted line, serialized with all the clones of the handler.
+func (h *DefaultHandler) write(line []byte) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(line)
+	return err
+}
+
+// sameEncoding reports whether h and h2 format every record to the same bytes, so a record
+// can be encoded once for both.
+func (h *DefaultHandler) sameEncoding(h2 *DefaultHandler) bool {
+	if h == h2 {
+		return true
+	}
+	return h.json == h2.json && h.theme == h2.theme && h.color == h2.color && h.name == h2.name &&
+		h.sep == h2.sep && h.kvDelim == h2.kvDelim &&
+		h.opts.AddSource == h2.opts.AddSource &&
+		slices.Equal(h.sourceLevels, h2.sourceLevels) && equalLevelPtr(h.sourceFrom, h2.sourceFrom) &&
+		h.timeLayout == h2.timeLayout && h.timeLoc == h2.timeLoc &&
+		h.redact == h2.redact && h.keys == h2.keys && h.limits == h2.limits &&
+		equalLevelPtr(h.stackFrom, h2.stackFrom) && h.stackDepth == h2.stackDepth && h.stackSkip == h2.stackSkip &&
+		h.errFormat == h2.errFormat &&
+		h.opts.ReplaceAttr == nil && h2.opts.ReplaceAttr == nil &&
+		string(h.preformattedAttrs) == string(h2.preformattedAttrs) &&
+		h.groupPrefix == h2.groupPrefix && slices.Equal(h.groups, h2.groups) &&
+		h.nOpenGroups == h2.nOpenGroups
+}
+
+func (h *DefaultHandler) encodeText(buf *Buffer, r slog.Record) {
+	state := h.newHandleState(buf, false, h.attrSep())
 	defer state.free()
+	if h.limits != nil {
+		state.maxLen = h.limits.Record
+	}
 
 	// Built-in attributes. They are not in a group.
 	stateGroups := state.groups
 	state.groups = nil // So ReplaceAttrs sees no groups instead of the pre groups.
 	// time
 	if !r.Time.IsZero() {
-		state.appendTime(r.Time.Round(0))
+		if h.theme != nil && h.theme.TimeFormat != "" {
+			state.buf.WriteByte('[')
+			*state.buf = r.Time.AppendFormat(*state.buf, h.theme.TimeFormat)
+			state.buf.WriteByte(']')
+		} else {
+			state.appendTime(r.Time.Round(0))
+		}
 	}
 	// level
+	levelStart := state.buf.Len()
 	state.buf.WriteByte('[')
-	state.appendString(r.Level.String())
+	if h.theme != nil {
+		state.appendThemedLevel(r.Level)
+	} else {
+		state.appendString(r.Level.String())
+	}
 	state.buf.WriteByte(']')
+	aligned := h.theme != nil && h.theme.Aligned
+	if aligned {
+		state.alignLevelAndName(levelStart, r)
+	}
 
 	// source
-	if h.opts.AddSource && r.Level == slog.LevelDebug {
+	if h.addSource(r.Level) {
 		src := source(&r)
 		state.buf.WriteByte('[')
 		state.appendString(fmt.Sprintf("%s:%d", src.File, src.Line))
@@ -68,17 +165,50 @@ func (h *DefaultHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// msg
 	state.appendSep()
-	state.appendString(r.Message)
+	msgStart := state.buf.Len()
+	state.appendString(h.limits.message(h.redact.message(r.Message)))
+	if aligned {
+		state.padTo(msgStart, h.theme.messageWidth())
+		state.attrStarts = []int{}
+	}
 
 	// groups
 	state.groups = stateGroups // Restore groups passed to ReplaceAttrs.
 	state.appendNonBuiltIns(r)
+	if aligned {
+		state.wrapAttrs(msgStart)
+	}
 	state.buf.WriteByte('\n')
+}
 
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	_, err := h.w.Write(*state.buf)
-	return err
+// Close writes the EncodingReport to os.Stderr in the builds with the rloginstrument tag, then
+// closes the writer of the handler if it is an io.Closer other than os.Stdout and os.Stderr.
+// The clones of the handler share the writer, they must not be used afterwards.
+func (h *DefaultHandler) Close() error {
+	if report := EncodingReport(); report != "" {
+		os.Stderr.WriteString(report)
+	}
+	if h.w == os.Stdout || h.w == os.Stderr {
+		return nil
+	}
+	if c, ok := h.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// WriteRaw writes an already formatted line to the writer of the handler, bypassing the
+// formatting, a newline is appended if missing. The line still goes through the rotation of
+// the writer, and it is serialized with the records of the handler and all its clones.
+func (h *DefaultHandler) WriteRaw(line []byte) error {
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		buf := NewBuffer()
+		defer buf.Free()
+		buf.Write(line)
+		buf.WriteByte('\n')
+		line = *buf
+	}
+	return h.write(line)
 }
 
 func (h *DefaultHandler) WithAttrs(as []slog.Attr) slog.Handler {
@@ -97,7 +227,13 @@ func (h *DefaultHandler) WithAttrs(as []slog.Attr) slog.Handler {
 	}
 	state.openGroups()
 	for _, a := range as {
-		state.appendAttr(a)
+		if h2.isNameAttr(a) {
+			h2.name = a.Value.String()
+			continue
+		}
+		if a, ok := h2.filterKey(a); ok {
+			state.appendAttr(a)
+		}
 	}
 	// Remember the new prefix for later keys.
 	h2.groupPrefix = state.prefix.String()
@@ -116,6 +252,24 @@ func (h *DefaultHandler) WithGroup(name string) slog.Handler {
 func (h *DefaultHandler) clone() *DefaultHandler {
 	// We can't use assignment because we can't copy the mutex.
 	return &DefaultHandler{
+		json:              h.json,
+		theme:             h.theme,
+		color:             h.color,
+		name:              h.name,
+		sep:               h.sep,
+		kvDelim:           h.kvDelim,
+		sourceLevels:      h.sourceLevels,
+		sourceFrom:        h.sourceFrom,
+		timeLayout:        h.timeLayout,
+		timeLoc:           h.timeLoc,
+		redact:            h.redact,
+		keys:              h.keys,
+		limits:            h.limits,
+		stackFrom:         h.stackFrom,
+		stackDepth:        h.stackDepth,
+		stackSkip:         h.stackSkip,
+		errFormat:         h.errFormat,
+		registry:          h.registry,
 		opts:              h.opts,
 		preformattedAttrs: slices.Clip(h.preformattedAttrs),
 		groupPrefix:       h.groupPrefix,
@@ -128,7 +282,37 @@ func (h *DefaultHandler) clone() *DefaultHandler {
 
 // attrSep returns the separator between attributes.
 func (h *DefaultHandler) attrSep() string {
-	return " "
+	if h.json {
+		return ","
+	}
+	return h.sep
+}
+
+func equalLevelPtr(a, b *slog.Level) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+// timeIn returns t in the location of the times of the handler.
+func (h *DefaultHandler) timeIn(t time.Time) time.Time {
+	if h.timeLoc == nil {
+		return t.UTC()
+	}
+	return t.In(h.timeLoc)
+}
+
+// addSource reports whether the records of level l get their source.
+func (h *DefaultHandler) addSource(l slog.Level) bool {
+	if !h.opts.AddSource {
+		return false
+	}
+	return (h.sourceFrom != nil && l >= *h.sourceFrom) || slices.Contains(h.sourceLevels, l)
+}
+
+// customSepIn reports whether str contains a custom separator or delimiter of the text
+// handler, so it must be quoted. The default ones are handled by needsQuoting.
+func (h *DefaultHandler) customSepIn(str string) bool {
+	return (h.sep != " " && strings.Contains(str, h.sep)) ||
+		(h.kvDelim != "=" && strings.Contains(str, h.kvDelim))
 }
 
 func (h *DefaultHandler) newHandleState(buf *Buffer, freeBuf bool, sep string) handleState {
@@ -156,6 +340,10 @@ type handleState struct {
 	sep     string    // separator to write before next key
 	prefix  *Buffer   // for text: key prefix
 	groups  *[]string // pool-allocated slice of active groups, for ReplaceAttr
+
+	attrStarts []int // the offsets of attrs in buf, only for the aligned console handler
+	maxLen     int   // the max length of buf with the attrs of a record, 0 for no limit
+	truncated  int   // the number of attrs dropped by maxLen
 }
 
 func (s *handleState) free() {
@@ -178,8 +366,14 @@ func (s *handleState) openGroups() {
 // openGroup starts a new group of attributes
 // with the given name.
 func (s *handleState) openGroup(name string) {
-	s.prefix.WriteString(name)
-	s.prefix.WriteByte(keyComponentSep)
+	if s.h.json {
+		s.appendKey(name)
+		s.buf.WriteByte('{')
+		s.sep = ""
+	} else {
+		s.prefix.WriteString(name)
+		s.prefix.WriteByte(keyComponentSep)
+	}
 	// Collect group names for ReplaceAttr.
 	if s.groups != nil {
 		*s.groups = append(*s.groups, name)
@@ -188,43 +382,92 @@ func (s *handleState) openGroup(name string) {
 
 // closeGroup ends the group with the given name.
 func (s *handleState) closeGroup(name string) {
-	(*s.prefix) = (*s.prefix)[:len(*s.prefix)-len(name)-1 /* for keyComponentSep */]
+	if s.h.json {
+		s.buf.WriteByte('}')
+		s.sep = s.h.attrSep()
+	} else {
+		(*s.prefix) = (*s.prefix)[:len(*s.prefix)-len(name)-1 /* for keyComponentSep */]
+	}
 	if s.groups != nil {
 		*s.groups = (*s.groups)[:len(*s.groups)-1]
 	}
 }
 
 func (s *handleState) appendNonBuiltIns(r slog.Record) {
+	// preformatted Attrs
+	if pfa := s.h.preformattedAttrs; len(pfa) > 0 {
+		if s.attrStarts != nil {
+			s.attrStarts = append(s.attrStarts, s.buf.Len())
+		}
+		s.buf.WriteString(s.sep)
+		s.buf.Write(pfa)
+		s.sep = s.h.attrSep()
+		if s.h.json && pfa[len(pfa)-1] == '{' {
+			s.sep = ""
+		}
+	}
 	// Attrs in Record -- unlike the built-in ones, they are in groups started
 	// from WithGroup.
 	// If the record has no Attrs, don't output any groups.
+	nOpenGroups := s.h.nOpenGroups
 	if r.NumAttrs() > 0 {
 		s.prefix.WriteString(s.h.groupPrefix)
 		s.openGroups()
+		nOpenGroups = len(s.h.groups)
 		r.Attrs(func(a slog.Attr) bool {
-			s.appendAttr(a)
+			if s.h.isNameAttr(a) {
+				return true
+			}
+			if a, ok := s.h.filterKey(a); ok {
+				s.appendAttr(a)
+			}
 			return true
 		})
 	}
+	if s.h.json {
+		// Close all open groups.
+		for range s.h.groups[:nOpenGroups] {
+			s.buf.WriteByte('}')
+			s.sep = s.h.attrSep()
+		}
+		s.appendTruncated()
+		s.appendStack(r)
+		// Close the top-level object.
+		s.buf.WriteByte('}')
+	} else {
+		s.appendTruncated()
+		s.appendStack(r)
+	}
 }
 
 // appendAttr appends the Attr's key and value using app.
-// It handles replacement and checking for an empty key.
-// after replacement).
+// It handles replacement and checking for an empty key
+// (after replacement).
+//
+// The value is resolved first, so a LogValuer resolving to a group is
+// traversed like a group: ReplaceAttr sees each of its leaves with its
+// group path, as for the groups passed directly, never the whole group.
 func (s *handleState) appendAttr(a slog.Attr) {
-	if rep := s.h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
-		var gs []string
-		if s.groups != nil {
-			gs = *s.groups
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		if r := s.h.redact; r != nil {
+			a = r.Attr(a)
+		}
+		if rep := s.h.opts.ReplaceAttr; rep != nil {
+			var gs []string
+			if s.groups != nil {
+				gs = *s.groups
+			}
+			a = rep(gs, a)
+			a.Value = a.Value.Resolve()
+		}
+		if l := s.h.limits; l != nil {
+			a.Value = l.value(a.Value)
 		}
-		// Resolve before calling ReplaceAttr, so the user doesn't have to.
-		a.Value = a.Value.Resolve()
-		a = rep(gs, a)
 	}
-	a.Value = a.Value.Resolve()
 
-	// Elide empty Attrs.
-	if a.Key == "" {
+	// Elide empty Attrs, but inline the groups with an empty key.
+	if a.Key == "" && a.Value.Kind() != slog.KindGroup {
 		return
 	}
 
@@ -244,25 +487,56 @@ func (s *handleState) appendAttr(a slog.Attr) {
 			}
 		}
 	} else {
+		start, sep, nStarts := s.buf.Len(), s.sep, len(s.attrStarts)
 		s.appendKey(a.Key)
 		s.appendValue(a.Value)
+		if err, ok := errorValue(a.Value); ok && s.h.errFormat != (ErrorFormat{}) {
+			s.appendErrorDetails(a.Key, err)
+		}
+		if s.maxLen > 0 && s.buf.Len() > s.maxLen {
+			// drop the attr exceeding the record size
+			*s.buf = (*s.buf)[:start]
+			s.sep = sep
+			if s.attrStarts != nil {
+				s.attrStarts = s.attrStarts[:nStarts]
+			}
+			s.truncated++
+		}
 	}
 }
 
 func (s *handleState) appendKey(key string) {
+	if s.attrStarts != nil {
+		s.attrStarts = append(s.attrStarts, s.buf.Len())
+	}
 	s.buf.WriteString(s.sep)
+	dim := s.h.color && s.h.theme.DimKeys
+	if dim {
+		s.buf.WriteString(ansiDim)
+	}
 	if s.prefix != nil && len(*s.prefix) > 0 {
 		// TODO: optimize by avoiding allocation.
 		s.appendString(string(*s.prefix) + key)
 	} else {
 		s.appendString(key)
 	}
-	s.buf.WriteByte('=')
+	if s.h.json {
+		s.buf.WriteByte(':')
+	} else {
+		s.buf.WriteString(s.h.kvDelim)
+	}
+	if dim {
+		s.buf.WriteString(ansiReset)
+	}
 	s.sep = s.h.attrSep()
 }
 
 func (s *handleState) appendString(str string) {
-	if needsQuoting(str) {
+	if s.h.json {
+		s.buf.WriteByte('"')
+		*s.buf = appendEscapedJSONString(*s.buf, str)
+		s.buf.WriteByte('"')
+	} else if needsQuoting(str) || s.h.customSepIn(str) {
 		*s.buf = strconv.AppendQuote(*s.buf, str)
 	} else {
 		s.buf.WriteString(str)
@@ -270,22 +544,39 @@ func (s *handleState) appendString(str string) {
 }
 
 func (s *handleState) appendValue(v slog.Value) {
-	err := s.appendTextValue(v)
+	mark := instrumentStart()
+	var err error
+	if s.h.json {
+		err = s.appendJSONValue(v)
+	} else {
+		err = s.appendTextValue(v)
+	}
 	if err != nil {
 		s.appendError(err)
 	}
+	instrumentEnd(v.Kind(), mark)
 }
 
 func (s *handleState) appendTime(t time.Time) {
+	if s.h.json {
+		s.appendJSONTime(t)
+		return
+	}
+	t = s.h.timeIn(t)
 	s.buf.WriteByte('[')
-	year, month, day := t.UTC().Date()
+	if s.h.timeLayout != "" {
+		*s.buf = t.AppendFormat(*s.buf, s.h.timeLayout)
+		s.buf.WriteByte(']')
+		return
+	}
+	year, month, day := t.Date()
 	s.buf.WritePosIntWidth(year, 4)
 	s.buf.WriteByte('-')
 	s.buf.WritePosIntWidth(int(month), 2)
 	s.buf.WriteByte('-')
 	s.buf.WritePosIntWidth(day, 2)
 	s.buf.WriteByte('T')
-	hour, min, sec := t.UTC().Clock()
+	hour, min, sec := t.Clock()
 	s.buf.WritePosIntWidth(hour, 2)
 	s.buf.WriteByte(':')
 	s.buf.WritePosIntWidth(min, 2)
@@ -326,6 +617,11 @@ func (s *handleState) appendTextValue(v slog.Value) error {
 			s.buf.WriteString(strconv.Quote(string(bs)))
 			return nil
 		}
+		// the details of the errors, such as their stack, are written by WithErrorFormat
+		if err, ok := asError(v.Any()); ok {
+			s.appendString(err.Error())
+			return nil
+		}
 		s.appendString(fmt.Sprintf("%+v", v.Any()))
 	case slog.KindInt64:
 		*s.buf = strconv.AppendInt(*s.buf, v.Int64(), 10)
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"encoding"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diff returns a group attr holding the changes between the values before and after, such as
+// two versions of a config or an entity, for audit records. The fields of the structs and the
+// entries of the maps are compared recursively, the unchanged ones are elided, and a changed
+// one is a group of its values "from" and "to", one of them missing if the field was added or
+// removed:
+//
+//	logger.Info("config changed", handler.Diff("config", old, new))
+//	// config.timeout.from=5s config.timeout.to=10s config.tags.b.to=2
+//
+// The fields are named by their json tags if they have one. The values implementing
+// fmt.Stringer or encoding.TextMarshaler, and the structs without exported fields such as
+// time.Time, are compared as a whole. The group is empty if nothing changed. The values
+// referring back to a value being compared are cycles, printed as "<cycle>" if they changed.
+func Diff(key string, before, after any) slog.Attr {
+	return (*Redaction)(nil).Diff(key, before, after)
+}
+
+// Diff is like the function Diff, with the changed values redacted by r: the values of the
+// fields whose names match the keys of r are masked, and the patterns of r are masked in the
+// string values. As the changed values are keyed "from" and "to", a RedactHandler could not
+// match the field names.
+func (r *Redaction) Diff(key string, before, after any) slog.Attr {
+	d := differ{r: r, path: map[[2]uintptr]bool{}}
+	return slog.Attr{Key: key, Value: slog.GroupValue(d.diff(key, reflect.ValueOf(before), reflect.ValueOf(after))...)}
+}
+
+type differ struct {
+	r    *Redaction
+	path map[[2]uintptr]bool // the pairs of pointers and maps being compared, to stop at the cycles
+}
+
+var (
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// diff returns the attrs of the changes between a and b, which are the values of the field
+// name.
+func (d differ) diff(name string, a, b reflect.Value) []slog.Attr {
+	refs := [2]uintptr{ref(a), ref(b)}
+	cycle := refs != [2]uintptr{} && d.path[refs]
+	a, b = indirect(a), indirect(b)
+	if a.IsValid() && b.IsValid() && a.Type() == b.Type() && !isLeaf(a.Type()) && !cycle {
+		if refs != [2]uintptr{} {
+			d.path[refs] = true
+			defer delete(d.path, refs)
+		}
+		switch a.Kind() {
+		case reflect.Struct:
+			return d.diffStruct(a, b)
+		case reflect.Map:
+			return d.diffMap(a, b)
+		}
+	}
+	if a.IsValid() && b.IsValid() && reflect.DeepEqual(a.Interface(), b.Interface()) || !a.IsValid() && !b.IsValid() {
+		return nil
+	}
+	if cycle {
+		// a cycle is compared as a whole, which DeepEqual supports, but can't be printed
+		return []slog.Attr{slog.String("from", "<cycle>"), slog.String("to", "<cycle>")}
+	}
+	var as []slog.Attr
+	if a.IsValid() {
+		as = append(as, d.value(name, "from", a))
+	}
+	if b.IsValid() {
+		as = append(as, d.value(name, "to", b))
+	}
+	return as
+}
+
+func (d differ) diffStruct(a, b reflect.Value) []slog.Attr {
+	var as []slog.Attr
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag, _, _ := strings.Cut(f.Tag.Get("json"), ","); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+		as = d.appendField(as, name, a.Field(i), b.Field(i))
+	}
+	return as
+}
+
+func (d differ) diffMap(a, b reflect.Value) []slog.Attr {
+	keys := map[string]reflect.Value{}
+	for _, m := range []reflect.Value{a, b} {
+		for _, k := range m.MapKeys() {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+	}
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var as []slog.Attr
+	for _, name := range names {
+		k := keys[name]
+		as = d.appendField(as, name, a.MapIndex(k), b.MapIndex(k))
+	}
+	return as
+}
+
+// appendField appends the changes of the field name as a group, if any.
+func (d differ) appendField(as []slog.Attr, name string, a, b reflect.Value) []slog.Attr {
+	if changes := d.diff(name, a, b); len(changes) > 0 {
+		as = append(as, slog.Attr{Key: name, Value: slog.GroupValue(changes...)})
+	}
+	return as
+}
+
+// value returns v as the attr key, redacted as a value of the field name.
+func (d differ) value(name, key string, v reflect.Value) slog.Attr {
+	a := slog.Any(key, v.Interface())
+	if d.r != nil {
+		a = d.r.Attr(slog.Attr{Key: name, Value: a.Value})
+		a.Key = key
+	}
+	return a
+}
+
+// indirect dereferences the pointers and interfaces of v, it returns the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		if v.Kind() == reflect.Pointer && isLeaf(v.Type()) {
+			return v
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// ref returns the address of the pointer or the map v, 0 if it's neither or nil.
+func ref(v reflect.Value) uintptr {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Map) && !v.IsNil() {
+		return v.Pointer()
+	}
+	return 0
+}
+
+// isLeaf reports whether the values of t are compared as a whole.
+func isLeaf(t reflect.Type) bool {
+	if t.Implements(stringerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
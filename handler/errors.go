@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// ErrorFormat sets the details written after the attrs whose value is an error, which are
+// always written as their Error string. The details are written under the key of the attr
+// followed by a dot and their suffix, such as error.cause and error.stack.
+type ErrorFormat struct {
+	// CauseKey is the suffix of the messages of the errors wrapped by the error, found by
+	// errors.Unwrap, none if empty.
+	CauseKey string
+	// StackKey is the suffix of the %+v formatting of the errors implementing fmt.Formatter,
+	// such as the errors with stack traces, none if empty. It's only written if it differs
+	// from the Error string.
+	StackKey string
+}
+
+// WithErrorFormat writes the details of the errors set by f, such as
+// ErrorFormat{CauseKey: "cause", StackKey: "stack"}.
+func WithErrorFormat(f ErrorFormat) Option {
+	return func(h *DefaultHandler) {
+		h.errFormat = f
+	}
+}
+
+// appendErrorDetails appends the details of the error err of the attr key.
+func (s *handleState) appendErrorDetails(key string, err error) {
+	f := s.h.errFormat
+	if f.CauseKey != "" {
+		if causes := errorCauses(err); len(causes) > 0 {
+			s.appendKey(key + "." + f.CauseKey)
+			if s.h.json {
+				s.buf.WriteByte('[')
+				for i, c := range causes {
+					if i > 0 {
+						s.buf.WriteByte(',')
+					}
+					s.appendString(c)
+				}
+				s.buf.WriteByte(']')
+			} else {
+				s.appendString(strings.Join(causes, "; "))
+			}
+		}
+	}
+	if _, ok := err.(fmt.Formatter); ok && f.StackKey != "" {
+		if detail := fmt.Sprintf("%+v", err); detail != err.Error() {
+			s.appendKey(key + "." + f.StackKey)
+			s.appendString(detail)
+		}
+	}
+}
+
+// errorCauses returns the messages of the errors wrapped by err, from the outermost. The
+// errors joined by errors.Join and the like are all listed.
+func errorCauses(err error) []string {
+	var causes []string
+	var walk func(err error)
+	walk = func(err error) {
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				if e, ok := asError(e); ok {
+					causes = append(causes, e.Error())
+					walk(e)
+				}
+			}
+		default:
+			if e, ok := asError(errors.Unwrap(err)); ok {
+				causes = append(causes, e.Error())
+				walk(e)
+			}
+		}
+	}
+	walk(err)
+	return causes
+}
+
+// errorValue returns the error of v, if it holds one.
+func errorValue(v slog.Value) (error, bool) {
+	if v.Kind() != slog.KindAny {
+		return nil, false
+	}
+	return asError(v.Any())
+}
+
+// asError returns a as an error, if it's one which isn't a nil pointer, whose Error method
+// would panic. The nil pointers are formatted by fmt as <nil>.
+func asError(a any) (error, bool) {
+	err, ok := a.(error)
+	if !ok || err == nil {
+		return nil, false
+	}
+	if v := reflect.ValueOf(err); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, false
+	}
+	return err, true
+}
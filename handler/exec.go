@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os/exec"
+	"sync"
+)
+
+// default max bytes of one forwarded line, longer lines are split into chunks
+const defaultMaxLineSize = 64 << 10
+
+// ExecOptions configures how the output of a child process is forwarded.
+type ExecOptions struct {
+	Name        string     // the value of the "child" attr, defaults to the command path
+	StdoutLevel slog.Level // level for lines read from stdout, defaults to INFO
+	StderrLevel slog.Level // level for lines read from stderr, defaults to INFO
+	MaxLineSize int        // lines longer than this are split, defaults to 64KB
+}
+
+// LineWriter is an io.Writer which logs every complete line written to it as a record.
+// Partial lines are kept until the newline arrives or Flush is called.
+type LineWriter struct {
+	logger  *slog.Logger
+	level   slog.Level
+	maxSize int
+	mu      sync.Mutex
+	buf     []byte
+}
+
+// NewLineWriter returns a LineWriter logging every line with level to logger.
+func NewLineWriter(logger *slog.Logger, level slog.Level, maxLineSize int) *LineWriter {
+	if maxLineSize <= 0 {
+		maxLineSize = defaultMaxLineSize
+	}
+	return &LineWriter{
+		logger:  logger,
+		level:   level,
+		maxSize: maxLineSize,
+	}
+}
+
+// Write implements io.Writer.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i], false)
+		w.buf = w.buf[i+1:]
+	}
+	// split overlong lines so a child without newlines can't grow the buffer forever
+	for len(w.buf) >= w.maxSize {
+		w.emit(w.buf[:w.maxSize], true)
+		w.buf = w.buf[w.maxSize:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+// Flush logs the pending partial line, if any.
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.emit(w.buf, false)
+	}
+	w.buf = nil
+}
+
+func (w *LineWriter) emit(line []byte, partial bool) {
+	line = bytes.TrimSuffix(line, []byte{'\r'})
+	if partial {
+		w.logger.Log(context.Background(), w.level, string(line), slog.Bool("partial", true))
+		return
+	}
+	w.logger.Log(context.Background(), w.level, string(line))
+}
+
+// RunCommand runs cmd and logs its stdout and stderr line by line to logger.
+// Every record carries a "child" attr with the name of the process.
+func RunCommand(logger *slog.Logger, cmd *exec.Cmd, opts *ExecOptions) error {
+	if opts == nil {
+		opts = &ExecOptions{}
+	}
+	name := opts.Name
+	if name == "" {
+		name = cmd.Path
+	}
+	l := logger.With(slog.String("child", name))
+	stdout := NewLineWriter(l.With(slog.String("stream", "stdout")), opts.StdoutLevel, opts.MaxLineSize)
+	stderr := NewLineWriter(l.With(slog.String("stream", "stderr")), opts.StderrLevel, opts.MaxLineSize)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+	return err
+}
@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The chunk sizes of GELF over UDP, the WAN one fits the usual MTU.
+const (
+	GELFChunkWAN = 1420
+	GELFChunkLAN = 8154
+)
+
+// gelfMaxChunks is the max number of chunks of a message, Graylog drops the bigger ones.
+const gelfMaxChunks = 128
+
+// GELFHandler formats the records as GELF 1.1, the JSON format of Graylog. The level is the
+// syslog severity, the attrs are additional fields whose names are the keys of their groups and
+// their own joined by underscores, such as "_req_id". GELF fields are strings or numbers, so
+// the other values are formatted as strings.
+//
+// A GELFHandler either writes newline delimited messages to a writer, such as a rotation
+// Logger read by a Graylog input, or sends them over UDP, chunked when they are bigger than a
+// datagram. Handlers derived by WithAttrs and WithGroup share the output.
+type GELFHandler struct {
+	opts   slog.HandlerOptions
+	host   string
+	groups []string
+	prefix string // the groups joined by underscores, followed by one
+	fields []byte // the fields preformatted by WithAttrs
+	out    *gelfOutput
+}
+
+type gelfOutput struct {
+	mu        sync.Mutex
+	w         io.Writer // nil over UDP
+	conn      net.Conn
+	chunkSize int
+}
+
+// NewGELFHandler creates a GELFHandler writing to w, one message per line.
+func NewGELFHandler(w io.Writer, opts *slog.HandlerOptions) *GELFHandler {
+	return newGELFHandler(&gelfOutput{w: w}, opts)
+}
+
+// NewGELFUDPHandler creates a GELFHandler sending to the Graylog UDP input at addr, in chunks
+// of chunkSize bytes, GELFChunkWAN if 0.
+func NewGELFUDPHandler(addr string, chunkSize int, opts *slog.HandlerOptions) (*GELFHandler, error) {
+	if chunkSize <= 0 {
+		chunkSize = GELFChunkWAN
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return newGELFHandler(&gelfOutput{conn: conn, chunkSize: chunkSize}, opts), nil
+}
+
+func newGELFHandler(out *gelfOutput, opts *slog.HandlerOptions) *GELFHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	host, _ := os.Hostname()
+	return &GELFHandler{opts: *opts, host: host, out: out}
+}
+
+func (h *GELFHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *GELFHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	buf := NewBuffer()
+	defer buf.Free()
+	buf.WriteString(`{"version":"1.1","host":`)
+	*buf = appendGELFString(*buf, h.host)
+	buf.WriteString(`,"short_message":`)
+	*buf = appendGELFString(*buf, r.Message)
+	buf.WriteString(`,"timestamp":`)
+	*buf = strconv.AppendFloat(*buf, float64(t.UnixMicro())/1e6, 'f', 6, 64)
+	buf.WriteString(`,"level":`)
+	*buf = strconv.AppendInt(*buf, int64(syslogSeverity(r.Level)), 10)
+	if h.opts.AddSource && r.PC != 0 {
+		src := source(&r)
+		buf.WriteString(`,"_file":`)
+		*buf = appendGELFString(*buf, src.File)
+		buf.WriteString(`,"_line":`)
+		*buf = strconv.AppendInt(*buf, int64(src.Line), 10)
+	}
+	buf.Write(h.fields)
+	r.Attrs(func(a slog.Attr) bool {
+		*buf = h.appendField(*buf, h.groups, h.prefix, a)
+		return true
+	})
+	buf.WriteByte('}')
+	return h.out.send(*buf)
+}
+
+func (h *GELFHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.fields = slices.Clip(h.fields)
+	for _, a := range as {
+		h2.fields = h.appendField(h2.fields, h.groups, h.prefix, a)
+	}
+	return &h2
+}
+
+func (h *GELFHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(slices.Clip(h.groups), name)
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+// Close closes the UDP connection, the writer is left open.
+func (h *GELFHandler) Close() error {
+	if h.out.conn == nil {
+		return nil
+	}
+	return h.out.conn.Close()
+}
+
+// appendField appends a as the fields of a message.
+func (h *GELFHandler) appendField(buf []byte, groups []string, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if rep := h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
+		a = rep(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		as := a.Value.Group()
+		if len(as) == 0 {
+			return buf
+		}
+		if a.Key != "" {
+			groups = append(slices.Clip(groups), a.Key)
+			prefix += a.Key + "_"
+		}
+		for _, ga := range as {
+			buf = h.appendField(buf, groups, prefix, ga)
+		}
+		return buf
+	}
+	name := gelfFieldName(prefix + a.Key)
+	if name == "_id" { // reserved by GELF
+		name = "_id_"
+	}
+	buf = append(buf, ',')
+	buf = appendGELFString(buf, name)
+	buf = append(buf, ':')
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		if f := v.Float64(); !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return strconv.AppendFloat(buf, f, 'g', -1, 64)
+		}
+	case slog.KindTime:
+		return appendGELFString(buf, v.Time().Format(time.RFC3339Nano))
+	case slog.KindAny:
+		if err, ok := asError(v.Any()); ok {
+			return appendGELFString(buf, err.Error())
+		}
+	}
+	return appendGELFString(buf, v.String())
+}
+
+// appendGELFString appends s as a quoted JSON string.
+func appendGELFString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	buf = appendEscapedJSONString(buf, s)
+	return append(buf, '"')
+}
+
+// gelfFieldName prefixes key by an underscore, and replaces the characters not allowed in the
+// field names by underscores.
+func gelfFieldName(key string) string {
+	b := make([]byte, 0, len(key)+1)
+	b = append(b, '_')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c == '_' || c == '.' || c == '-' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// send writes msg as a line, or sends it over UDP in as many chunks as needed.
+func (o *gelfOutput) send(msg []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.w != nil {
+		msg = append(msg, '\n')
+		_, err := o.w.Write(msg)
+		return err
+	}
+	if len(msg) <= o.chunkSize {
+		_, err := o.conn.Write(msg)
+		return err
+	}
+	const header = 12 // the magic bytes, the message id, the sequence number and count
+	size := o.chunkSize - header
+	count := (len(msg) + size - 1) / size
+	if count > gelfMaxChunks {
+		return errors.New("rlog: GELF message too big for UDP")
+	}
+	chunk := make([]byte, header, o.chunkSize)
+	chunk[0], chunk[1] = 0x1e, 0x0f
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		chunk[10] = byte(i)
+		chunk = append(chunk[:header], msg[i*size:min((i+1)*size, len(msg))]...)
+		if _, err := o.conn.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+//go:build rloginstrument
+
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// The encoding costs per kind of value, accumulated by the builds with the rloginstrument tag.
+var encodingStats [slog.KindLogValuer + 1]struct {
+	count  atomic.Int64
+	nanos  atomic.Int64
+	allocs atomic.Int64
+}
+
+type instrumentMark struct {
+	start  time.Time
+	allocs uint64
+}
+
+// heapAllocs returns the number of allocations of the process. runtime.MemStats is read
+// rather than runtime/metrics, whose counts of small allocations lag behind. It stops the
+// world, which is acceptable for an instrumented build only.
+func heapAllocs() uint64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.Mallocs
+}
+
+// allocsOverhead is the number of allocations of heapAllocs itself.
+var allocsOverhead = func() uint64 {
+	a := heapAllocs()
+	return heapAllocs() - a
+}()
+
+func instrumentStart() instrumentMark {
+	allocs := heapAllocs()
+	return instrumentMark{start: time.Now(), allocs: allocs}
+}
+
+func instrumentEnd(k slog.Kind, m instrumentMark) {
+	d := time.Since(m.start)
+	st := &encodingStats[k]
+	st.count.Add(1)
+	st.nanos.Add(int64(d))
+	st.allocs.Add(int64(heapAllocs() - m.allocs - allocsOverhead))
+}
+
+// EncodingReport returns the number of encoded values, their average encoding time and
+// allocations per kind of value, for the builds with the rloginstrument tag. The allocations
+// are counted for the whole process, so they are approximate while other goroutines run.
+func EncodingReport() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s %12s %12s %12s\n", "kind", "count", "ns/value", "allocs/value")
+	for k := range encodingStats {
+		st := &encodingStats[k]
+		n := st.count.Load()
+		if n == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%-10s %12d %12.1f %12.2f\n", slog.Kind(k), n,
+			float64(st.nanos.Load())/float64(n), float64(st.allocs.Load())/float64(n))
+	}
+	return b.String()
+}
@@ -0,0 +1,19 @@
+//go:build !rloginstrument
+
+package handler
+
+import "log/slog"
+
+// instrumentMark is empty out of the builds with the rloginstrument tag, so the
+// instrumentation is compiled out.
+type instrumentMark struct{}
+
+func instrumentStart() instrumentMark { return instrumentMark{} }
+
+func instrumentEnd(k slog.Kind, m instrumentMark) {}
+
+// EncodingReport returns the encoding costs per kind of value, it is empty out of the builds
+// with the rloginstrument tag.
+func EncodingReport() string {
+	return ""
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// NewJSONHandler creates a handler which writes every record as a line of JSON object to w.
+// Groups are written as nested JSON objects. It shares the buffers and the preformatting
+// of attrs with the DefaultHandler.
+func NewJSONHandler(w io.Writer, opts *slog.HandlerOptions, options ...Option) *DefaultHandler {
+	h := NewDefaultHandler(w, opts, options...)
+	h.json = true
+	return h
+}
+
+// encodeJSON formats the record r into buf as a JSON object.
+func (h *DefaultHandler) encodeJSON(buf *Buffer, r slog.Record) {
+	state := h.newHandleState(buf, false, "")
+	defer state.free()
+	if h.limits != nil {
+		state.maxLen = h.limits.Record
+	}
+	state.buf.WriteByte('{')
+
+	// Built-in attributes. They are not in a group.
+	stateGroups := state.groups
+	state.groups = nil // So ReplaceAttrs sees no groups instead of the pre groups.
+	// time
+	if !r.Time.IsZero() {
+		state.appendKey(slog.TimeKey)
+		state.appendTime(r.Time.Round(0))
+	}
+	// level
+	state.appendKey(slog.LevelKey)
+	state.appendString(r.Level.String())
+
+	// source
+	if h.addSource(r.Level) {
+		src := source(&r)
+		state.appendAttr(slog.Group(slog.SourceKey,
+			slog.String("file", src.File),
+			slog.Int("line", src.Line),
+		))
+	}
+
+	// msg
+	state.appendKey(slog.MessageKey)
+	state.appendString(h.limits.message(h.redact.message(r.Message)))
+
+	// groups
+	state.groups = stateGroups // Restore groups passed to ReplaceAttrs.
+	state.appendNonBuiltIns(r)
+	state.buf.WriteByte('\n')
+}
+
+// Adapted from time.Time.MarshalJSON to avoid allocation.
+func (s *handleState) appendJSONTime(t time.Time) {
+	if y := t.Year(); y < 0 || y >= 10000 {
+		// RFC 3339 is clear that years are 4 digits exactly.
+		s.appendError(errors.New("time.Time year outside of range [0,9999]"))
+		return
+	}
+	t = s.h.timeIn(t)
+	if s.h.timeLayout != "" {
+		s.appendString(t.Format(s.h.timeLayout))
+		return
+	}
+	s.buf.WriteByte('"')
+	*s.buf = t.AppendFormat(*s.buf, "2006-01-02T15:04:05.000Z07:00")
+	s.buf.WriteByte('"')
+}
+
+func (s *handleState) appendJSONValue(v slog.Value) error {
+	switch v.Kind() {
+	case slog.KindString:
+		s.appendString(v.String())
+	case slog.KindInt64:
+		*s.buf = strconv.AppendInt(*s.buf, v.Int64(), 10)
+	case slog.KindUint64:
+		*s.buf = strconv.AppendUint(*s.buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		// json.Marshal is funny about floats; it doesn't
+		// always match strconv.AppendFloat. So just call it.
+		// That's expensive, but floats are rare.
+		if err := appendJSONMarshal(s.buf, v.Float64()); err != nil {
+			return err
+		}
+	case slog.KindBool:
+		*s.buf = strconv.AppendBool(*s.buf, v.Bool())
+	case slog.KindDuration:
+		// Do what json.Marshal does.
+		*s.buf = strconv.AppendInt(*s.buf, int64(v.Duration()), 10)
+	case slog.KindTime:
+		s.appendTime(v.Time())
+	default:
+		a := v.Any()
+		_, jm := a.(json.Marshaler)
+		if err, ok := asError(a); ok && !jm {
+			s.appendString(err.Error())
+		} else {
+			return appendJSONMarshal(s.buf, a)
+		}
+	}
+	return nil
+}
+
+type jsonEncoder struct {
+	buf *bytes.Buffer
+	// Use a json.Encoder to avoid escaping HTML.
+	json *json.Encoder
+}
+
+var jsonEncoderPool = &sync.Pool{
+	New: func() any {
+		enc := &jsonEncoder{
+			buf: new(bytes.Buffer),
+		}
+		enc.json = json.NewEncoder(enc.buf)
+		enc.json.SetEscapeHTML(false)
+		return enc
+	},
+}
+
+func appendJSONMarshal(buf *Buffer, v any) error {
+	j := jsonEncoderPool.Get().(*jsonEncoder)
+	defer func() {
+		// To reduce peak allocation, return only smaller buffers to the pool.
+		const maxBufferSize = 16 << 10
+		if j.buf.Cap() > maxBufferSize {
+			return
+		}
+		j.buf.Reset()
+		jsonEncoderPool.Put(j)
+	}()
+
+	if err := j.json.Encode(v); err != nil {
+		return err
+	}
+
+	bs := j.buf.Bytes()
+	buf.Write(bs[:len(bs)-1]) // remove final newline
+	return nil
+}
+
+// appendEscapedJSONString escapes s for JSON and appends it to buf.
+// It does not surround the string in quotation marks.
+//
+// Modified from encoding/json/encode.go:encodeState.string,
+// with escapeHTML set to false.
+func appendEscapedJSONString(buf []byte, s string) []byte {
+	char := func(b byte) { buf = append(buf, b) }
+	str := func(s string) { buf = append(buf, s...) }
+
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if safeSet[b] {
+				i++
+				continue
+			}
+			if start < i {
+				str(s[start:i])
+			}
+			char('\\')
+			switch b {
+			case '\\', '"':
+				char(b)
+			case '\n':
+				char('n')
+			case '\r':
+				char('r')
+			case '\t':
+				char('t')
+			default:
+				// This encodes bytes < 0x20 except for \t, \n and \r.
+				str(`u00`)
+				char(hex[b>>4])
+				char(hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			if start < i {
+				str(s[start:i])
+			}
+			str(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 is LINE SEPARATOR.
+		// U+2029 is PARAGRAPH SEPARATOR.
+		// They are both technically valid characters in JSON strings,
+		// but don't work in JSONP, which has to be evaluated as JavaScript,
+		// and can lead to security holes there. It is valid JSON to
+		// escape them, so we do so unconditionally.
+		if c == '\u2028' || c == '\u2029' {
+			if start < i {
+				str(s[start:i])
+			}
+			str(`\u202`)
+			char(hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	if start < len(s) {
+		str(s[start:])
+	}
+	return buf
+}
+
+const hex = "0123456789abcdef"
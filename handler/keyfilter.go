@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"log/slog"
+	"path"
+	"strings"
+)
+
+// KeyFilter keeps or drops the attrs by their keys qualified by their groups, joined by dots,
+// such as "http.request.body", which suits the groups of third-party packages, such as the
+// LogValue of their types, with members too noisy to keep. The patterns are in the syntax of
+// path.Match and case insensitive, a "*" matching the dots too: "*.body" drops the body members
+// of every group. Only the attrs and the groups are filtered, a struct logged as a slog.Any
+// value is formatted whole, its fields aren't filtered.
+type KeyFilter struct {
+	// Keep are the patterns of the keys kept, all the keys are kept if empty. The groups of a
+	// kept key are kept, with their other members dropped, and all the members of a kept group
+	// are kept.
+	Keep []string
+	// Drop are the patterns of the keys dropped, even if they are kept by Keep.
+	Drop []string
+}
+
+// WithKeyFilter filters the attrs by f before they are formatted, the built-in ones excepted.
+// A group left empty is dropped.
+func WithKeyFilter(f *KeyFilter) Option {
+	return func(h *DefaultHandler) {
+		h.keys = f
+	}
+}
+
+// attr returns a filtered, in the groups, and whether it's kept.
+func (f *KeyFilter) attr(groups []string, a slog.Attr) (slog.Attr, bool) {
+	return f.filter(strings.ToLower(strings.Join(groups, ".")), a, false)
+}
+
+// filter filters a in the group qualified by prefix, kept is set if the group is kept whole.
+func (f *KeyFilter) filter(prefix string, a slog.Attr, kept bool) (slog.Attr, bool) {
+	key := prefix
+	if a.Key != "" {
+		if key != "" {
+			key += "."
+		}
+		key += strings.ToLower(a.Key)
+	}
+	if a.Key != "" && matchAny(f.Drop, key) {
+		return a, false
+	}
+	kept = kept || len(f.Keep) == 0 || a.Key != "" && matchAny(f.Keep, key)
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup {
+		return a, kept
+	}
+	var as []slog.Attr
+	for _, ga := range a.Value.Group() {
+		if ga, ok := f.filter(key, ga, kept); ok {
+			as = append(as, ga)
+		}
+	}
+	if len(as) == 0 {
+		return a, false
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(as...)}, true
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filterKey filters a, passed to WithAttrs or in a record, by the key filter of h.
+func (h *DefaultHandler) filterKey(a slog.Attr) (slog.Attr, bool) {
+	if h.keys == nil {
+		return a, true
+	}
+	return h.keys.attr(h.groups, a)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultLevel is the level of the loggers created by this package.
+var defaultLevel = NewDynamicLevel(slog.LevelDebug)
+
+// SetLevel sets the level of the loggers created by this package, such as NewDailyLogger,
+// at runtime.
+func SetLevel(l slog.Level) {
+	defaultLevel.SetLevel(l)
+}
+
+// DefaultLevel returns the DynamicLevel of the loggers created by this package, so it can be
+// shared with handlers created by the caller.
+func DefaultLevel() *DynamicLevel {
+	return defaultLevel
+}
+
+// DynamicLevel is a slog.Leveler which can be changed at runtime, permanently with SetLevel or
+// temporarily with SetLevelFor. It can be used as the Level of slog.HandlerOptions.
+type DynamicLevel struct {
+	v     slog.LevelVar
+	mu    sync.Mutex
+	base  slog.Level  // the level to revert to after a temporary level expires
+	timer *time.Timer // the timer of the temporary level, nil if none
+}
+
+// NewDynamicLevel creates a DynamicLevel starting at l.
+func NewDynamicLevel(l slog.Level) *DynamicLevel {
+	d := &DynamicLevel{base: l}
+	d.v.Set(l)
+	return d
+}
+
+// Level implements slog.Leveler.
+func (d *DynamicLevel) Level() slog.Level {
+	return d.v.Level()
+}
+
+// SetLevel sets the level permanently, cancelling a pending temporary level.
+func (d *DynamicLevel) SetLevel(l slog.Level) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopTimer()
+	d.base = l
+	d.v.Set(l)
+}
+
+// SetLevelFor sets the level for the duration dur, then reverts to the level set by SetLevel,
+// so a debug level switched on for an investigation can't be forgotten. A later SetLevelFor
+// replaces the earlier one and its expiry, they are not stacked.
+func (d *DynamicLevel) SetLevelFor(l slog.Level, dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopTimer()
+	d.v.Set(l)
+	var t *time.Timer
+	t = time.AfterFunc(dur, func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		// ignore a timer replaced while it was firing
+		if d.timer == t {
+			d.timer = nil
+			d.v.Set(d.base)
+		}
+	})
+	d.timer = t
+}
+
+// Temporary reports whether a temporary level is in effect.
+func (d *DynamicLevel) Temporary() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.timer != nil
+}
+
+func (d *DynamicLevel) stopTimer() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// LevelHandler returns an http.Handler controlling the level of the loggers created by this
+// package. GET returns the level as JSON, PUT or POST sets it from the "level" form value,
+// such as "DEBUG" or "INFO+2". An optional "for" value, such as "15m", makes the level
+// temporary.
+func LevelHandler() http.Handler {
+	return defaultLevel
+}
+
+// ServeHTTP implements LevelHandler for any DynamicLevel.
+func (d *DynamicLevel) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(req.FormValue("level"))); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if s := req.FormValue("for"); s != "" {
+			dur, err := time.ParseDuration(s)
+			if err != nil || dur <= 0 {
+				http.Error(w, "invalid duration "+s, http.StatusBadRequest)
+				return
+			}
+			d.SetLevelFor(l, dur)
+		} else {
+			d.SetLevel(l)
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Level     string `json:"level"`
+		Temporary bool   `json:"temporary"`
+	}{d.Level().String(), d.Temporary()})
+}
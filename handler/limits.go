@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Limits caps the sizes of the parts of the records, so an accidentally logged response body
+// doesn't write a line of megabytes that breaks the parsers downstream. A zero limit is no
+// limit.
+type Limits struct {
+	Message int // the max length of the message in bytes
+	// String is the max length of the string values in bytes, and of the text of the other
+	// values formatted as text, such as the structs and the errors, which become strings when
+	// they are truncated.
+	String int
+	Bytes  int // the max length of the []byte values
+	// Record is the max size of an encoded record in bytes: the attrs which would exceed it are
+	// dropped, and counted by an attr "!TRUNCATED" at the end of the record. The built-in attrs
+	// are always written, so a record may still exceed it.
+	Record int
+	// Marker is appended to the truncated messages, strings and byte slices, "...[truncated]"
+	// if empty.
+	Marker string
+}
+
+// WithLimits truncates the messages and the values of the records by l, and drops the attrs
+// exceeding its record size.
+func WithLimits(l *Limits) Option {
+	return func(h *DefaultHandler) {
+		h.limits = l
+	}
+}
+
+func (l *Limits) marker() string {
+	if l.Marker == "" {
+		return "...[truncated]"
+	}
+	return l.Marker
+}
+
+// message returns msg truncated to the max message length.
+func (l *Limits) message(msg string) string {
+	if l == nil || l.Message <= 0 || len(msg) <= l.Message {
+		return msg
+	}
+	return truncateString(msg, l.Message) + l.marker()
+}
+
+// value returns v truncated to the max length of its kind.
+func (l *Limits) value(v slog.Value) slog.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		if s := v.String(); l.String > 0 && len(s) > l.String {
+			return slog.StringValue(truncateString(s, l.String) + l.marker())
+		}
+	case slog.KindAny:
+		if bs, ok := byteSlice(v.Any()); ok {
+			if l.Bytes > 0 && len(bs) > l.Bytes {
+				return slog.AnyValue(append(bs[:l.Bytes:l.Bytes], l.marker()...))
+			}
+			return v
+		}
+		if l.String <= 0 {
+			return v
+		}
+		if s := anyText(v.Any()); len(s) > l.String {
+			return slog.StringValue(truncateString(s, l.String) + l.marker())
+		}
+	}
+	return v
+}
+
+// anyText returns x formatted as by the text format of the handler.
+func anyText(x any) string {
+	if tm, ok := x.(encoding.TextMarshaler); ok {
+		if data, err := tm.MarshalText(); err == nil {
+			return string(data)
+		}
+	}
+	if err, ok := asError(x); ok {
+		return err.Error()
+	}
+	return fmt.Sprintf("%+v", x)
+}
+
+// truncateString returns the first n bytes of s at most, without splitting a rune.
+func truncateString(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// appendTruncated appends the number of the attrs dropped by the record size limit, as a
+// top-level attr.
+func (s *handleState) appendTruncated() {
+	if s.truncated == 0 {
+		return
+	}
+	prefix := s.prefix
+	s.prefix = nil
+	s.appendKey("!TRUNCATED")
+	s.buf.WriteString(strconv.Itoa(s.truncated))
+	s.prefix = prefix
+}
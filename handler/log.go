@@ -1,36 +1,84 @@
 package handler
 
 import (
+	"io"
 	"log/slog"
 
 	"github.com/wytools/rlog/rotation"
 )
 
-// GetDefaultDailyLogger
+// GetDefaultDailyLogger is like NewDailyLogger but panics if the file can't be opened. The file is
+// closed by Shutdown.
 func GetDefaultDailyLogger(filename string, h, m int) *slog.Logger {
-	fileLog, err := rotation.NewDailyLogger(filename, h, m, false)
+	logger, _, err := NewDailyLogger(filename, h, m)
 	if err != nil {
 		panic(err)
 	}
+	return logger
+}
 
-	opts := slog.HandlerOptions{
-		AddSource:   true,
-		Level:       slog.LevelDebug,
-		ReplaceAttr: nil,
+// GetDefaultSizeLogger is like NewSizeLogger but panics if the file can't be opened. The file is
+// closed by Shutdown.
+func GetDefaultSizeLogger(filename string, size int64, number int) *slog.Logger {
+	logger, _, err := NewSizeLogger(filename, size, number)
+	if err != nil {
+		panic(err)
 	}
-	return slog.New(NewDefaultHandler(fileLog, &opts))
+	return logger
 }
 
-func GetDefaultSizeLogger(filename string, size int64, number int) *slog.Logger {
-	fileLog, err := rotation.NewSizeLogger(filename, size, number, true)
+// GetDefaultDailyLoggerCloser is like GetDefaultDailyLogger, and returns the closer of the file
+// too, for the programs closing it themselves rather than by Shutdown.
+func GetDefaultDailyLoggerCloser(filename string, h, m int) (*slog.Logger, io.Closer) {
+	logger, fileLog, err := NewDailyLogger(filename, h, m)
 	if err != nil {
 		panic(err)
 	}
+	return logger, fileLog
+}
+
+// GetDefaultSizeLoggerCloser is like GetDefaultSizeLogger, and returns the closer of the file
+// too, for the programs closing it themselves rather than by Shutdown.
+func GetDefaultSizeLoggerCloser(filename string, size int64, number int) (*slog.Logger, io.Closer) {
+	logger, fileLog, err := NewSizeLogger(filename, size, number)
+	if err != nil {
+		panic(err)
+	}
+	return logger, fileLog
+}
+
+// NewDailyLogger creates a logger writing to a daily rotation file, rotating at h:m.
+// The rotation.Logger is returned too, so it can be closed or rotated by the caller, it's also
+// registered to be closed by Shutdown until it's closed.
+func NewDailyLogger(filename string, h, m int) (*slog.Logger, *rotation.Logger, error) {
+	return newRegisteredLogger(filename, rotation.WithDailyAt(h, m))
+}
+
+// NewSizeLogger creates a logger writing to a size rotation file, rotating when the file
+// exceeds size bytes, with at most number files.
+// The rotation.Logger is returned too, so it can be closed or rotated by the caller, it's also
+// registered to be closed by Shutdown until it's closed.
+func NewSizeLogger(filename string, size int64, number int) (*slog.Logger, *rotation.Logger, error) {
+	return newRegisteredLogger(filename, rotation.WithMaxSize(size), rotation.WithMaxFiles(number), rotation.WithLock())
+}
+
+// newRegisteredLogger creates a logger writing to a rotation.Logger configured by opts, which is
+// registered to be closed by Shutdown until it's closed.
+func newRegisteredLogger(filename string, opts ...rotation.Option) (*slog.Logger, *rotation.Logger, error) {
+	var fileLog *rotation.Logger
+	opts = append(opts, rotation.WithOnClose(func() { Unregister(fileLog) }))
+	fileLog, err := rotation.New(filename, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	OnShutdown(fileLog)
+	return slog.New(NewDefaultHandler(fileLog, defaultOptions(), WithShutdown(nil))), fileLog, nil
+}
 
-	opts := slog.HandlerOptions{
+func defaultOptions() *slog.HandlerOptions {
+	return &slog.HandlerOptions{
 		AddSource:   true,
-		Level:       slog.LevelDebug,
+		Level:       defaultLevel,
 		ReplaceAttr: nil,
 	}
-	return slog.New(NewDefaultHandler(fileLog, &opts))
 }
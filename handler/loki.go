@@ -0,0 +1,340 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LokiOptions configures a LokiHandler.
+type LokiOptions struct {
+	URL string // the push API, such as "http://loki:3100/loki/api/v1/push"
+
+	// Labels are the keys of the attrs turned into the labels of the streams, such as "app"
+	// or "host". The key "level" is the level of the records. Only the attrs outside of any
+	// group are taken.
+	Labels []string
+	// StaticLabels are added to all the streams, such as the host name.
+	StaticLabels map[string]string
+	TenantID     string // sent as X-Scope-OrgID if set
+
+	BatchSize     int           // the number of records pushing a batch, 1000 if 0
+	FlushInterval time.Duration // the max delay before pushing a record, 1 second if 0
+	MaxRetries    int           // the retries of a failed push before the batch is dropped, 3 if 0
+	RetryBackoff  time.Duration // the delay before the first retry, doubling every retry, 500ms if 0
+	// MaxBuffered is the max number of records kept while a push is retried or slow, 10 batches
+	// if 0. The records over it are dropped, and counted by Dropped.
+	MaxBuffered int
+
+	Client  *http.Client // a client with a 10 seconds timeout if nil
+	OnError func(error)  // called when a batch is dropped
+}
+
+// LokiHandler batches the records and pushes them to the HTTP push API of Grafana Loki, in
+// streams labeled by the configured attrs. The lines are the records formatted by the text
+// handler without their time. The batches are pushed by a background goroutine, so Handle
+// never waits for Loki. The failed pushes are retried on 429 and 5xx responses and network
+// errors, with an exponential backoff. Handlers derived by WithAttrs and WithGroup share the
+// batch, Close pushes the last one, and Handle returns an error after it.
+type LokiHandler struct {
+	enc     *DefaultHandler
+	labels  map[string]string // the labels from WithAttrs
+	grouped bool              // WithGroup was called, the following attrs aren't labels
+	s       *lokiSink
+}
+
+type lokiSink struct {
+	LokiOptions
+	keys map[string]string // the label names of the label keys
+
+	mu      sync.Mutex
+	streams map[string]*lokiStream
+	count   int
+	closed  bool
+	dropped atomic.Uint64
+
+	flush   chan chan struct{} // requests a push, closing the channel when done
+	done    chan struct{}
+	closing sync.Once
+	wg      sync.WaitGroup
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewLokiHandler creates a LokiHandler pushing to opts.URL, and starts its goroutine.
+func NewLokiHandler(opts LokiOptions, hopts *slog.HandlerOptions, options ...Option) *LokiHandler {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = 3
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBuffered <= 0 {
+		opts.MaxBuffered = 10 * opts.BatchSize
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	s := &lokiSink{
+		LokiOptions: opts,
+		keys:        map[string]string{},
+		streams:     map[string]*lokiStream{},
+		flush:       make(chan chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	for _, k := range opts.Labels {
+		s.keys[k] = lokiLabelName(k)
+	}
+	s.wg.Add(1)
+	go s.run()
+	return &LokiHandler{enc: NewDefaultHandler(nil, hopts, options...), s: s}
+}
+
+func (h *LokiHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.enc.Enabled(ctx, l)
+}
+
+func (h *LokiHandler) Handle(ctx context.Context, r slog.Record) error {
+	labels := make(map[string]string, len(h.s.StaticLabels)+len(h.labels)+1)
+	for k, v := range h.s.StaticLabels {
+		labels[lokiLabelName(k)] = v
+	}
+	for k, v := range h.labels {
+		labels[k] = v
+	}
+	if name, ok := h.s.keys[slog.LevelKey]; ok {
+		labels[name] = strings.ToLower(r.Level.String())
+	}
+	if !h.grouped {
+		r.Attrs(func(a slog.Attr) bool {
+			if name, ok := h.s.keys[a.Key]; ok {
+				labels[name] = a.Value.Resolve().String()
+			}
+			return true
+		})
+	}
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	r.Time = time.Time{} // the time is the timestamp of the entry
+	buf := h.enc.encode(r)
+	line := strings.TrimSuffix(string(*buf), "\n")
+	buf.Free()
+	return h.s.add(labels, strconv.FormatInt(t.UnixNano(), 10), line)
+}
+
+func (h *LokiHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	h2 := &LokiHandler{enc: h.enc.WithAttrs(as).(*DefaultHandler), labels: h.labels, grouped: h.grouped, s: h.s}
+	if h.grouped {
+		return h2
+	}
+	copied := false
+	for _, a := range as {
+		if name, ok := h.s.keys[a.Key]; ok {
+			if !copied { // the labels of the parent are shared
+				copied = true
+				h2.labels = make(map[string]string, len(h.labels)+1)
+				for k, v := range h.labels {
+					h2.labels[k] = v
+				}
+			}
+			h2.labels[name] = a.Value.Resolve().String()
+		}
+	}
+	return h2
+}
+
+func (h *LokiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &LokiHandler{enc: h.enc.WithGroup(name).(*DefaultHandler), labels: h.labels, grouped: true, s: h.s}
+}
+
+// Flush pushes the kept records, and waits for the push.
+func (h *LokiHandler) Flush() {
+	done := make(chan struct{})
+	select {
+	case h.s.flush <- done:
+		<-done
+	case <-h.s.done:
+	}
+}
+
+// Dropped returns the number of records dropped as the buffer was full.
+func (h *LokiHandler) Dropped() uint64 {
+	return h.s.dropped.Load()
+}
+
+// Close pushes the kept records and stops the goroutine of the handler.
+func (h *LokiHandler) Close() error {
+	h.s.closing.Do(func() {
+		h.s.mu.Lock()
+		h.s.closed = true
+		h.s.mu.Unlock()
+		close(h.s.done)
+	})
+	h.s.wg.Wait()
+	return nil
+}
+
+// lokiLabelName replaces the characters not allowed in the label names by underscores.
+func lokiLabelName(k string) string {
+	b := []byte(k)
+	for i, c := range b {
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// add keeps an entry in its stream, and requests a push when the batch is full. It drops the
+// entry if the buffer is full.
+func (s *lokiSink) add(labels map[string]string, ts, line string) error {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var key strings.Builder
+	for _, k := range names {
+		key.WriteString(k + "=" + strconv.Quote(labels[k]) + ",")
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errors.New("rlog: loki handler is closed")
+	}
+	if s.count >= s.MaxBuffered {
+		s.mu.Unlock()
+		s.dropped.Add(1)
+		return nil
+	}
+	st, ok := s.streams[key.String()]
+	if !ok {
+		st = &lokiStream{Stream: labels}
+		s.streams[key.String()] = st
+	}
+	st.Values = append(st.Values, [2]string{ts, line})
+	s.count++
+	full := s.count >= s.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.flush <- nil:
+		default: // a push is already requested
+		}
+	}
+	return nil
+}
+
+func (s *lokiSink) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.push()
+		case done := <-s.flush:
+			s.push()
+			if done != nil {
+				close(done)
+			}
+		case <-s.done:
+			s.push()
+			return
+		}
+	}
+}
+
+// push sends the kept records, retrying while the error is temporary.
+func (s *lokiSink) push() {
+	s.mu.Lock()
+	if s.count == 0 {
+		s.mu.Unlock()
+		return
+	}
+	streams := make([]*lokiStream, 0, len(s.streams))
+	for _, st := range s.streams {
+		streams = append(streams, st)
+	}
+	s.streams = map[string]*lokiStream{}
+	s.count = 0
+	s.mu.Unlock()
+
+	body, err := json.Marshal(struct {
+		Streams []*lokiStream `json:"streams"`
+	}{streams})
+	if err != nil {
+		s.report(err)
+		return
+	}
+	backoff := s.RetryBackoff
+	for i := 0; ; i++ {
+		retry, err := s.send(body)
+		if err == nil {
+			return
+		}
+		if !retry || i >= s.MaxRetries {
+			s.report(err)
+			return
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.done:
+			// still retry while closing, but without waiting
+		}
+		backoff *= 2
+	}
+}
+
+// send posts body once, and reports whether a failure is worth a retry.
+func (s *lokiSink) send(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.TenantID)
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("rlog: loki push: %s", resp.Status)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+func (s *lokiSink) report(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
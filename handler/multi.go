@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+)
+
+// MultiHandler fans out every record to all of its handlers, such as a rotating file and stdout.
+type MultiHandler struct {
+	handlers []slog.Handler
+}
+
+// NewMultiHandler creates a MultiHandler writing to all the handlers.
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: handlers}
+}
+
+// Enabled reports whether any of the handlers is enabled for l.
+func (m *MultiHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range m.handlers {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes r to every enabled handler, the errors of all the handlers are joined.
+//
+// A record is encoded once for all the DefaultHandlers with the same formatting, such as a
+// file and a TCP shipper both writing JSON, and the same bytes are written to all their
+// writers. DefaultHandlers don't keep the record, so they get it without Clone, other
+// handlers get a Clone each. The encoded bytes belong to Handle and are reused after the
+// writers return, as the io.Writer contract requires: a writer keeping them must copy them.
+//
+// The sinks can redact differently with WithRedaction, such as the full values in the local
+// file and hashed ones in the sinks leaving the host. A record is then encoded once per
+// Redaction, the handlers sharing a *Redaction sharing the encoding.
+func (m *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	var encoded []encodedRecord
+	defer func() {
+		for _, e := range encoded {
+			e.buf.Free()
+		}
+	}()
+	for _, h := range m.handlers {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		var err error
+		if dh, ok := h.(*DefaultHandler); ok {
+			i := slices.IndexFunc(encoded, func(e encodedRecord) bool { return e.h.sameEncoding(dh) })
+			if i < 0 {
+				i = len(encoded)
+				encoded = append(encoded, encodedRecord{h: dh, buf: dh.encode(r)})
+			}
+			err = dh.write(*encoded[i].buf)
+		} else {
+			err = h.Handle(ctx, r.Clone())
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// encodedRecord is a record encoded by a DefaultHandler.
+type encodedRecord struct {
+	h   *DefaultHandler
+	buf *Buffer
+}
+
+func (m *MultiHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithAttrs(as)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+func (m *MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(m.handlers))
+	for i, h := range m.handlers {
+		handlers[i] = h.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers}
+}
+
+// FanoutWriter writes the same bytes to all of its writers, so a handler encodes a record once
+// for several sinks of the same format. Unlike io.MultiWriter, a failing writer doesn't stop
+// the writes to the others, the errors of all the writers are joined.
+type FanoutWriter struct {
+	writers []io.Writer
+}
+
+// NewFanoutWriter creates a FanoutWriter writing to all the writers.
+func NewFanoutWriter(writers ...io.Writer) *FanoutWriter {
+	return &FanoutWriter{writers: writers}
+}
+
+// Write writes p to every writer, p is shared and must not be kept by the writers.
+func (f *FanoutWriter) Write(p []byte) (int, error) {
+	var errs []error
+	for _, w := range f.writers {
+		if _, err := w.Write(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(p), errors.Join(errs...)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures a DefaultHandler created by NewDefaultHandler, NewJSONHandler or
+// NewConsoleHandler.
+type Option func(*DefaultHandler)
+
+// WithSeparators sets the separator between the fields of the text format, a space by default,
+// and the delimiter between the keys and the values of the attrs, "=" by default, e.g. "\t"
+// and ":" for the parsers expecting tab separated fields. The keys and values containing
+// them are quoted. The JSON format ignores them.
+func WithSeparators(sep, kvDelim string) Option {
+	return func(h *DefaultHandler) {
+		if sep != "" {
+			h.sep = sep
+		}
+		if kvDelim != "" {
+			h.kvDelim = kvDelim
+		}
+	}
+}
+
+// WithSourceLevels sets the levels of the records getting their source location, when
+// AddSource is set. By default only the debug records get it.
+func WithSourceLevels(levels ...slog.Level) Option {
+	return func(h *DefaultHandler) {
+		h.sourceLevels = levels
+	}
+}
+
+// WithSourceFrom gives the source location to the records at level l or above, when AddSource
+// is set, besides the levels set by WithSourceLevels, e.g. WithSourceFrom(slog.LevelWarn) for
+// the warnings and errors as well as the debug records.
+func WithSourceFrom(l slog.Level) Option {
+	return func(h *DefaultHandler) {
+		h.sourceFrom = &l
+	}
+}
+
+// WithTimeFormat sets the layout of the times, such as time.RFC3339 or time.RFC3339Nano for
+// the pipelines expecting RFC 3339 with the timezone offset, and their location, such as
+// time.Local. The precision is set by the layout. An empty layout keeps the default format,
+// UTC milliseconds, and a nil loc keeps UTC. The console handler formats the record times by
+// its Theme.TimeFormat instead, if it is set.
+func WithTimeFormat(layout string, loc *time.Location) Option {
+	return func(h *DefaultHandler) {
+		h.timeLayout = layout
+		h.timeLoc = loc
+	}
+}
+
+// WithRedaction applies r to the attrs and the message before they are formatted, like a
+// RedactHandler but within the encoding. Unlike a ReplaceAttr, it keeps the encode-once fanout
+// of MultiHandler, which groups its DefaultHandlers by their *Redaction, so every sink of a
+// fanout can have its own policy.
+func WithRedaction(r *Redaction) Option {
+	return func(h *DefaultHandler) {
+		h.redact = r
+	}
+}
@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Partition describes the directory layout of a PartitionHandler, such as
+// logs/2024/05/01/error/app.log for {Dir: "logs", DateLayout: "2006/01/02", Keys: {"level"},
+// Filename: "app.log"}, the layout expected by the lifecycle rules of object stores and by
+// the hive partitioned queries of Athena or DuckDB.
+type Partition struct {
+	Dir        string         // the root directory
+	DateLayout string         // the layout of the date directories, "2006/01/02" if empty
+	Location   *time.Location // the location of the dates, UTC if nil
+	Keys       []string       // the attrs giving the next directories, "level" for the record level
+	Hive       bool           // name the attr directories key=value, such as level=error
+	Filename   string         // the name of the files, "out.log" if empty
+
+	// NewHandler creates the handler writing the records of a partition to w,
+	// NewJSONHandler with nil options if nil.
+	NewHandler func(w io.Writer) slog.Handler
+}
+
+// PartitionHandler writes every record to the file of its partition, derived from the record
+// time and from selected attrs. Missing attrs give the directory "unknown". The files of a date
+// are closed when the records of a later date arrive. Handlers derived by WithAttrs and
+// WithGroup share the files, Close closes them all.
+type PartitionHandler struct {
+	p      *partitions
+	ops    []func(slog.Handler) slog.Handler // the WithAttrs and WithGroup calls to replay on every partition
+	values map[string]string                 // the values of the keys set by WithAttrs
+	prefix string                            // the qualified name of the groups, with a trailing dot
+
+	mu       sync.Mutex
+	date     string // the date of the handlers
+	handlers map[string]slog.Handler
+}
+
+type partitions struct {
+	Partition
+	mu    sync.Mutex
+	date  string // the latest date
+	files map[string]*os.File
+}
+
+// NewPartitionHandler creates a PartitionHandler writing to the files laid out by p.
+func NewPartitionHandler(p Partition) *PartitionHandler {
+	if p.DateLayout == "" {
+		p.DateLayout = "2006/01/02"
+	}
+	if p.Filename == "" {
+		p.Filename = "out.log"
+	}
+	if p.NewHandler == nil {
+		p.NewHandler = func(w io.Writer) slog.Handler { return NewJSONHandler(w, nil) }
+	}
+	return &PartitionHandler{
+		p:        &partitions{Partition: p, files: map[string]*os.File{}},
+		handlers: map[string]slog.Handler{},
+	}
+}
+
+// Enabled reports whether the handlers of the partitions are enabled for l.
+func (h *PartitionHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.handler("").Enabled(ctx, l)
+}
+
+func (h *PartitionHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if h.p.Location != nil {
+		t = t.In(h.p.Location)
+	} else {
+		t = t.UTC()
+	}
+	date := t.Format(h.p.DateLayout)
+	h.p.newDate(date)
+
+	dirs := []string{h.p.Dir, date}
+	for _, k := range h.p.Keys {
+		v := h.value(k, r)
+		if h.p.Hive {
+			v = k + "=" + v
+		}
+		dirs = append(dirs, v)
+	}
+	path := filepath.Join(append(dirs, h.p.Filename)...)
+
+	h.mu.Lock()
+	if h.date != date {
+		h.date, h.handlers = date, map[string]slog.Handler{}
+	}
+	h.mu.Unlock()
+	return h.handler(path).Handle(ctx, r)
+}
+
+// handler returns the handler of the partition file path, with the WithAttrs and WithGroup
+// calls replayed.
+func (h *PartitionHandler) handler(path string) slog.Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ph, ok := h.handlers[path]; ok {
+		return ph
+	}
+	var ph slog.Handler = h.p.NewHandler(&partitionWriter{p: h.p, path: path})
+	for _, op := range h.ops {
+		ph = op(ph)
+	}
+	h.handlers[path] = ph
+	return ph
+}
+
+// value returns the directory name given by the attr key of r.
+func (h *PartitionHandler) value(key string, r slog.Record) string {
+	if key == slog.LevelKey {
+		return strings.ToLower(r.Level.String())
+	}
+	v, ok := h.values[key]
+	r.Attrs(func(a slog.Attr) bool {
+		if h.prefix+a.Key == key {
+			v, ok = a.Value.Resolve().String(), true
+			return false
+		}
+		return true
+	})
+	if !ok || v == "" {
+		return "unknown"
+	}
+	// keep the values from escaping their directory
+	v = strings.NewReplacer("/", "_", `\`, "_").Replace(v)
+	if v == "." || v == ".." {
+		v = "_"
+	}
+	return v
+}
+
+func (h *PartitionHandler) derive(op func(slog.Handler) slog.Handler) *PartitionHandler {
+	return &PartitionHandler{
+		p:        h.p,
+		ops:      append(h.ops[:len(h.ops):len(h.ops)], op),
+		values:   h.values,
+		prefix:   h.prefix,
+		handlers: map[string]slog.Handler{},
+	}
+}
+
+func (h *PartitionHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	h2 := h.derive(func(ph slog.Handler) slog.Handler { return ph.WithAttrs(as) })
+	copied := false
+	for _, a := range as {
+		if !slices.Contains(h.p.Keys, h.prefix+a.Key) {
+			continue
+		}
+		if !copied {
+			h2.values = maps.Clone(h.values)
+			if h2.values == nil {
+				h2.values = map[string]string{}
+			}
+			copied = true
+		}
+		h2.values[h.prefix+a.Key] = a.Value.Resolve().String()
+	}
+	return h2
+}
+
+func (h *PartitionHandler) WithGroup(name string) slog.Handler {
+	h2 := h.derive(func(ph slog.Handler) slog.Handler { return ph.WithGroup(name) })
+	h2.prefix += name + "."
+	return h2
+}
+
+// Close closes all the files of the partitions.
+func (h *PartitionHandler) Close() error {
+	h.p.mu.Lock()
+	defer h.p.mu.Unlock()
+	return h.p.closeFiles()
+}
+
+// newDate closes the files of the earlier dates when the first record of a later date arrives.
+func (p *partitions) newDate(date string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if date > p.date {
+		if p.date != "" {
+			p.closeFiles()
+		}
+		p.date = date
+	}
+}
+
+func (p *partitions) closeFiles() error {
+	var err error
+	for path, f := range p.files {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		delete(p.files, path)
+	}
+	return err
+}
+
+// partitionWriter writes to the file of a partition, opened on demand, so a closed partition
+// is reopened by its late records.
+type partitionWriter struct {
+	p    *partitions
+	path string
+}
+
+func (w *partitionWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	f, ok := w.p.files[w.path]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(w.path), os.ModePerm); err != nil {
+			return 0, err
+		}
+		var err error
+		f, err = os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+		if err != nil {
+			return 0, err
+		}
+		w.p.files[w.path] = f
+	}
+	return f.Write(b)
+}
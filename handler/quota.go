@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// QuotaPolicy decides what happens to the records over a quota.
+type QuotaPolicy int
+
+const (
+	QuotaDrop   QuotaPolicy = iota // drop all the records over the quota
+	QuotaSample                    // keep one of every SampleEvery records over the quota
+)
+
+// Quota limits the volume logged by a component, protecting the shared log files and pipelines
+// from a single noisy one.
+type Quota struct {
+	Name                string      // the name of the limited component, passed to OnExhausted
+	MaxRecordsPerMinute int64       // 0 for no record limit
+	MaxBytesPerDay      int64       // 0 for no byte limit, the bytes are estimated from the message and attrs
+	Policy              QuotaPolicy // what to do over the quota
+	SampleEvery         int64       // the sampling rate of QuotaSample, 100 if 0
+
+	// OnExhausted is called once per minute or per day, when the record or byte quota
+	// is exhausted. kind is "records" or "bytes".
+	OnExhausted func(name, kind string)
+}
+
+// QuotaHandler enforces a Quota on the records passed to the inner handler.
+// Handlers derived by WithAttrs and WithGroup share the quota.
+type QuotaHandler struct {
+	inner slog.Handler
+	q     *quotaState
+}
+
+type quotaState struct {
+	Quota
+	mu        sync.Mutex
+	minute    int64 // unix minute of the record counter
+	records   int64
+	day       int64 // unix day of the byte counter
+	bytes     int64
+	overCount int64 // the number of records over the quota, for sampling
+}
+
+// NewQuotaHandler creates a QuotaHandler passing the records within q to inner.
+func NewQuotaHandler(inner slog.Handler, q Quota) *QuotaHandler {
+	if q.SampleEvery <= 0 {
+		q.SampleEvery = 100
+	}
+	return &QuotaHandler{inner: inner, q: &quotaState{Quota: q}}
+}
+
+func (h *QuotaHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *QuotaHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.q.allow(time.Now(), recordSize(r)) {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *QuotaHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &QuotaHandler{inner: h.inner.WithAttrs(as), q: h.q}
+}
+
+func (h *QuotaHandler) WithGroup(name string) slog.Handler {
+	return &QuotaHandler{inner: h.inner.WithGroup(name), q: h.q}
+}
+
+// allow counts a record of size bytes and reports whether it is within the quota.
+func (q *quotaState) allow(now time.Time, size int64) bool {
+	var exhausted string
+	q.mu.Lock()
+	if m := now.Unix() / 60; m != q.minute {
+		q.minute, q.records = m, 0
+	}
+	if d := now.Unix() / 86400; d != q.day {
+		q.day, q.bytes = d, 0
+	}
+	q.records++
+	q.bytes += size
+	over := false
+	if q.MaxRecordsPerMinute > 0 && q.records > q.MaxRecordsPerMinute {
+		over = true
+		if q.records == q.MaxRecordsPerMinute+1 {
+			exhausted = "records"
+		}
+	}
+	if q.MaxBytesPerDay > 0 && q.bytes > q.MaxBytesPerDay {
+		over = true
+		if q.bytes-size <= q.MaxBytesPerDay {
+			exhausted = "bytes"
+		}
+	}
+	allowed := !over
+	if over && q.Policy == QuotaSample {
+		q.overCount++
+		allowed = q.overCount%q.SampleEvery == 1
+	}
+	q.mu.Unlock()
+
+	if exhausted != "" && q.OnExhausted != nil {
+		q.OnExhausted(q.Name, exhausted)
+	}
+	return allowed
+}
+
+// recordSize estimates the formatted size of r without formatting it.
+func recordSize(r slog.Record) int64 {
+	n := int64(len(r.Message)) + 32 // time, level and separators
+	r.Attrs(func(a slog.Attr) bool {
+		n += attrSize(a)
+		return true
+	})
+	return n
+}
+
+func attrSize(a slog.Attr) int64 {
+	n := int64(len(a.Key)) + 2
+	switch a.Value.Kind() {
+	case slog.KindString:
+		n += int64(len(a.Value.String()))
+	case slog.KindGroup:
+		for _, aa := range a.Value.Group() {
+			n += attrSize(aa)
+		}
+	default:
+		n += 8
+	}
+	return n
+}
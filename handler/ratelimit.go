@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimitOptions configures a RateLimiter.
+type RateLimitOptions struct {
+	Rate  float64 // the records per second passed per key
+	Burst int     // the records passed at once after a quiet period, Rate rounded up if 0
+
+	// Key returns the key of a record, the records with the same key are limited together. All
+	// the records are limited together if nil. See RateKeyMessage and RateKeyAttr.
+	Key func(r slog.Record) string
+	// MaxKeys is the max number of keys tracked, 10000 if 0. When it's reached, the keys
+	// tracked are forgotten, and start again with a full burst.
+	MaxKeys int
+
+	// ReportInterval is the min interval between the warnings logged to the inner handler with
+	// the number of records suppressed since the last one, per key, 10s if 0, no warnings if
+	// negative.
+	ReportInterval time.Duration
+}
+
+// RateKeyMessage limits the records by level and message.
+func RateKeyMessage(r slog.Record) string {
+	return r.Level.String() + " " + r.Message
+}
+
+// RateKeyAttr limits the records by the value of their attr key, such as "user" or "peer". The
+// records without the attr are limited together.
+func RateKeyAttr(key string) func(r slog.Record) string {
+	return func(r slog.Record) string {
+		var v string
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == key {
+				v = a.Value.Resolve().String()
+				return false
+			}
+			return true
+		})
+		return v
+	}
+}
+
+// RateLimiter caps the records passed to the inner handler by a token bucket per key, so a retry
+// loop logging thousands of errors per second doesn't fill the disk. Unlike the Sampler, the
+// suppressed records are reported by periodic warnings "rlog: suppressed log records" with the
+// key and the number of records. Handlers derived by WithAttrs and WithGroup share the buckets.
+type RateLimiter struct {
+	inner slog.Handler
+	rl    *rateLimit
+}
+
+type rateLimit struct {
+	inner   slog.Handler // the handler of the reports
+	rate    float64
+	burst   float64
+	key     func(slog.Record) string
+	maxKeys int
+	every   time.Duration
+
+	mu         sync.Mutex
+	buckets    map[string]*rateBucket
+	suppressed map[string]uint64 // the records suppressed since the last report, per key
+	scheduled  bool              // a report is scheduled
+	lastReport time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a RateLimiter passing the records within opts to inner. It's registered
+// to be closed by Shutdown, which reports the last suppressed records.
+func NewRateLimiter(inner slog.Handler, opts RateLimitOptions) *RateLimiter {
+	if opts.Burst <= 0 {
+		opts.Burst = max(int(opts.Rate+0.999), 1)
+	}
+	if opts.MaxKeys <= 0 {
+		opts.MaxKeys = 10000
+	}
+	if opts.ReportInterval == 0 {
+		opts.ReportInterval = 10 * time.Second
+	}
+	h := &RateLimiter{inner: inner, rl: &rateLimit{
+		inner:      inner,
+		rate:       opts.Rate,
+		burst:      float64(opts.Burst),
+		key:        opts.Key,
+		maxKeys:    opts.MaxKeys,
+		every:      opts.ReportInterval,
+		buckets:    map[string]*rateBucket{},
+		suppressed: map[string]uint64{},
+		lastReport: time.Now(),
+	}}
+	OnShutdown(h.rl)
+	return h
+}
+
+func (h *RateLimiter) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *RateLimiter) Handle(ctx context.Context, r slog.Record) error {
+	if !h.rl.allow(r) {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *RateLimiter) WithAttrs(as []slog.Attr) slog.Handler {
+	return &RateLimiter{inner: h.inner.WithAttrs(as), rl: h.rl}
+}
+
+func (h *RateLimiter) WithGroup(name string) slog.Handler {
+	return &RateLimiter{inner: h.inner.WithGroup(name), rl: h.rl}
+}
+
+// Close reports the records suppressed since the last report at once.
+func (h *RateLimiter) Close() error {
+	return h.rl.Close()
+}
+
+// Close reports the suppressed records, and unregisters rl from Shutdown.
+func (rl *rateLimit) Close() error {
+	Unregister(rl)
+	return rl.report(true)
+}
+
+// allow takes a token of the bucket of r and reports whether there was one, counting r as
+// suppressed otherwise.
+func (rl *rateLimit) allow(r slog.Record) bool {
+	var key string
+	if rl.key != nil {
+		key = rl.key(r)
+	}
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	b, ok := rl.buckets[key]
+	if !ok {
+		if len(rl.buckets) >= rl.maxKeys {
+			clear(rl.buckets)
+		}
+		b = &rateBucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*rl.rate, rl.burst)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true
+	}
+	if rl.every < 0 {
+		return false
+	}
+	if len(rl.suppressed) < rl.maxKeys || rl.suppressed[key] > 0 {
+		rl.suppressed[key]++
+	} else {
+		rl.suppressed[""]++
+	}
+	if !rl.scheduled {
+		rl.scheduled = true
+		time.AfterFunc(time.Until(rl.lastReport.Add(rl.every)), func() { rl.report(false) })
+	}
+	return false
+}
+
+// report logs the records suppressed since the last report, one warning per key. The last
+// report, on closing, doesn't check the inner handler is enabled, as the handlers of WithShutdown
+// are disabled by then, like the repeats of a DedupHandler.
+func (rl *rateLimit) report(closing bool) error {
+	rl.mu.Lock()
+	suppressed := rl.suppressed
+	rl.suppressed = map[string]uint64{}
+	rl.scheduled = false
+	rl.lastReport = time.Now()
+	rl.mu.Unlock()
+
+	ctx := context.Background()
+	if len(suppressed) == 0 || !closing && !rl.inner.Enabled(ctx, slog.LevelWarn) {
+		return nil
+	}
+	keys := make([]string, 0, len(suppressed))
+	for k := range suppressed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, k := range keys {
+		r := slog.NewRecord(time.Now(), slog.LevelWarn, "rlog: suppressed log records", 0)
+		if rl.key != nil {
+			r.AddAttrs(slog.String("key", k))
+		}
+		r.AddAttrs(slog.Uint64("suppressed", suppressed[k]))
+		if err := rl.inner.Handle(ctx, r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Redaction masks the secrets and the personal data of the attrs before they are formatted.
+type Redaction struct {
+	// Keys are the patterns of the keys whose values are masked entirely, in the syntax of
+	// path.Match and case insensitive, such as "password", "*_token" or "ssn".
+	Keys []string
+	// Values are the patterns of the substrings masked in the string values, the text of the
+	// other values and the messages, such as the card numbers or the email addresses.
+	Values []*regexp.Regexp
+	// Hash replaces the values by "hmac-sha256:" and the first 16 hex digits of their
+	// HMAC-SHA256 keyed by HashKey, so they can still be correlated, instead of Mask. Without
+	// HashKey, the values are masked, as the hashes of the unkeyed guesses would reveal them.
+	Hash bool
+	// HashKey is the secret key of Hash.
+	HashKey []byte
+	// Mask replaces the values, "***" if empty.
+	Mask string
+}
+
+// RedactHandler applies a Redaction to the attrs of the records, including the ones added by
+// WithAttrs, before passing them to the inner handler.
+type RedactHandler struct {
+	inner slog.Handler
+	r     *Redaction
+}
+
+// NewRedactHandler creates a RedactHandler passing the redacted records to inner.
+func NewRedactHandler(inner slog.Handler, r *Redaction) *RedactHandler {
+	return &RedactHandler{inner: inner, r: r}
+}
+
+func (h *RedactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *RedactHandler) Handle(ctx context.Context, r slog.Record) error {
+	r2 := slog.NewRecord(r.Time, r.Level, h.r.message(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		r2.AddAttrs(h.r.Attr(a))
+		return true
+	})
+	return h.inner.Handle(ctx, r2)
+}
+
+func (h *RedactHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	as2 := make([]slog.Attr, len(as))
+	for i, a := range as {
+		as2[i] = h.r.Attr(a)
+	}
+	return &RedactHandler{inner: h.inner.WithAttrs(as2), r: h.r}
+}
+
+func (h *RedactHandler) WithGroup(name string) slog.Handler {
+	return &RedactHandler{inner: h.inner.WithGroup(name), r: h.r}
+}
+
+// ReplaceAttr redacts a, it can be used as the ReplaceAttr of slog.HandlerOptions instead of
+// a RedactHandler.
+func (r *Redaction) ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	return r.Attr(a)
+}
+
+// Attr returns a redacted, the members of groups included.
+func (r *Redaction) Attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch {
+	case v.Kind() == slog.KindGroup:
+		as := v.Group()
+		as2 := make([]slog.Attr, len(as))
+		for i, ga := range as {
+			as2[i] = r.Attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(as2...)}
+	case r.matchKey(a.Key):
+		return slog.String(a.Key, r.replace(v.String()))
+	case len(r.Values) > 0 && v.Kind() == slog.KindString:
+		return slog.String(a.Key, r.replaceValues(v.String()))
+	case len(r.Values) > 0 && v.Kind() == slog.KindAny:
+		// the values formatted as text, such as the errors and the structs, are replaced by
+		// their redacted text only if it's changed
+		s := v.String()
+		if s2 := r.replaceValues(s); s2 != s {
+			return slog.String(a.Key, s2)
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// message returns the message of a record redacted by the patterns of Values.
+func (r *Redaction) message(msg string) string {
+	if r == nil {
+		return msg
+	}
+	return r.replaceValues(msg)
+}
+
+func (r *Redaction) replaceValues(s string) string {
+	for _, re := range r.Values {
+		s = re.ReplaceAllStringFunc(s, r.replace)
+	}
+	return s
+}
+
+func (r *Redaction) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range r.Keys {
+		if ok, _ := path.Match(strings.ToLower(p), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redaction) replace(s string) string {
+	if r.Hash && len(r.HashKey) > 0 {
+		mac := hmac.New(sha256.New, r.HashKey)
+		mac.Write([]byte(s))
+		return fmt.Sprintf("hmac-sha256:%x", mac.Sum(nil)[:8])
+	}
+	if r.Mask == "" {
+		return "***"
+	}
+	return r.Mask
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReorderHandler holds the records for a short window and passes them to the inner handler
+// sorted by time, so the records of concurrent goroutines, which may reach a lock-free or
+// async writer out of order, are written in chronological order. A record is passed once the
+// window has passed since its time, the records arriving later than that are passed at once.
+//
+// As BatchHandler, it encodes the records at once if the inner handler is a DefaultHandler.
+// The errors of the inner handler are returned by the next Handle. Handlers derived by
+// WithAttrs and WithGroup share the window, Close passes the held records, and the records
+// handled after Close are passed at once.
+type ReorderHandler struct {
+	inner slog.Handler
+	w     *reorderWindow
+}
+
+type reorderWindow struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	records []reorderRecord
+	err     error // the error of the last pass by the timer, returned by the next Handle
+	closed  bool  // the records are passed at once
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type reorderRecord struct {
+	batchRecord
+	t time.Time
+}
+
+// NewReorderHandler creates a ReorderHandler passing the records to inner sorted within
+// window, such as 5ms.
+func NewReorderHandler(inner slog.Handler, window time.Duration) *ReorderHandler {
+	w := &reorderWindow{
+		window: window,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	return &ReorderHandler{inner: inner, w: w}
+}
+
+func (h *ReorderHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *ReorderHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if time.Since(t) >= h.w.window {
+		// too late to be sorted, the records before it were passed already
+		h.w.mu.Lock()
+		defer h.w.mu.Unlock()
+		return errors.Join(h.w.takeErr(), h.inner.Handle(ctx, r))
+	}
+	rr := reorderRecord{batchRecord: batchRecord{h: h.inner}, t: t}
+	if dh, ok := h.inner.(*DefaultHandler); ok {
+		rr.buf = dh.encode(r)
+	} else {
+		rr.ctx, rr.r = ctx, r.Clone()
+	}
+
+	h.w.mu.Lock()
+	defer h.w.mu.Unlock()
+	if h.w.closed {
+		// closed, the held records were passed already
+		return errors.Join(h.w.takeErr(), rr.pass())
+	}
+	h.w.records = append(h.w.records, rr)
+	return h.w.takeErr()
+}
+
+func (h *ReorderHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &ReorderHandler{inner: h.inner.WithAttrs(as), w: h.w}
+}
+
+func (h *ReorderHandler) WithGroup(name string) slog.Handler {
+	return &ReorderHandler{inner: h.inner.WithGroup(name), w: h.w}
+}
+
+// Close stops the timer, and passes all the held records to the inner handler.
+func (h *ReorderHandler) Close() error {
+	select {
+	case <-h.w.stop:
+		return nil
+	default:
+	}
+	close(h.w.stop)
+	<-h.w.done
+	h.w.mu.Lock()
+	defer h.w.mu.Unlock()
+	h.w.closed = true
+	return errors.Join(h.w.takeErr(), h.w.pass(time.Time{}))
+}
+
+func (w *reorderWindow) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(max(w.window/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.pass(time.Now().Add(-w.window)); err != nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// pass passes the records up to the time until, or all of them if until is zero, sorted by
+// time. It's called with w.mu held.
+func (w *reorderWindow) pass(until time.Time) error {
+	sort.SliceStable(w.records, func(i, j int) bool { return w.records[i].t.Before(w.records[j].t) })
+	n := len(w.records)
+	if !until.IsZero() {
+		n = sort.Search(len(w.records), func(i int) bool { return w.records[i].t.After(until) })
+	}
+	var errs []error
+	for i, rr := range w.records[:n] {
+		if err := rr.pass(); err != nil {
+			errs = append(errs, err)
+		}
+		w.records[i] = reorderRecord{} // release the record for GC
+	}
+	w.records = append(w.records[:0], w.records[n:]...)
+	return errors.Join(errs...)
+}
+
+// pass passes the record to its handler.
+func (rr reorderRecord) pass() error {
+	if rr.buf != nil {
+		defer rr.buf.Free()
+		return rr.h.(*DefaultHandler).write(*rr.buf)
+	}
+	return rr.h.Handle(rr.ctx, rr.r)
+}
+
+func (w *reorderWindow) takeErr() error {
+	err := w.err
+	w.err = nil
+	return err
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wytools/rlog/rotation"
+)
+
+// LevelRouter dispatches records to different handlers by level. Every handler is bound to a
+// minimum level, a record is handled by all the handlers whose minimum level it reaches.
+// For example, with {INFO: app, WARN: errs}, INFO records go to app, and WARN and ERROR
+// records go to both app and errs.
+type LevelRouter struct {
+	routes []levelRoute // sorted by level
+}
+
+type levelRoute struct {
+	level   slog.Level
+	handler slog.Handler
+}
+
+// NewLevelRouter creates a LevelRouter from the handlers keyed by their minimum levels.
+func NewLevelRouter(routes map[slog.Level]slog.Handler) *LevelRouter {
+	r := &LevelRouter{}
+	for l, h := range routes {
+		r.routes = append(r.routes, levelRoute{level: l, handler: h})
+	}
+	sort.Slice(r.routes, func(i, j int) bool { return r.routes[i].level < r.routes[j].level })
+	return r
+}
+
+func (r *LevelRouter) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, rt := range r.routes {
+		if l >= rt.level && rt.handler.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LevelRouter) Handle(ctx context.Context, rec slog.Record) error {
+	var errs []error
+	for _, rt := range r.routes {
+		if rec.Level < rt.level {
+			break
+		}
+		if rt.handler.Enabled(ctx, rec.Level) {
+			if err := rt.handler.Handle(ctx, rec.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *LevelRouter) WithAttrs(as []slog.Attr) slog.Handler {
+	r2 := &LevelRouter{routes: make([]levelRoute, len(r.routes))}
+	for i, rt := range r.routes {
+		r2.routes[i] = levelRoute{level: rt.level, handler: rt.handler.WithAttrs(as)}
+	}
+	return r2
+}
+
+func (r *LevelRouter) WithGroup(name string) slog.Handler {
+	r2 := &LevelRouter{routes: make([]levelRoute, len(r.routes))}
+	for i, rt := range r.routes {
+		r2.routes[i] = levelRoute{level: rt.level, handler: rt.handler.WithGroup(name)}
+	}
+	return r2
+}
+
+// NewLevelFileRouter creates a LevelRouter writing to a daily rotation file per level of
+// retention, each keeping its files for its own duration, e.g. {INFO: 7 days, ERROR: 90 days}.
+// The level names are inserted in filename, such as app_info.log and app_error.log. The opts
+// are applied to every rotation.Logger, after the daily rotation at 00:00 and before the
+// retention. The rotation.Loggers are returned too, so they can be closed by the caller.
+func NewLevelFileRouter(filename string, retention map[slog.Level]time.Duration, opts ...rotation.Option) (*LevelRouter, []*rotation.Logger, error) {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	routes := map[slog.Level]slog.Handler{}
+	var loggers []*rotation.Logger
+	for l, d := range retention {
+		name := base + "_" + strings.ToLower(l.String()) + ext
+		lopts := append([]rotation.Option{rotation.WithDailyAt(0, 0)}, opts...)
+		fileLog, err := rotation.New(name, append(lopts, rotation.WithMaxAge(d))...)
+		if err != nil {
+			for _, fl := range loggers {
+				fl.Close()
+			}
+			return nil, nil, err
+		}
+		loggers = append(loggers, fileLog)
+		routes[l] = NewDefaultHandler(fileLog, defaultOptions())
+	}
+	return NewLevelRouter(routes), loggers, nil
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const samplerCounters = 4096 // the number of counters of a Sampler, per level
+
+// Sampler limits the records with the same level and message, like the sampler of zap:
+// in every second, the first records are passed to the inner handler, then one of every
+// thereafter records. It keeps hot loops from blowing out the rotation budget.
+//
+// The messages are counted by hash in a fixed number of counters, so distinct messages
+// sharing a counter are sampled together. Handlers derived by WithAttrs and WithGroup share
+// the counters.
+type Sampler struct {
+	inner      slog.Handler
+	first      uint64
+	thereafter uint64
+	counters   *[4][samplerCounters]samplerCounter // per level: debug, info, warn and error
+}
+
+type samplerCounter struct {
+	second atomic.Int64 // unix second of the count
+	n      atomic.Uint64
+}
+
+// NewSampler creates a Sampler passing to inner the first records of every second with the
+// same level and message, then every thereafter-th one. If thereafter is 0, all the records
+// after the first ones are dropped.
+func NewSampler(inner slog.Handler, first, thereafter int) *Sampler {
+	return &Sampler{
+		inner:      inner,
+		first:      uint64(max(first, 0)),
+		thereafter: uint64(max(thereafter, 0)),
+		counters:   &[4][samplerCounters]samplerCounter{},
+	}
+}
+
+func (s *Sampler) Enabled(ctx context.Context, l slog.Level) bool {
+	return s.inner.Enabled(ctx, l)
+}
+
+func (s *Sampler) Handle(ctx context.Context, r slog.Record) error {
+	if !s.sample(r.Time, r.Level, r.Message) {
+		return nil
+	}
+	return s.inner.Handle(ctx, r)
+}
+
+func (s *Sampler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &Sampler{inner: s.inner.WithAttrs(as), first: s.first, thereafter: s.thereafter, counters: s.counters}
+}
+
+func (s *Sampler) WithGroup(name string) slog.Handler {
+	return &Sampler{inner: s.inner.WithGroup(name), first: s.first, thereafter: s.thereafter, counters: s.counters}
+}
+
+// sample counts the record and reports whether it is kept.
+func (s *Sampler) sample(t time.Time, l slog.Level, msg string) bool {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	h := fnv.New32a()
+	h.Write([]byte(msg))
+	c := &s.counters[samplerLevel(l)][h.Sum32()%samplerCounters]
+
+	sec := t.Unix()
+	if old := c.second.Load(); old != sec && c.second.CompareAndSwap(old, sec) {
+		c.n.Store(0)
+	}
+	n := c.n.Add(1)
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first)%s.thereafter == 0
+}
+
+func samplerLevel(l slog.Level) int {
+	switch {
+	case l < slog.LevelInfo:
+		return 0
+	case l < slog.LevelWarn:
+		return 1
+	case l < slog.LevelError:
+		return 2
+	default:
+		return 3
+	}
+}
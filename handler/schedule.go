@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// LevelWindow is a period of time with its own level.
+//
+// A recurring window is set by From and To, the times of day as durations since midnight,
+// such as 9*time.Hour, on the Weekdays (every day if empty). A window crossing midnight has
+// From after To. A one-off window, such as a maintenance window, is set by Start and End
+// instead, and it takes precedence over the recurring fields. A zero Start or End leaves the
+// window open on that side, such as a window from Start on.
+type LevelWindow struct {
+	Weekdays []time.Weekday
+	From, To time.Duration
+
+	Start, End time.Time
+
+	Level slog.Level
+}
+
+// LevelSchedule is a slog.Leveler whose level changes over time according to its windows,
+// so verbose logging can be planned and is always reverted automatically. It can be used
+// directly as the Level of slog.HandlerOptions.
+type LevelSchedule struct {
+	Default  slog.Level     // the level out of all windows
+	Windows  []LevelWindow  // the first matching window gives the level
+	Location *time.Location // the location of the recurring windows, time.Local if nil
+}
+
+// Level implements slog.Leveler.
+func (s *LevelSchedule) Level() slog.Level {
+	return s.LevelAt(time.Now())
+}
+
+// LevelAt returns the level at the time t.
+func (s *LevelSchedule) LevelAt(t time.Time) slog.Level {
+	for _, w := range s.Windows {
+		if w.contains(t, s.Location) {
+			return w.Level
+		}
+	}
+	return s.Default
+}
+
+func (w *LevelWindow) contains(t time.Time, loc *time.Location) bool {
+	if !w.Start.IsZero() || !w.End.IsZero() {
+		return !t.Before(w.Start) && (w.End.IsZero() || t.Before(w.End))
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	y, m, d := t.Date()
+	tod := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, loc))
+	day := t.Weekday()
+	if w.From <= w.To {
+		return w.onDay(day) && tod >= w.From && tod < w.To
+	}
+	// crossing midnight: the part after midnight belongs to the window started the day before
+	if tod >= w.From {
+		return w.onDay(day)
+	}
+	return tod < w.To && w.onDay((day+6)%7)
+}
+
+func (w *LevelWindow) onDay(day time.Weekday) bool {
+	return len(w.Weekdays) == 0 || slices.Contains(w.Weekdays, day)
+}
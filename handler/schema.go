@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// SchemaPolicy decides what happens to the records violating a Schema.
+type SchemaPolicy int
+
+const (
+	SchemaReport SchemaPolicy = iota // pass the record and report the violation
+	SchemaDrop                       // drop the record and report the violation
+	SchemaPanic                      // panic, for the tests and the dev mode
+)
+
+// Schema is the log contract of a service: the allowed keys of the attrs with their kinds.
+// The keys in groups are qualified by the group names, such as "req.method".
+type Schema struct {
+	Keys   map[string]slog.Kind // the allowed keys, slog.KindAny allows any value
+	Policy SchemaPolicy
+
+	// OnViolation is called for every violation, with the message of the record and the
+	// description of the violation, e.g. to count them or to log them to diagnostics.
+	OnViolation func(msg string, err error)
+}
+
+// SchemaError describes a violation of a Schema.
+type SchemaError struct {
+	Key   string
+	Kind  slog.Kind // the kind of the value
+	Want  slog.Kind // the kind of the schema, unset if the key is unknown
+	Known bool      // the key is in the schema
+}
+
+func (e *SchemaError) Error() string {
+	if !e.Known {
+		return fmt.Sprintf("rlog: unknown key %q", e.Key)
+	}
+	return fmt.Sprintf("rlog: key %q is %s, want %s", e.Key, e.Kind, e.Want)
+}
+
+// SchemaHandler validates the attrs of the records against a Schema before passing them to the
+// inner handler. The attrs added by WithAttrs are validated once, when they are added.
+type SchemaHandler struct {
+	inner  slog.Handler
+	schema *Schema
+	prefix string // the qualified name of the groups opened by WithGroup, with a trailing dot
+}
+
+// NewSchemaHandler creates a SchemaHandler passing the records to inner.
+func NewSchemaHandler(inner slog.Handler, schema *Schema) *SchemaHandler {
+	return &SchemaHandler{inner: inner, schema: schema}
+}
+
+func (h *SchemaHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *SchemaHandler) Handle(ctx context.Context, r slog.Record) error {
+	ok := true
+	r.Attrs(func(a slog.Attr) bool {
+		ok = h.validate(r.Message, h.prefix, a) && ok
+		return ok || h.schema.Policy != SchemaDrop
+	})
+	if !ok && h.schema.Policy == SchemaDrop {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *SchemaHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	for _, a := range as {
+		// the attrs can't be dropped, the violations are reported only
+		h.validate("", h.prefix, a)
+	}
+	return &SchemaHandler{inner: h.inner.WithAttrs(as), schema: h.schema, prefix: h.prefix}
+}
+
+func (h *SchemaHandler) WithGroup(name string) slog.Handler {
+	return &SchemaHandler{inner: h.inner.WithGroup(name), schema: h.schema, prefix: h.prefix + name + "."}
+}
+
+// validate reports whether a and its members comply with the schema, reporting the violations.
+func (h *SchemaHandler) validate(msg, prefix string, a slog.Attr) bool {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		ok := true
+		for _, ga := range v.Group() {
+			ok = h.validate(msg, prefix, ga) && ok
+		}
+		return ok
+	}
+	if a.Key == "" {
+		return true
+	}
+	key := prefix + a.Key
+	want, known := h.schema.Keys[key]
+	if known && (want == slog.KindAny || want == v.Kind()) {
+		return true
+	}
+	err := &SchemaError{Key: key, Kind: v.Kind(), Want: want, Known: known}
+	if h.schema.Policy == SchemaPanic {
+		panic(err)
+	}
+	if h.schema.OnViolation != nil {
+		h.schema.OnViolation(msg, err)
+	}
+	return false
+}
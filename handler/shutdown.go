@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds the closers to sync and close by its Shutdown. The functions OnShutdown,
+// Unregister and Shutdown use the registry of the package, to which the loggers and the
+// handlers of this package register; other registries suit the components with their own
+// lifetime, such as the tests.
+type Registry struct {
+	mu      sync.Mutex
+	closers []io.Closer
+	done    atomic.Bool // set by Shutdown, the handlers of WithShutdown stop accepting records
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+var defaultRegistry = NewRegistry()
+
+// OnShutdown registers c to be synced, if it's a Syncer, and closed by Shutdown, such as a
+// rotation.Logger or a LokiHandler.
+func (r *Registry) OnShutdown(c io.Closer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closers = append(r.closers, c)
+}
+
+// Unregister removes c from the closers of r, such as when c is closed before Shutdown.
+func (r *Registry) Unregister(c io.Closer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rc := range r.closers {
+		if rc == c {
+			r.closers = append(r.closers[:i], r.closers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Done reports whether r was shut down.
+func (r *Registry) Done() bool {
+	return r.done.Load()
+}
+
+// Shutdown cleanly stops the logging of r: the DefaultHandlers of WithShutdown(r) stop accepting
+// records, then the registered closers are synced and closed in the reverse order of their
+// registration, so the AsyncHandlers drain their buffers to the files before the files are
+// closed. It returns the error of ctx if the drains don't complete before ctx is done, the
+// remaining closers are left open then. The closers registered after Shutdown are closed by
+// the next one.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.done.Store(true)
+	r.mu.Lock()
+	cs := r.closers
+	r.closers = nil
+	r.mu.Unlock()
+
+	var errs []error
+	for i := len(cs) - 1; i >= 0; i-- {
+		c := cs[i]
+		done := make(chan error, 1)
+		go func() {
+			var err error
+			if s, ok := c.(Syncer); ok {
+				err = s.Sync()
+			}
+			done <- errors.Join(err, c.Close())
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			return errors.Join(append(errs, context.Cause(ctx))...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// OnShutdown registers c to the registry of the package, see Registry.OnShutdown. The loggers
+// created by the constructors of this package, such as GetDefaultDailyLogger, and the
+// AsyncHandlers, RateLimiters and DedupHandlers are registered already, until they are closed.
+func OnShutdown(c io.Closer) {
+	defaultRegistry.OnShutdown(c)
+}
+
+// Unregister removes c from the registry of the package.
+func Unregister(c io.Closer) {
+	defaultRegistry.Unregister(c)
+}
+
+// Shutdown cleanly stops the logging before the process exits, it shuts down the registry of
+// the package, see Registry.Shutdown:
+//
+//	defer handler.Shutdown(context.Background())
+//	slog.SetDefault(handler.GetDefaultDailyLogger("app.log", 0, 0))
+//
+// The records logged after Shutdown by the loggers of the constructors of this package, or by
+// the DefaultHandlers of WithShutdown(nil), are dropped. The other handlers aren't affected.
+func Shutdown(ctx context.Context) error {
+	return defaultRegistry.Shutdown(ctx)
+}
+
+// WithShutdown makes the handler drop the records once r is shut down, or the registry of the
+// package if r is nil, as the files it writes to are closed then.
+func WithShutdown(r *Registry) Option {
+	if r == nil {
+		r = defaultRegistry
+	}
+	return func(h *DefaultHandler) {
+		h.registry = r
+	}
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SkewPolicy decides what happens to the records whose time is too far from now.
+type SkewPolicy int
+
+const (
+	SkewClamp      SkewPolicy = iota // set the time of the record to now
+	SkewAnnotate                     // keep the time, and add the skew attr to the record
+	SkewQuarantine                   // pass the record to the quarantine handler instead
+)
+
+// SkewKey is the key of the attr added by SkewAnnotate, the time of the record minus now.
+const SkewKey = "skew"
+
+// Skew validates the time of the records, such as the times passed through from upstream
+// systems with skewed clocks, so the time ranges of the daily files can be trusted.
+type Skew struct {
+	MaxFuture time.Duration // the records later than now plus MaxFuture are skewed, 0 for no limit
+	MaxPast   time.Duration // the records earlier than now minus MaxPast are skewed, 0 for no limit
+	Policy    SkewPolicy
+	// Quarantine receives the skewed records of SkewQuarantine, such as a handler writing to
+	// a separate file. They are dropped if it's nil.
+	Quarantine slog.Handler
+}
+
+// SkewHandler applies a Skew to the records before passing them to the inner handler.
+type SkewHandler struct {
+	inner      slog.Handler
+	quarantine slog.Handler
+	s          *Skew
+}
+
+// NewSkewHandler creates a SkewHandler passing the validated records to inner.
+func NewSkewHandler(inner slog.Handler, s Skew) *SkewHandler {
+	return &SkewHandler{inner: inner, quarantine: s.Quarantine, s: &s}
+}
+
+func (h *SkewHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *SkewHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Time.IsZero() {
+		return h.inner.Handle(ctx, r)
+	}
+	now := time.Now()
+	skew := r.Time.Sub(now)
+	if !(h.s.MaxFuture > 0 && skew > h.s.MaxFuture) && !(h.s.MaxPast > 0 && -skew > h.s.MaxPast) {
+		return h.inner.Handle(ctx, r)
+	}
+	switch h.s.Policy {
+	case SkewAnnotate:
+		r = r.Clone()
+		r.AddAttrs(slog.Duration(SkewKey, skew))
+	case SkewQuarantine:
+		if h.quarantine == nil || !h.quarantine.Enabled(ctx, r.Level) {
+			return nil
+		}
+		return h.quarantine.Handle(ctx, r)
+	default:
+		r.Time = now
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *SkewHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	h2 := &SkewHandler{inner: h.inner.WithAttrs(as), s: h.s}
+	if h.quarantine != nil {
+		h2.quarantine = h.quarantine.WithAttrs(as)
+	}
+	return h2
+}
+
+func (h *SkewHandler) WithGroup(name string) slog.Handler {
+	h2 := &SkewHandler{inner: h.inner.WithGroup(name), s: h.s}
+	if h.quarantine != nil {
+		h2.quarantine = h.quarantine.WithGroup(name)
+	}
+	return h2
+}
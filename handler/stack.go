@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"log/slog"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StackKey is the key of the stack trace added by WithStackTrace.
+const StackKey = "stack"
+
+// WithStackTrace adds the stack trace of the goroutine to the records at level l or above,
+// such as the errors, so they can be debugged post mortem. The trace starts at the caller of
+// the logging method, skip frames skipped, and holds depth frames at most, 32 if 0. It is the
+// last attr of the record, outside of the groups, a string of lines of the function and of the
+// file and line indented by a tab, as printed by a panic.
+func WithStackTrace(l slog.Level, depth, skip int) Option {
+	return func(h *DefaultHandler) {
+		if depth <= 0 {
+			depth = 32
+		}
+		h.stackFrom = &l
+		h.stackDepth = depth
+		h.stackSkip = skip
+	}
+}
+
+// stackTrace formats the stack of the goroutine logging r.
+func (h *DefaultHandler) stackTrace(r slog.Record) string {
+	pcs := make([]uintptr, 64+h.stackSkip+h.stackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	if i := slices.Index(pcs, r.PC); i >= 0 {
+		pcs = pcs[i:]
+	} else {
+		// the record wasn't created by this goroutine, or by a logging method, so the frames
+		// of slog and of the handlers are skipped instead
+		frames := runtime.CallersFrames(pcs)
+		i := 0
+		for {
+			f, more := frames.Next()
+			if !strings.HasPrefix(f.Function, "log/slog.") && !strings.HasPrefix(f.Function, "github.com/wytools/rlog/handler.") {
+				break
+			}
+			i++
+			if !more {
+				break
+			}
+		}
+		pcs = pcs[min(i, len(pcs)):]
+	}
+	pcs = pcs[min(h.stackSkip, len(pcs)):]
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for n := 0; n < h.stackDepth; n++ {
+		f, more := frames.Next()
+		if f.Function == "" {
+			break
+		}
+		if n > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(f.Function + "\n\t" + f.File + ":" + strconv.Itoa(f.Line))
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// appendStack appends the stack trace of r as the last attr, if its level needs one.
+func (s *handleState) appendStack(r slog.Record) {
+	if s.h.stackFrom == nil || r.Level < *s.h.stackFrom {
+		return
+	}
+	*s.prefix = (*s.prefix)[:0] // outside of the groups
+	s.sep = s.h.attrSep()
+	s.appendKey(StackKey)
+	s.appendString(s.h.stackTrace(r))
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	statsMinutes     = 60  // the number of minutes kept by the StatsHandler
+	statsMaxMessages = 100 // the max number of distinct messages counted per minute
+	statsTopMessages = 10  // the number of messages in StatsSnapshot.TopMessages
+)
+
+// StatsHandler counts the records passing through it per minute and per level, for the
+// last 60 minutes, together with the most frequent messages. It answers questions like
+// "is the error rate up?" without a metrics stack. It also serves the snapshot as JSON,
+// so it can be mounted on an admin endpoint.
+type StatsHandler struct {
+	inner slog.Handler
+	s     *stats
+}
+
+type stats struct {
+	mu      sync.Mutex
+	buckets [statsMinutes]statsBucket
+}
+
+type statsBucket struct {
+	minute   int64 // unix minute of the bucket
+	levels   map[slog.Level]int64
+	messages map[string]int64
+}
+
+// MinuteStats holds the counts of one minute.
+type MinuteStats struct {
+	Time   time.Time        `json:"time"`
+	Counts map[string]int64 `json:"counts"` // keyed by level name
+}
+
+// MessageCount is the number of records logged with a message.
+type MessageCount struct {
+	Message string `json:"message"`
+	Count   int64  `json:"count"`
+}
+
+// StatsSnapshot is a copy of the statistics of a StatsHandler.
+type StatsSnapshot struct {
+	Minutes     []MinuteStats  `json:"minutes"`      // from the oldest to the newest, minutes without records are omitted
+	TopMessages []MessageCount `json:"top_messages"` // the most frequent messages of the whole period
+}
+
+// NewStatsHandler creates a StatsHandler passing the records to inner.
+func NewStatsHandler(inner slog.Handler) *StatsHandler {
+	return &StatsHandler{inner: inner, s: &stats{}}
+}
+
+func (h *StatsHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.inner.Enabled(ctx, l)
+}
+
+func (h *StatsHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.s.add(time.Now(), r.Level, r.Message)
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *StatsHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &StatsHandler{inner: h.inner.WithAttrs(as), s: h.s}
+}
+
+func (h *StatsHandler) WithGroup(name string) slog.Handler {
+	return &StatsHandler{inner: h.inner.WithGroup(name), s: h.s}
+}
+
+func (s *stats) add(now time.Time, l slog.Level, msg string) {
+	minute := now.Unix() / 60
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[minute%statsMinutes]
+	if b.minute != minute || b.levels == nil {
+		*b = statsBucket{
+			minute:   minute,
+			levels:   map[slog.Level]int64{},
+			messages: map[string]int64{},
+		}
+	}
+	b.levels[l]++
+	if _, ok := b.messages[msg]; ok || len(b.messages) < statsMaxMessages {
+		b.messages[msg]++
+	}
+}
+
+// Count returns the number of records at or above level l logged during the last d.
+// The counting is done by whole minutes, d is rounded up to minutes.
+func (h *StatsHandler) Count(l slog.Level, d time.Duration) int64 {
+	now := time.Now().Unix() / 60
+	from := now - int64((d+time.Minute-1)/time.Minute) + 1
+	h.s.mu.Lock()
+	defer h.s.mu.Unlock()
+	var n int64
+	for _, b := range h.s.buckets {
+		if b.levels == nil || b.minute < from || b.minute > now {
+			continue
+		}
+		for bl, c := range b.levels {
+			if bl >= l {
+				n += c
+			}
+		}
+	}
+	return n
+}
+
+// Snapshot returns the statistics of the last 60 minutes.
+func (h *StatsHandler) Snapshot() StatsSnapshot {
+	now := time.Now().Unix() / 60
+	var snap StatsSnapshot
+	messages := map[string]int64{}
+	h.s.mu.Lock()
+	for _, b := range h.s.buckets {
+		if b.levels == nil || b.minute <= now-statsMinutes {
+			continue
+		}
+		ms := MinuteStats{Time: time.Unix(b.minute*60, 0), Counts: map[string]int64{}}
+		for l, c := range b.levels {
+			ms.Counts[l.String()] = c
+		}
+		snap.Minutes = append(snap.Minutes, ms)
+		for m, c := range b.messages {
+			messages[m] += c
+		}
+	}
+	h.s.mu.Unlock()
+
+	sort.Slice(snap.Minutes, func(i, j int) bool { return snap.Minutes[i].Time.Before(snap.Minutes[j].Time) })
+	for m, c := range messages {
+		snap.TopMessages = append(snap.TopMessages, MessageCount{Message: m, Count: c})
+	}
+	sort.Slice(snap.TopMessages, func(i, j int) bool { return snap.TopMessages[i].Count > snap.TopMessages[j].Count })
+	if len(snap.TopMessages) > statsTopMessages {
+		snap.TopMessages = snap.TopMessages[:statsTopMessages]
+	}
+	return snap
+}
+
+// ServeHTTP writes the snapshot as JSON.
+func (h *StatsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.Snapshot())
+}
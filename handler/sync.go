@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/wytools/rlog/rotation"
+)
+
+// Syncer is implemented by the handlers able to flush the records they buffer and to sync their
+// writers to stable storage, such as the DefaultHandler writing to a rotation.Logger and the
+// AsyncHandler. Sync is the flush point before the process exits, or in a panic handler:
+//
+//	h := handler.NewAsyncHandler(handler.NewJSONHandler(fileLog, nil), handler.AsyncOptions{})
+//	logger := slog.New(h)
+//	defer func() {
+//		if r := recover(); r != nil {
+//			logger.Error("panic", "err", r)
+//			h.Sync()
+//			panic(r)
+//		}
+//	}()
+//
+// The handlers wrapping others don't forward Sync, it's called on the handler which buffers.
+type Syncer interface {
+	Sync() error
+}
+
+var (
+	_ Syncer = (*DefaultHandler)(nil)
+	_ Syncer = (*AsyncHandler)(nil)
+	_ Syncer = (*BatchHandler)(nil)
+	_ Syncer = (*ReorderHandler)(nil)
+	_ Syncer = (*MultiHandler)(nil)
+)
+
+// syncHandler syncs h if it's a Syncer.
+func syncHandler(h slog.Handler) error {
+	if s, ok := h.(Syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// syncWriter syncs w if it has a Sync or a Flush method, except os.Stdout and os.Stderr which
+// can't be synced when they are terminals or pipes.
+func syncWriter(w io.Writer) error {
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	return rotation.NewWriteSyncer(w).Sync()
+}
+
+// Sync syncs the writer of the handler, serialized with the writes of the handler and all its
+// clones.
+func (h *DefaultHandler) Sync() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return syncWriter(h.w)
+}
+
+// Sync writes the buffered records and syncs the inner handler.
+func (h *AsyncHandler) Sync() error {
+	return errors.Join(h.Flush(), syncHandler(h.inner))
+}
+
+// Sync passes the kept records to the inner handler and syncs it. Like Flush, it must be
+// called by the goroutine owning the handler.
+func (h *BatchHandler) Sync() error {
+	return errors.Join(h.Flush(), syncHandler(h.inner))
+}
+
+// Sync passes the held records to the inner handler without waiting for the window, and syncs
+// it.
+func (h *ReorderHandler) Sync() error {
+	h.w.mu.Lock()
+	err := errors.Join(h.w.takeErr(), h.w.pass(time.Time{}))
+	h.w.mu.Unlock()
+	return errors.Join(err, syncHandler(h.inner))
+}
+
+// Sync syncs all the handlers, the errors are joined.
+func (m *MultiHandler) Sync() error {
+	var errs []error
+	for _, h := range m.handlers {
+		if err := syncHandler(h); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sync syncs all the writers, the errors are joined.
+func (f *FanoutWriter) Sync() error {
+	var errs []error
+	for _, w := range f.writers {
+		if err := syncWriter(w); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
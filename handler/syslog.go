@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogFormat is the framing of the syslog messages.
+type SyslogFormat int
+
+const (
+	RFC3164 SyslogFormat = iota // the BSD syslog format, understood by every daemon
+	RFC5424                     // the structured syslog format, with a full timestamp
+)
+
+// The syslog severities of the slog levels.
+const (
+	syslogCrit    = 2
+	syslogErr     = 3
+	syslogWarning = 4
+	syslogNotice  = 5
+	syslogInfo    = 6
+	syslogDebug   = 7
+)
+
+// SyslogOptions configures a SyslogHandler.
+type SyslogOptions struct {
+	// Network and Addr are the syslog server, such as "udp" and "logs.example.com:514", or
+	// "unix" and "/dev/log". If Network is empty, the local daemon is used.
+	Network, Addr string
+	Format        SyslogFormat
+	Facility      int    // the syslog facility, 1 (user) if 0
+	Tag           string // the application name, the base name of the program if empty
+	Hostname      string // the host name, os.Hostname if empty
+}
+
+// SyslogHandler sends every record to syslog, the slog levels mapped to the syslog severities.
+// The message is the record formatted by the text handler without its time. Over TCP, the
+// messages are framed by octet counting for RFC5424, and by newlines for RFC3164. The
+// connection is redialed once when a send fails. Handlers derived by WithAttrs and WithGroup
+// share the connection, Close closes it.
+type SyslogHandler struct {
+	enc  *DefaultHandler
+	conn *syslogConn
+}
+
+type syslogConn struct {
+	SyslogOptions
+	pid string
+
+	mu     sync.Mutex
+	conn   net.Conn // nil after a failed redial, dialed again by the next send
+	closed bool
+}
+
+// NewSyslogHandler creates a SyslogHandler connected to the syslog server of opts.
+func NewSyslogHandler(opts SyslogOptions, hopts *slog.HandlerOptions, options ...Option) (*SyslogHandler, error) {
+	if opts.Facility == 0 {
+		opts.Facility = 1
+	}
+	if opts.Tag == "" {
+		opts.Tag = filepath.Base(os.Args[0])
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	c := &syslogConn{SyslogOptions: opts, pid: strconv.Itoa(os.Getpid())}
+	if err := c.dial(); err != nil {
+		return nil, err
+	}
+	return &SyslogHandler{enc: NewDefaultHandler(nil, hopts, options...), conn: c}, nil
+}
+
+func (h *SyslogHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.enc.Enabled(ctx, l)
+}
+
+func (h *SyslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	r.Time = time.Time{} // the time is in the syslog header
+	buf := h.enc.encode(r)
+	defer buf.Free()
+	body := *buf
+	if n := len(body); n > 0 && body[n-1] == '\n' {
+		body = body[:n-1]
+	}
+	return h.conn.send(t, syslogSeverity(r.Level), body)
+}
+
+func (h *SyslogHandler) WithAttrs(as []slog.Attr) slog.Handler {
+	return &SyslogHandler{enc: h.enc.WithAttrs(as).(*DefaultHandler), conn: h.conn}
+}
+
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	return &SyslogHandler{enc: h.enc.WithGroup(name).(*DefaultHandler), conn: h.conn}
+}
+
+// Close closes the connection to syslog.
+func (h *SyslogHandler) Close() error {
+	h.conn.mu.Lock()
+	defer h.conn.mu.Unlock()
+	h.conn.closed = true
+	if h.conn.conn == nil {
+		return nil
+	}
+	err := h.conn.conn.Close()
+	h.conn.conn = nil
+	return err
+}
+
+func syslogSeverity(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError+4:
+		return syslogCrit
+	case l >= slog.LevelError:
+		return syslogErr
+	case l >= slog.LevelWarn:
+		return syslogWarning
+	case l > slog.LevelInfo:
+		return syslogNotice
+	case l >= slog.LevelInfo:
+		return syslogInfo
+	default:
+		return syslogDebug
+	}
+}
+
+// dial connects to the syslog server, trying the usual sockets of the local daemon if no
+// network is set.
+func (c *syslogConn) dial() error {
+	if c.Network != "" {
+		conn, err := net.Dial(c.Network, c.Addr)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+		return nil
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range []string{"/dev/log", "/var/run/syslog", "/var/run/log"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				c.conn = conn
+				return nil
+			}
+		}
+	}
+	return errors.New("rlog: no local syslog daemon found")
+}
+
+// stream reports whether the connection is a stream, whose messages must be framed.
+func (c *syslogConn) stream() bool {
+	a := c.conn.RemoteAddr()
+	return a != nil && (a.Network() == "tcp" || a.Network() == "unix")
+}
+
+// rfc5424Time is the TIMESTAMP of RFC5424, whose TIME-SECFRAC has 6 digits at most.
+const rfc5424Time = "2006-01-02T15:04:05.999999Z07:00"
+
+// nilValue returns s, or the NILVALUE of RFC5424 if it's empty.
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (c *syslogConn) send(t time.Time, severity int, body []byte) error {
+	buf := NewBuffer()
+	defer buf.Free()
+	buf.WriteByte('<')
+	*buf = strconv.AppendInt(*buf, int64(c.Facility*8+severity), 10)
+	buf.WriteByte('>')
+	if c.Format == RFC5424 {
+		buf.WriteString("1 ")
+		*buf = t.AppendFormat(*buf, rfc5424Time)
+		buf.WriteString(" " + nilValue(c.Hostname) + " " + nilValue(c.Tag) + " " + c.pid + " - - ")
+	} else {
+		*buf = t.AppendFormat(*buf, time.Stamp)
+		buf.WriteString(" " + c.Hostname + " " + c.Tag + "[" + c.pid + "]: ")
+	}
+	buf.Write(body)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errors.New("rlog: syslog handler is closed")
+	}
+	if c.conn == nil {
+		if err := c.dial(); err != nil {
+			return err
+		}
+	}
+	msg := *buf
+	if c.stream() {
+		if c.Format == RFC5424 {
+			msg = append(strconv.AppendInt(nil, int64(len(msg)), 10), ' ')
+			msg = append(msg, *buf...)
+		} else {
+			msg = append(msg, '\n')
+		}
+	}
+	if _, err := c.conn.Write(msg); err == nil {
+		return nil
+	}
+	c.conn.Close()
+	if err := c.dial(); err != nil {
+		c.conn = nil
+		return err
+	}
+	_, err := c.conn.Write(msg)
+	return err
+}
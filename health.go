@@ -0,0 +1,69 @@
+package rlog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// HealthChecker is implemented by the writers and handlers reporting their health, such as
+// rotation.Logger and rotation.NetworkWriter. CheckHealth returns nil when healthy, or an
+// error describing why logs are at risk of being lost.
+type HealthChecker interface {
+	CheckHealth() error
+}
+
+var (
+	healthMu sync.Mutex
+	checkers = map[string]HealthChecker{}
+)
+
+// RegisterHealth adds c to the checks of Healthy under name, replacing the checker of the same
+// name, or removes it if c is nil.
+func RegisterHealth(name string, c HealthChecker) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	if c == nil {
+		delete(checkers, name)
+		return
+	}
+	checkers[name] = c
+}
+
+// Healthy runs the registered checks, and returns their errors prefixed by their names, nil if
+// they all pass.
+func Healthy() error {
+	healthMu.Lock()
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	cs := make(map[string]HealthChecker, len(checkers))
+	for name, c := range checkers {
+		cs[name] = c
+	}
+	healthMu.Unlock()
+
+	sort.Strings(names)
+	var errs []error
+	for _, name := range names {
+		if err := cs[name].CheckHealth(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HealthHandler returns an http.Handler for the probes of the orchestrators, answering 200
+// when Healthy passes, and 503 with the errors otherwise, so they can act before logs are lost.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := Healthy(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+}
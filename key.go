@@ -0,0 +1,24 @@
+// Package rlog holds the small shared pieces of rlog used across the handler and rotation packages.
+package rlog
+
+import "log/slog"
+
+// Key is an attr key bound to the type of its value, so a codebase can declare its keys once
+// and log them consistently:
+//
+//	var UserID = rlog.Key[int64]("user_id")
+//
+//	logger.Info("login", UserID.Value(42))
+//
+// The analyzer in github.com/wytools/rlog/analyzer reports string keys spelled like a declared Key.
+type Key[T any] string
+
+// Name returns the key name.
+func (k Key[T]) Name() string {
+	return string(k)
+}
+
+// Value returns an attr of the key with the value v.
+func (k Key[T]) Value(v T) slog.Attr {
+	return slog.Any(string(k), v)
+}
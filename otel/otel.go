@@ -0,0 +1,308 @@
+// Package otel provides a slog.Handler converting the records into OpenTelemetry log records,
+// and exporting them in batches to an OTLP endpoint, or to any Exporter such as an adapter to
+// the exporters of the OpenTelemetry SDK. Like the other packages of rlog, it only uses the
+// standard library, talking OTLP/HTTP with JSON encoding directly.
+package otel
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogRecord is a record converted to the OpenTelemetry log data model.
+type LogRecord struct {
+	Time           time.Time
+	ObservedTime   time.Time
+	SeverityNumber int    // 1 to 24, 9 for slog.LevelInfo
+	SeverityText   string // the slog level, such as "INFO"
+	Body           string // the message of the record
+	Attributes     []slog.Attr
+	TraceID        [16]byte // zero if the record has no trace context
+	SpanID         [8]byte
+}
+
+// Exporter exports batches of log records.
+type Exporter interface {
+	Export(ctx context.Context, records []LogRecord) error
+}
+
+// Options configures a Handler.
+type Options struct {
+	// Endpoint is the OTLP/HTTP logs endpoint, such as "http://localhost:4318/v1/logs", used
+	// when Exporter is nil.
+	Endpoint string
+	Headers  map[string]string // sent with every export, such as an API key
+	// Resource are the attributes of the resource producing the logs, such as
+	// slog.String("service.name", "api"), sent by the OTLP exporter.
+	Resource []slog.Attr
+	Exporter Exporter // overrides Endpoint
+
+	Level slog.Leveler // the min level, slog.LevelInfo if nil
+	// SpanContext returns the trace and span ids of ctx, such as from the span of the
+	// OpenTelemetry API, ok false if there is none.
+	SpanContext func(ctx context.Context) (traceID [16]byte, spanID [8]byte, ok bool)
+	// Tee also passes the records to this handler, such as a JSON handler writing to the
+	// rotating file, so the logs are kept locally as well.
+	Tee slog.Handler
+
+	BatchSize     int           // the number of records exporting a batch, 512 if 0
+	FlushInterval time.Duration // the max delay before exporting a record, 1 second if 0
+	OnError       func(error)   // called when an export fails, the batch is dropped
+	// MaxBuffered is the max number of records kept while an export is slow, 10 batches if 0.
+	// The records over it are dropped, and counted by Dropped.
+	MaxBuffered int
+}
+
+// Handler converts the records into LogRecords and exports them in batches from a background
+// goroutine, so Handle never waits for the exporter. The groups become nested attributes, and
+// the values are resolved by Handle. Handlers derived by WithAttrs and WithGroup share the
+// batch, Close exports the last one, and Handle returns an error after it.
+type Handler struct {
+	frames []frame
+	tee    slog.Handler
+	b      *batcher
+}
+
+// frame is a group opened by WithGroup, empty for the top level, and the attrs added in it.
+type frame struct {
+	group string
+	attrs []slog.Attr
+}
+
+type batcher struct {
+	Options
+	exp Exporter
+
+	mu      sync.Mutex
+	records []LogRecord
+	closed  bool
+	dropped atomic.Uint64
+
+	flush   chan chan struct{} // requests an export, closing the channel when done
+	done    chan struct{}
+	closing sync.Once
+	wg      sync.WaitGroup
+}
+
+// NewHandler creates a Handler exporting to opts.Exporter, or to opts.Endpoint, and starts its
+// goroutine.
+func NewHandler(opts Options) *Handler {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.MaxBuffered <= 0 {
+		opts.MaxBuffered = 10 * opts.BatchSize
+	}
+	b := &batcher{
+		Options: opts,
+		exp:     opts.Exporter,
+		flush:   make(chan chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if b.exp == nil {
+		b.exp = &HTTPExporter{Endpoint: opts.Endpoint, Headers: opts.Headers, Resource: opts.Resource}
+	}
+	b.wg.Add(1)
+	go b.run()
+	return &Handler{frames: []frame{{}}, tee: opts.Tee, b: b}
+}
+
+// Severity maps a slog level to an OpenTelemetry severity number, slog.LevelDebug, LevelInfo,
+// LevelWarn and LevelError being the first severities of DEBUG, INFO, WARN and ERROR.
+func Severity(l slog.Level) int {
+	return min(max(int(l)+9, 1), 24)
+}
+
+// Enabled reports whether l is at least the level of the handler, or enabled by the tee.
+func (h *Handler) Enabled(ctx context.Context, l slog.Level) bool {
+	if h.enabled(l) {
+		return true
+	}
+	return h.tee != nil && h.tee.Enabled(ctx, l)
+}
+
+func (h *Handler) enabled(l slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.b.Level != nil {
+		minLevel = h.b.Level.Level()
+	}
+	return l >= minLevel
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var err error
+	if h.tee != nil && h.tee.Enabled(ctx, r.Level) {
+		err = h.tee.Handle(ctx, r.Clone())
+	}
+	if !h.enabled(r.Level) {
+		return err
+	}
+	lr := LogRecord{
+		Time:           r.Time,
+		ObservedTime:   time.Now(),
+		SeverityNumber: Severity(r.Level),
+		SeverityText:   r.Level.String(),
+		Body:           r.Message,
+		Attributes:     h.attributes(r),
+	}
+	if h.b.SpanContext != nil && ctx != nil {
+		if traceID, spanID, ok := h.b.SpanContext(ctx); ok {
+			lr.TraceID, lr.SpanID = traceID, spanID
+		}
+	}
+	return errors.Join(err, h.b.add(lr))
+}
+
+// attributes nests the attrs of r and of the handler into their groups.
+func (h *Handler) attributes(r slog.Record) []slog.Attr {
+	as := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		as = append(as, a)
+		return true
+	})
+	as = resolve(as)
+	for i := len(h.frames) - 1; i >= 0; i-- {
+		f := h.frames[i]
+		as = append(slices.Clip(f.attrs), as...)
+		if f.group == "" {
+			continue
+		}
+		if len(as) == 0 {
+			as = nil
+			continue
+		}
+		as = []slog.Attr{{Key: f.group, Value: slog.GroupValue(as...)}}
+	}
+	return as
+}
+
+func (h *Handler) WithAttrs(as []slog.Attr) slog.Handler {
+	if len(as) == 0 {
+		return h
+	}
+	h2 := &Handler{frames: slices.Clone(h.frames), tee: h.tee, b: h.b}
+	last := &h2.frames[len(h2.frames)-1]
+	last.attrs = append(slices.Clip(last.attrs), resolve(as)...)
+	if h.tee != nil {
+		h2.tee = h.tee.WithAttrs(as)
+	}
+	return h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := &Handler{frames: append(slices.Clip(h.frames), frame{group: name}), tee: h.tee, b: h.b}
+	if h.tee != nil {
+		h2.tee = h.tee.WithGroup(name)
+	}
+	return h2
+}
+
+// Flush exports the kept records, and waits for the export.
+func (h *Handler) Flush() {
+	done := make(chan struct{})
+	select {
+	case h.b.flush <- done:
+		<-done
+	case <-h.b.done:
+	}
+}
+
+// Dropped returns the number of records dropped as the buffer was full.
+func (h *Handler) Dropped() uint64 {
+	return h.b.dropped.Load()
+}
+
+// Close exports the kept records and stops the goroutine of the handler. The tee is not closed.
+func (h *Handler) Close() error {
+	h.b.closing.Do(func() {
+		h.b.mu.Lock()
+		h.b.closed = true
+		h.b.mu.Unlock()
+		close(h.b.done)
+	})
+	h.b.wg.Wait()
+	return nil
+}
+
+// resolve returns the attrs as with their values resolved, in the groups too, so the exporter
+// doesn't call the LogValuers from its goroutine, after the values changed.
+func resolve(as []slog.Attr) []slog.Attr {
+	as2 := make([]slog.Attr, len(as))
+	for i, a := range as {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			a.Value = slog.GroupValue(resolve(a.Value.Group())...)
+		}
+		as2[i] = a
+	}
+	return as2
+}
+
+// add keeps a record, and requests an export when the batch is full. It drops the record if
+// the buffer is full.
+func (b *batcher) add(lr LogRecord) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return errors.New("otel: handler is closed")
+	}
+	if len(b.records) >= b.MaxBuffered {
+		b.mu.Unlock()
+		b.dropped.Add(1)
+		return nil
+	}
+	b.records = append(b.records, lr)
+	full := len(b.records) >= b.BatchSize
+	b.mu.Unlock()
+	if full {
+		select {
+		case b.flush <- nil:
+		default: // an export is already requested
+		}
+	}
+	return nil
+}
+
+func (b *batcher) run() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.export()
+		case done := <-b.flush:
+			b.export()
+			if done != nil {
+				close(done)
+			}
+		case <-b.done:
+			b.export()
+			return
+		}
+	}
+}
+
+func (b *batcher) export() {
+	b.mu.Lock()
+	records := b.records
+	b.records = nil
+	b.mu.Unlock()
+	if len(records) == 0 {
+		return
+	}
+	if err := b.exp.Export(context.Background(), records); err != nil && b.OnError != nil {
+		b.OnError(err)
+	}
+}
@@ -0,0 +1,190 @@
+package otel
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// HTTPExporter posts the log records to an OTLP/HTTP endpoint, encoded as JSON.
+type HTTPExporter struct {
+	Endpoint string            // such as "http://localhost:4318/v1/logs"
+	Headers  map[string]string // sent with every request
+	Resource []slog.Attr       // the attributes of the resource
+	Scope    string            // the instrumentation scope, "github.com/wytools/rlog/otel" if empty
+	Client   *http.Client      // a client with a 10 seconds timeout if nil
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// The OTLP JSON messages, with the field names of the protobuf JSON mapping.
+type (
+	otlpRequest struct {
+		ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+	}
+	otlpResourceLogs struct {
+		Resource  otlpResource    `json:"resource"`
+		ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpScopeLogs struct {
+		Scope      otlpScope       `json:"scope"`
+		LogRecords []otlpLogRecord `json:"logRecords"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpLogRecord struct {
+		TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+		ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+		SeverityNumber       int            `json:"severityNumber"`
+		SeverityText         string         `json:"severityText"`
+		Body                 otlpAnyValue   `json:"body"`
+		Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+		TraceID              string         `json:"traceId,omitempty"`
+		SpanID               string         `json:"spanId,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	// otlpAnyValue holds one of its fields, the int64 values are strings in JSON.
+	otlpAnyValue struct {
+		StringValue *string           `json:"stringValue,omitempty"`
+		BoolValue   *bool             `json:"boolValue,omitempty"`
+		IntValue    *string           `json:"intValue,omitempty"`
+		DoubleValue *float64          `json:"doubleValue,omitempty"`
+		KvlistValue *otlpKeyValueList `json:"kvlistValue,omitempty"`
+	}
+	otlpKeyValueList struct {
+		Values []otlpKeyValue `json:"values"`
+	}
+)
+
+// Export implements Exporter.
+func (e *HTTPExporter) Export(ctx context.Context, records []LogRecord) error {
+	scope := e.Scope
+	if scope == "" {
+		scope = "github.com/wytools/rlog/otel"
+	}
+	lrs := make([]otlpLogRecord, len(records))
+	for i, r := range records {
+		lr := otlpLogRecord{
+			ObservedTimeUnixNano: strconv.FormatInt(r.ObservedTime.UnixNano(), 10),
+			SeverityNumber:       r.SeverityNumber,
+			SeverityText:         r.SeverityText,
+			Body:                 otlpString(r.Body),
+			Attributes:           otlpAttributes(r.Attributes),
+		}
+		if !r.Time.IsZero() {
+			lr.TimeUnixNano = strconv.FormatInt(r.Time.UnixNano(), 10)
+		}
+		if r.TraceID != [16]byte{} {
+			lr.TraceID = hex.EncodeToString(r.TraceID[:])
+			lr.SpanID = hex.EncodeToString(r.SpanID[:])
+		}
+		lrs[i] = lr
+	}
+	body, err := json.Marshal(otlpRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource:  otlpResource{Attributes: otlpAttributes(e.Resource)},
+		ScopeLogs: []otlpScopeLogs{{Scope: otlpScope{Name: scope}, LogRecords: lrs}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.Headers {
+		req.Header.Set(k, v)
+	}
+	client := e.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otel: export to %s: %s", e.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+func otlpAttributes(as []slog.Attr) []otlpKeyValue {
+	var kvs []otlpKeyValue
+	for _, a := range as {
+		a.Value = a.Value.Resolve()
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup && a.Key == "" {
+			// inline a group with an empty key, as the slog handlers do
+			kvs = append(kvs, otlpAttributes(a.Value.Group())...)
+			continue
+		}
+		kvs = append(kvs, otlpKeyValue{Key: a.Key, Value: otlpValue(a.Value)})
+	}
+	return kvs
+}
+
+func otlpValue(v slog.Value) otlpAnyValue {
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpAnyValue{BoolValue: &b}
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindUint64:
+		if v.Uint64() > math.MaxInt64 {
+			// beyond the int64 of intValue, it would wrap
+			break
+		}
+		s := strconv.FormatUint(v.Uint64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindFloat64:
+		if f := v.Float64(); !math.IsInf(f, 0) && !math.IsNaN(f) { // not valid JSON numbers
+			return otlpAnyValue{DoubleValue: &f}
+		}
+	case slog.KindDuration:
+		s := strconv.FormatInt(int64(v.Duration()), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindGroup:
+		return otlpAnyValue{KvlistValue: &otlpKeyValueList{Values: otlpAttributes(v.Group())}}
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			if isNilPointer(err) {
+				// its Error method would panic in the goroutine of the batcher
+				return otlpString("<nil>")
+			}
+			return otlpString(err.Error())
+		}
+	}
+	return otlpString(v.String())
+}
+
+// isNilPointer reports whether x is a nil pointer, such as a typed nil error.
+func isNilPointer(x any) bool {
+	v := reflect.ValueOf(x)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+func otlpString(s string) otlpAnyValue {
+	return otlpAnyValue{StringValue: &s}
+}
@@ -0,0 +1,361 @@
+// Package parquet converts the JSON log files written by rlog into Parquet files, for cheap
+// analytical queries over the historical logs with tools such as DuckDB or Athena.
+//
+// It only uses the standard library. The files are written with a single row group, PLAIN
+// encoding and no compression, which every Parquet reader supports.
+package parquet
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/wytools/rlog/handler"
+)
+
+// The Parquet enums used by this package.
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	repetitionOptional = 1
+)
+
+var magic = []byte("PAR1")
+
+// column holds the values of a column, the rows without a value are null.
+type column struct {
+	name    string
+	kind    slog.Kind // KindString, KindInt64, KindFloat64, KindBool or KindTime
+	defs    []bool    // whether every row has a value
+	data    []byte    // the PLAIN encoded values
+	bools   []bool    // the values of a KindBool column, bit packed when the page is written
+	nValues int       // the number of values, not null
+}
+
+// Export converts the JSON lines of r, as written by handler.NewJSONHandler, into a Parquet
+// file written to w, and returns the number of rows. The lines which are not JSON objects,
+// such as a torn final record, are skipped.
+//
+// The columns are time, level and msg, followed by the keys of schema, with types derived
+// from their kinds, the other attrs are dropped. If schema is nil, the columns are inferred
+// from the records. The attrs in groups are flattened, such as "req.method".
+func Export(w io.Writer, r io.Reader, schema *handler.Schema) (int, error) {
+	var records []map[string]any
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		dec := json.NewDecoder(bytes.NewReader(sc.Bytes()))
+		dec.UseNumber()
+		var m map[string]any
+		if dec.Decode(&m) != nil {
+			continue
+		}
+		flat := map[string]any{}
+		flatten(flat, "", m)
+		records = append(records, flat)
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+
+	var kinds map[string]slog.Kind
+	if schema != nil {
+		kinds = map[string]slog.Kind{}
+		for k, kind := range schema.Keys {
+			kinds[k] = kind
+		}
+	} else {
+		kinds = inferKinds(records)
+	}
+	cols := []*column{
+		{name: slog.TimeKey, kind: slog.KindTime},
+		{name: slog.LevelKey, kind: slog.KindString},
+		{name: slog.MessageKey, kind: slog.KindString},
+	}
+	delete(kinds, slog.TimeKey)
+	delete(kinds, slog.LevelKey)
+	delete(kinds, slog.MessageKey)
+	keys := make([]string, 0, len(kinds))
+	for k := range kinds {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cols = append(cols, &column{name: k, kind: physicalKind(kinds[k])})
+	}
+
+	for _, rec := range records {
+		for _, c := range cols {
+			c.add(rec[c.name])
+		}
+	}
+	return len(records), write(w, cols, len(records))
+}
+
+// flatten copies the values of m into flat, with the keys of nested objects qualified.
+func flatten(flat map[string]any, prefix string, m map[string]any) {
+	for k, v := range m {
+		if mm, ok := v.(map[string]any); ok {
+			flatten(flat, prefix+k+".", mm)
+			continue
+		}
+		flat[prefix+k] = v
+	}
+}
+
+// inferKinds returns the kinds of the keys of the records, a key with conflicting values is a
+// string.
+func inferKinds(records []map[string]any) map[string]slog.Kind {
+	kinds := map[string]slog.Kind{}
+	for _, rec := range records {
+		for k, v := range rec {
+			var kind slog.Kind
+			switch v := v.(type) {
+			case nil:
+				continue
+			case bool:
+				kind = slog.KindBool
+			case json.Number:
+				kind = slog.KindFloat64
+				if _, err := v.Int64(); err == nil {
+					kind = slog.KindInt64
+				}
+			default:
+				kind = slog.KindString
+			}
+			old, ok := kinds[k]
+			switch {
+			case !ok || old == kind:
+				kinds[k] = kind
+			case (old == slog.KindInt64 && kind == slog.KindFloat64) || (old == slog.KindFloat64 && kind == slog.KindInt64):
+				kinds[k] = slog.KindFloat64
+			default:
+				kinds[k] = slog.KindString
+			}
+		}
+	}
+	return kinds
+}
+
+// physicalKind returns the kind of the column storing the values of kind.
+func physicalKind(kind slog.Kind) slog.Kind {
+	switch kind {
+	case slog.KindInt64, slog.KindUint64, slog.KindDuration:
+		return slog.KindInt64
+	case slog.KindFloat64, slog.KindBool, slog.KindTime:
+		return kind
+	default:
+		return slog.KindString
+	}
+}
+
+// add appends the value v of a row, the values which can't be converted to the kind of the
+// column are null.
+func (c *column) add(v any) {
+	ok := false
+	switch c.kind {
+	case slog.KindString:
+		var s string
+		switch v := v.(type) {
+		case nil:
+		case string:
+			s, ok = v, true
+		default:
+			b, err := json.Marshal(v)
+			s, ok = string(b), err == nil
+		}
+		if ok {
+			c.data = binary.LittleEndian.AppendUint32(c.data, uint32(len(s)))
+			c.data = append(c.data, s...)
+		}
+	case slog.KindInt64:
+		if n, isNum := v.(json.Number); isNum {
+			var i int64
+			var err error
+			if i, err = n.Int64(); err == nil {
+				c.data = binary.LittleEndian.AppendUint64(c.data, uint64(i))
+				ok = true
+			}
+		}
+	case slog.KindFloat64:
+		if n, isNum := v.(json.Number); isNum {
+			if f, err := strconv.ParseFloat(string(n), 64); err == nil {
+				c.data = binary.LittleEndian.AppendUint64(c.data, math.Float64bits(f))
+				ok = true
+			}
+		}
+	case slog.KindBool:
+		var b bool
+		if b, ok = v.(bool); ok {
+			c.bools = append(c.bools, b)
+		}
+	case slog.KindTime:
+		if s, isStr := v.(string); isStr {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				c.data = binary.LittleEndian.AppendUint64(c.data, uint64(t.UnixMilli()))
+				ok = true
+			}
+		}
+	}
+	c.defs = append(c.defs, ok)
+	if ok {
+		c.nValues++
+	}
+}
+
+// write writes the Parquet file of the columns.
+func write(w io.Writer, cols []*column, rows int) error {
+	cw := &countWriter{w: w}
+	if _, err := cw.Write(magic); err != nil {
+		return err
+	}
+
+	meta := &thriftWriter{}
+	meta.beginStruct()
+	meta.i32(1, 1) // version
+	meta.list(2, tStruct, len(cols)+1)
+	meta.beginStruct() // the root of the schema
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(cols)))
+	meta.endStruct()
+	for _, c := range cols {
+		meta.beginStruct()
+		meta.i32(1, c.physicalType())
+		meta.i32(3, repetitionOptional)
+		meta.binary(4, c.name)
+		switch c.kind {
+		case slog.KindString:
+			meta.i32(6, convertedUTF8)
+		case slog.KindTime:
+			meta.i32(6, convertedTimestampMillis)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, int64(rows))
+
+	meta.list(4, tStruct, 1)
+	meta.beginStruct() // the row group
+	meta.list(1, tStruct, len(cols))
+	var total int64
+	for _, c := range cols {
+		offset := cw.n
+		if err := c.writePage(cw); err != nil {
+			return err
+		}
+		size := cw.n - offset
+		total += size
+
+		meta.beginStruct() // the column chunk
+		meta.i64(2, offset)
+		meta.structField(3)
+		meta.i32(1, c.physicalType())
+		meta.list(2, tI32, 2)
+		meta.elemI32(encodingPlain)
+		meta.elemI32(encodingRLE)
+		meta.list(3, tBinary, 1)
+		meta.elemBinary(c.name)
+		meta.i32(4, 0) // uncompressed
+		meta.i64(5, int64(len(c.defs)))
+		meta.i64(6, size)
+		meta.i64(7, size)
+		meta.i64(9, offset)
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(rows))
+	meta.endStruct()
+	meta.binary(6, "rlog")
+	meta.endStruct()
+
+	if _, err := cw.Write(meta.buf); err != nil {
+		return err
+	}
+	footer := binary.LittleEndian.AppendUint32(nil, uint32(len(meta.buf)))
+	_, err := cw.Write(append(footer, magic...))
+	return err
+}
+
+func (c *column) physicalType() int32 {
+	switch c.kind {
+	case slog.KindInt64, slog.KindTime:
+		return typeInt64
+	case slog.KindFloat64:
+		return typeDouble
+	case slog.KindBool:
+		return typeBoolean
+	default:
+		return typeByteArray
+	}
+}
+
+// writePage writes the column as a single data page.
+func (c *column) writePage(w io.Writer) error {
+	// the definition levels, as a bit packed run of the RLE hybrid encoding of bit width 1
+	levels := binary.AppendUvarint(nil, uint64((len(c.defs)+7)/8)<<1|1)
+	levels = append(levels, packBits(c.defs)...)
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	if c.kind == slog.KindBool {
+		page = append(page, packBits(c.bools)...)
+	} else {
+		page = append(page, c.data...)
+	}
+
+	h := &thriftWriter{}
+	h.beginStruct()
+	h.i32(1, 0) // DATA_PAGE
+	h.i32(2, int32(len(page)))
+	h.i32(3, int32(len(page)))
+	h.structField(5)
+	h.i32(1, int32(len(c.defs)))
+	h.i32(2, encodingPlain)
+	h.i32(3, encodingRLE)
+	h.i32(4, encodingRLE)
+	h.endStruct()
+	h.endStruct()
+	if _, err := w.Write(h.buf); err != nil {
+		return err
+	}
+	_, err := w.Write(page)
+	return err
+}
+
+// packBits packs the bools by 8 in bytes, from the least significant bit.
+func packBits(bs []bool) []byte {
+	b := make([]byte, (len(bs)+7)/8)
+	for i, v := range bs {
+		if v {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return b
+}
+
+// countWriter counts the bytes written, for the offsets of the metadata.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,93 @@
+package parquet
+
+import (
+	"encoding/binary"
+)
+
+// The types of the thrift compact protocol, which encodes the metadata of Parquet files.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// thriftWriter encodes a thrift struct with the compact protocol. Only the types used by the
+// Parquet metadata written by this package are supported.
+type thriftWriter struct {
+	buf    []byte
+	lastID []int16 // the last field id of every open struct
+}
+
+func (t *thriftWriter) varint(v uint64) {
+	t.buf = binary.AppendUvarint(t.buf, v)
+}
+
+func (t *thriftWriter) zigzag(v int64) {
+	t.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (t *thriftWriter) field(id int16, typ byte) {
+	last := &t.lastID[len(t.lastID)-1]
+	if d := id - *last; d > 0 && d <= 15 {
+		t.buf = append(t.buf, byte(d)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.zigzag(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.lastID = append(t.lastID, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.lastID = t.lastID[:len(t.lastID)-1]
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, tI32)
+	t.zigzag(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, tI64)
+	t.zigzag(v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.field(id, tBinary)
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// list writes the header of a list of n elements of the type typ, the elements follow.
+func (t *thriftWriter) list(id int16, typ byte, n int) {
+	t.field(id, tList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|typ)
+	} else {
+		t.buf = append(t.buf, 0xf0|typ)
+		t.varint(uint64(n))
+	}
+}
+
+// structField opens a struct in the field id, closed by endStruct.
+func (t *thriftWriter) structField(id int16) {
+	t.field(id, tStruct)
+	t.beginStruct()
+}
+
+// elemI32 writes an i32 element of a list.
+func (t *thriftWriter) elemI32(v int32) {
+	t.zigzag(int64(v))
+}
+
+// elemBinary writes a binary element of a list.
+func (t *thriftWriter) elemBinary(s string) {
+	t.varint(uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
@@ -0,0 +1,3 @@
+module github.com/wytools/rlog/rlogapi
+
+go 1.21
@@ -0,0 +1,153 @@
+// Package rlogapi lets libraries log to the rlog pipeline of the application using them,
+// without importing rlog itself. A library asks for a pipeline by name:
+//
+//	var logger = slog.New(rlogapi.Pipeline("mydb"))
+//
+// and the application registers the pipelines once it has set them up:
+//
+//	rlogapi.Register("mydb", handler.NewSampler(h, 10, 100))
+//
+// The records of a pipeline nobody registered go to the pipeline registered under the empty
+// name, or to slog.Default. If slog.Default is a pipeline nobody registered either, they go to
+// a text handler writing to stderr.
+//
+// It lives in its own module depending on the standard library only, so libraries don't
+// compile or vendor the handlers, sinks and tools of rlog.
+package rlogapi
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Handler is a stage of an rlog pipeline. Every handler of rlog is a slog.Handler, and so is
+// every other one: the pipelines of other libraries can be registered as well.
+type Handler interface {
+	slog.Handler
+}
+
+// Writer is the sink at the end of an rlog pipeline, such as rotation.Logger or
+// rotation.BufferedWriter. Sync flushes the writes to stable storage.
+type Writer interface {
+	io.Writer
+	Sync() error
+	Close() error
+}
+
+var (
+	mu        sync.Mutex
+	pipelines = map[string]*pipeline{}
+)
+
+// pipeline holds the handler registered under a name. Every registration stores a new
+// registered, so the proxies derived by WithAttrs and WithGroup know to derive again.
+type pipeline struct {
+	h atomic.Pointer[registered]
+}
+
+type registered struct {
+	h slog.Handler
+}
+
+func lookup(name string) *pipeline {
+	mu.Lock()
+	defer mu.Unlock()
+	p, ok := pipelines[name]
+	if !ok {
+		p = &pipeline{}
+		pipelines[name] = p
+	}
+	return p
+}
+
+// Register sets h as the pipeline name, replacing the one registered before, or unregisters
+// it if h is nil. The handlers returned by Pipeline switch to h at once.
+func Register(name string, h slog.Handler) {
+	p := lookup(name)
+	if h == nil {
+		p.h.Store(nil)
+		return
+	}
+	p.h.Store(&registered{h: h})
+}
+
+// Pipeline returns a handler passing the records to the pipeline registered as name, when they
+// are logged. It can be called before the pipeline is registered, such as in a package
+// variable.
+func Pipeline(name string) slog.Handler {
+	return &proxy{p: lookup(name), def: lookup("")}
+}
+
+// proxy passes the records to the registered pipeline, derived by its attrs and groups. The
+// derived handler is cached until the registration changes.
+type proxy struct {
+	p, def *pipeline
+	ops    []func(slog.Handler) slog.Handler // the WithAttrs and WithGroup calls, in order
+
+	cache atomic.Pointer[derived]
+}
+
+type derived struct {
+	from *registered // the registration h is derived from, nil for slog.Default
+	base slog.Handler
+	h    slog.Handler
+}
+
+// fallback is the target of the proxies when nothing is registered and slog.Default is a
+// proxy itself, which would pass the records back to itself.
+var fallback slog.Handler = slog.NewTextHandler(os.Stderr, nil)
+
+// registered returns the registration the proxy passes to, nil if there is none.
+func (p *proxy) registered() *registered {
+	if reg := p.p.h.Load(); reg != nil {
+		return reg
+	}
+	return p.def.h.Load()
+}
+
+// handler returns the current target of the proxy.
+func (p *proxy) handler() slog.Handler {
+	reg := p.registered()
+	var base slog.Handler
+	if reg != nil {
+		base = reg.h
+	} else {
+		base = slog.Default().Handler()
+		if dp, ok := base.(*proxy); ok && dp.registered() == nil {
+			base = fallback
+		}
+	}
+	if d := p.cache.Load(); d != nil && d.from == reg && (reg != nil || d.base == base) {
+		return d.h
+	}
+	h := base
+	for _, op := range p.ops {
+		h = op(h)
+	}
+	p.cache.Store(&derived{from: reg, base: base, h: h})
+	return h
+}
+
+func (p *proxy) Enabled(ctx context.Context, l slog.Level) bool {
+	return p.handler().Enabled(ctx, l)
+}
+
+func (p *proxy) Handle(ctx context.Context, r slog.Record) error {
+	return p.handler().Handle(ctx, r)
+}
+
+func (p *proxy) WithAttrs(as []slog.Attr) slog.Handler {
+	return p.with(func(h slog.Handler) slog.Handler { return h.WithAttrs(as) })
+}
+
+func (p *proxy) WithGroup(name string) slog.Handler {
+	return p.with(func(h slog.Handler) slog.Handler { return h.WithGroup(name) })
+}
+
+func (p *proxy) with(op func(slog.Handler) slog.Handler) *proxy {
+	return &proxy{p: p.p, def: p.def, ops: append(p.ops[:len(p.ops):len(p.ops)], op)}
+}
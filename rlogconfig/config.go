@@ -0,0 +1,192 @@
+// Package rlogconfig builds a fully wired *slog.Logger from a configuration file or environment
+// variables, so the logging of a binary can change between environments without recompiling.
+//
+// JSON files are supported out of the box. To keep rlog free of dependencies, other formats are
+// plugged in by their unmarshal function, e.g. for YAML:
+//
+//	rlogconfig.RegisterDecoder(".yaml", yaml.Unmarshal)
+package rlogconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wytools/rlog/handler"
+	"github.com/wytools/rlog/rotation"
+)
+
+// Config describes a logger.
+type Config struct {
+	Path         string `json:"path" yaml:"path"`                     // the log file name
+	Rotation     string `json:"rotation" yaml:"rotation"`             // daily (default), size, hybrid or interval
+	DailyAt      string `json:"daily_at" yaml:"daily_at"`             // HH:MM of daily and hybrid rotations, 00:00 by default
+	Interval     string `json:"interval" yaml:"interval"`             // the interval of interval rotation, e.g. "1h"
+	MaxSize      int64  `json:"max_size" yaml:"max_size"`             // the max bytes of a file for size and hybrid rotations
+	MaxFiles     int    `json:"max_files" yaml:"max_files"`           // the max number of files for size rotation
+	MaxTotalSize int64  `json:"max_total_size" yaml:"max_total_size"` // the byte budget of all the files, 0 if unlimited
+	UTC          bool   `json:"utc" yaml:"utc"`                       // use UTC for the schedule and the file names
+	Level        string `json:"level" yaml:"level"`                   // debug, info (default), warn or error
+	Format       string `json:"format" yaml:"format"`                 // text (default), json or console
+	AddSource    bool   `json:"add_source" yaml:"add_source"`         // add the source location to debug records
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]func([]byte, any) error{
+		".json": json.Unmarshal,
+	}
+)
+
+// RegisterDecoder registers the unmarshal function for the files with the extension ext, such as ".yaml".
+func RegisterDecoder(ext string, unmarshal func([]byte, any) error) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+	decoders[strings.ToLower(ext)] = unmarshal
+}
+
+// LoadFile reads a Config from a file, decoded according to its extension.
+func LoadFile(filename string) (*Config, error) {
+	decodersMu.RLock()
+	unmarshal, ok := decoders[strings.ToLower(filepath.Ext(filename))]
+	decodersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("rlogconfig: no decoder registered for %q", filename)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err = unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("rlogconfig: %s: %w", filename, err)
+	}
+	return c, nil
+}
+
+// FromEnv overrides the fields of c by the environment variables named by prefix and the upper
+// case field tag, e.g. RLOG_PATH, RLOG_LEVEL, RLOG_MAX_SIZE for the prefix "RLOG_".
+func (c *Config) FromEnv(prefix string) error {
+	str := map[string]*string{
+		"PATH":     &c.Path,
+		"ROTATION": &c.Rotation,
+		"DAILY_AT": &c.DailyAt,
+		"INTERVAL": &c.Interval,
+		"LEVEL":    &c.Level,
+		"FORMAT":   &c.Format,
+	}
+	for k, p := range str {
+		if v, ok := os.LookupEnv(prefix + k); ok {
+			*p = v
+		}
+	}
+	ints := map[string]*int64{
+		"MAX_SIZE":       &c.MaxSize,
+		"MAX_TOTAL_SIZE": &c.MaxTotalSize,
+	}
+	for k, p := range ints {
+		if v, ok := os.LookupEnv(prefix + k); ok {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return fmt.Errorf("rlogconfig: %s%s: %w", prefix, k, err)
+			}
+			*p = n
+		}
+	}
+	if v, ok := os.LookupEnv(prefix + "MAX_FILES"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("rlogconfig: %sMAX_FILES: %w", prefix, err)
+		}
+		c.MaxFiles = n
+	}
+	bools := map[string]*bool{
+		"UTC":        &c.UTC,
+		"ADD_SOURCE": &c.AddSource,
+	}
+	for k, p := range bools {
+		if v, ok := os.LookupEnv(prefix + k); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("rlogconfig: %s%s: %w", prefix, k, err)
+			}
+			*p = b
+		}
+	}
+	return nil
+}
+
+// Build creates the logger described by c. The rotation.Logger is returned too, so it can be
+// closed by the caller.
+func (c *Config) Build() (*slog.Logger, *rotation.Logger, error) {
+	opts, err := c.rotationOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	var level slog.Level
+	if c.Level != "" {
+		if err = level.UnmarshalText([]byte(c.Level)); err != nil {
+			return nil, nil, fmt.Errorf("rlogconfig: level: %w", err)
+		}
+	}
+
+	fileLog, err := rotation.New(c.Path, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	hOpts := &slog.HandlerOptions{AddSource: c.AddSource, Level: level}
+	var h slog.Handler
+	switch strings.ToLower(c.Format) {
+	case "", "text":
+		h = handler.NewDefaultHandler(fileLog, hOpts)
+	case "json":
+		h = handler.NewJSONHandler(fileLog, hOpts)
+	case "console":
+		h = handler.NewConsoleHandler(fileLog, hOpts, nil)
+	default:
+		fileLog.Close()
+		return nil, nil, fmt.Errorf("rlogconfig: unknown format %q", c.Format)
+	}
+	return slog.New(h), fileLog, nil
+}
+
+func (c *Config) rotationOptions() ([]rotation.Option, error) {
+	var opts []rotation.Option
+	h, m := 0, 0
+	if c.DailyAt != "" {
+		t, err := time.Parse("15:04", c.DailyAt)
+		if err != nil {
+			return nil, fmt.Errorf("rlogconfig: daily_at: %w", err)
+		}
+		h, m = t.Hour(), t.Minute()
+	}
+	switch strings.ToLower(c.Rotation) {
+	case "", "daily":
+		opts = append(opts, rotation.WithDailyAt(h, m))
+	case "size":
+		opts = append(opts, rotation.WithMaxSize(c.MaxSize), rotation.WithMaxFiles(c.MaxFiles))
+	case "hybrid":
+		opts = append(opts, rotation.WithDailyAt(h, m), rotation.WithMaxSize(c.MaxSize))
+	case "interval":
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("rlogconfig: interval: %w", err)
+		}
+		opts = append(opts, rotation.WithInterval(d))
+	default:
+		return nil, fmt.Errorf("rlogconfig: unknown rotation %q", c.Rotation)
+	}
+	if c.MaxTotalSize > 0 {
+		opts = append(opts, rotation.WithMaxTotalSize(c.MaxTotalSize))
+	}
+	if c.UTC {
+		opts = append(opts, rotation.WithUTC())
+	}
+	return opts, nil
+}
@@ -0,0 +1,79 @@
+package rlogconfig
+
+import (
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/wytools/rlog/handler"
+	"github.com/wytools/rlog/rotation"
+)
+
+// Setup is a logger created by a preset, with the parts the caller may need later.
+type Setup struct {
+	Logger *slog.Logger
+	File   *rotation.Logger      // the log file, nil if the logger writes to the console only
+	Level  *handler.DynamicLevel // the level of the logger, it can be changed at runtime
+}
+
+// Close closes the log file, if any.
+func (s *Setup) Close() error {
+	if s.File == nil {
+		return nil
+	}
+	return s.File.Close()
+}
+
+// Development returns a logger for local work: colored console output to stderr at the debug
+// level, with the source locations.
+//
+//	setup := rlogconfig.Development()
+//	slog.SetDefault(setup.Logger)
+func Development() *Setup {
+	level := handler.NewDynamicLevel(slog.LevelDebug)
+	h := handler.NewConsoleHandler(os.Stderr, &slog.HandlerOptions{AddSource: true, Level: level}, nil)
+	return &Setup{Logger: slog.New(h), Level: level}
+}
+
+// Production returns a logger for services: JSON lines at the info level, to a file rotated
+// daily at 00:00 UTC and when it exceeds 100MB, keeping 30 days and at most 10GB of files. The
+// records with the same level and message are sampled over 100 per second, see
+// handler.NewSampler.
+//
+//	setup, err := rlogconfig.Production("/var/log/app/app.log")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer setup.Close()
+func Production(filename string) (*Setup, error) {
+	fileLog, err := rotation.New(filename,
+		rotation.WithDailyAt(0, 0),
+		rotation.WithMaxSize(100<<20),
+		rotation.WithMaxAge(30*24*time.Hour),
+		rotation.WithMaxTotalSize(10<<30),
+		rotation.WithUTC(),
+		rotation.WithLock(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	level := handler.NewDynamicLevel(slog.LevelInfo)
+	h := handler.NewSampler(handler.NewJSONHandler(fileLog, &slog.HandlerOptions{Level: level}), 100, 100)
+	return &Setup{Logger: slog.New(h), File: fileLog, Level: level}, nil
+}
+
+// Minimal returns a logger writing text at the info level to a file rotated by size only, when
+// it exceeds 10MB, keeping 5 files. Nothing else is set up.
+func Minimal(filename string) (*Setup, error) {
+	fileLog, err := rotation.New(filename,
+		rotation.WithMaxSize(10<<20),
+		rotation.WithMaxFiles(5),
+		rotation.WithLock(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	level := handler.NewDynamicLevel(slog.LevelInfo)
+	h := handler.NewDefaultHandler(fileLog, &slog.HandlerOptions{Level: level})
+	return &Setup{Logger: slog.New(h), File: fileLog, Level: level}, nil
+}
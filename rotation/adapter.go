@@ -0,0 +1,131 @@
+package rotation
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// WriteSyncer is an io.Writer which can flush its writes to stable storage. It has the method
+// set of zapcore.WriteSyncer, so the Logger and the BufferedWriter can be passed to
+// zapcore.NewCore directly:
+//
+//	l, _ := rotation.New("app.log", rotation.WithMaxSize(100<<20), rotation.WithLock())
+//	core := zapcore.NewCore(zapcore.NewJSONEncoder(cfg), l, zap.InfoLevel)
+//
+// The zap loggers call Sync on Logger.Sync of zap, so the buffered logs reach the file before
+// the process exits. A Logger without WithLock must be wrapped by zapcore.Lock if it's shared.
+// A zap.BufferedWriteSyncer keeps the lines of several Writes in one, which the Logger rotates
+// as a whole, so the size of a file may exceed WithMaxSize by the size of its buffer.
+//
+// The loggers writing to an io.Writer only, such as logrus, take the Logger directly, and sync
+// it themselves before exiting:
+//
+//	logrus.SetOutput(l)
+//	defer l.Sync()
+type WriteSyncer interface {
+	io.Writer
+	Sync() error
+}
+
+var (
+	_ WriteSyncer = (*Logger)(nil)
+	_ WriteSyncer = (*BufferedWriter)(nil)
+)
+
+// NewWriteSyncer returns w as a WriteSyncer. If w has no Sync method, Sync calls its Flush
+// method if it has one, such as a bufio.Writer, or does nothing.
+func NewWriteSyncer(w io.Writer) WriteSyncer {
+	if ws, ok := w.(WriteSyncer); ok {
+		return ws
+	}
+	return writeSyncer{w}
+}
+
+type writeSyncer struct {
+	io.Writer
+}
+
+func (w writeSyncer) Sync() error {
+	switch f := w.Writer.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
+
+// zerologLevels are the levels of zerolog, from the lowest.
+var zerologLevels = []string{"trace", "debug", "info", "warn", "error", "fatal", "panic"}
+
+// LevelSplitWriter writes all the JSON lines to a file, and the lines at a level or above to
+// the console too, such as the errors to stderr. It reads the "level" field written by zerolog,
+// so it suits the split of zerolog outputs without a zerolog.LevelWriter:
+//
+//	file, _ := rotation.New("app.log", rotation.WithLock())
+//	console := zerolog.ConsoleWriter{Out: os.Stderr}
+//	logger := zerolog.New(rotation.NewLevelSplitWriter(file, console, "warn"))
+//
+// Every Write must be a whole line, as zerolog writes them. The lines without a known level go
+// to the file only.
+type LevelSplitWriter struct {
+	file, console io.Writer
+	consoleFrom   int // the index of the min console level in zerologLevels
+}
+
+// NewLevelSplitWriter creates a LevelSplitWriter writing to the console the lines at level
+// consoleFrom or above, such as "warn". It panics if consoleFrom isn't a level of zerolog, as
+// writing all the lines to the console instead would hide the mistake.
+func NewLevelSplitWriter(file, console io.Writer, consoleFrom string) *LevelSplitWriter {
+	w := &LevelSplitWriter{file: file, console: console, consoleFrom: -1}
+	for i, l := range zerologLevels {
+		if l == consoleFrom {
+			w.consoleFrom = i
+		}
+	}
+	if w.consoleFrom < 0 {
+		panic(fmt.Sprintf("rotation: unknown zerolog level %q", consoleFrom))
+	}
+	return w
+}
+
+// Write writes p to the file, and to the console if its level is high enough. The error of
+// the file is returned first.
+func (w *LevelSplitWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	if w.level(p) >= w.consoleFrom {
+		if _, cerr := w.console.Write(p); err == nil {
+			err = cerr
+		}
+	}
+	return n, err
+}
+
+// Sync syncs the file and the console if they are WriteSyncers.
+func (w *LevelSplitWriter) Sync() error {
+	err := NewWriteSyncer(w.file).Sync()
+	if cerr := NewWriteSyncer(w.console).Sync(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// level returns the index of the level of the line p, -1 if unknown.
+func (w *LevelSplitWriter) level(p []byte) int {
+	const field = `"level":"`
+	i := bytes.Index(p, []byte(field))
+	if i < 0 {
+		return -1
+	}
+	v := p[i+len(field):]
+	if j := bytes.IndexByte(v, '"'); j >= 0 {
+		v = v[:j]
+	}
+	for i, l := range zerologLevels {
+		if string(v) == l {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,79 @@
+package rotation
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewWriteSyncer(t *testing.T) {
+	l, err := New(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if ws := NewWriteSyncer(l); ws != WriteSyncer(l) {
+		t.Errorf("NewWriteSyncer(Logger) = %T, want the Logger itself", ws)
+	}
+
+	var out bytes.Buffer
+	ws := NewWriteSyncer(bufio.NewWriter(&out))
+	ws.Write([]byte("line\n"))
+	if out.Len() != 0 {
+		t.Fatalf("written before Sync: %q", out.String())
+	}
+	if err := ws.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "line\n" {
+		t.Errorf("after Sync = %q, want the flushed line", out.String())
+	}
+}
+
+func TestLevelSplitWriterRotation(t *testing.T) {
+	dir := t.TempDir()
+	file, err := New(filepath.Join(dir, "app.log"), WithMaxSize(40), WithMaxFiles(3), WithLock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var console bytes.Buffer
+	w := NewLevelSplitWriter(file, &console, "warn")
+	lines := []string{
+		`{"level":"info","message":"a"}` + "\n",
+		`{"level":"warn","message":"b"}` + "\n",
+		`{"level":"debug","message":"c"}` + "\n",
+		`{"level":"error","message":"d"}` + "\n",
+		`{"message":"no level"}` + "\n",
+	}
+	for _, line := range lines {
+		if n, err := w.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := console.String(), lines[1]+lines[3]; got != want {
+		t.Errorf("console = %q, want %q", got, want)
+	}
+	// every line is a single Write, so the files are rotated between the lines
+	checkFiles(t, dir, map[string]string{
+		"app0.log": lines[0] + lines[1],
+		"app1.log": lines[2] + lines[3],
+		"app2.log": lines[4],
+	})
+}
+
+func TestNewLevelSplitWriterUnknownLevel(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), `"warning"`) {
+			t.Errorf("recovered %v, want a panic naming the level", r)
+		}
+	}()
+	NewLevelSplitWriter(&bytes.Buffer{}, &bytes.Buffer{}, "warning")
+}
@@ -0,0 +1,123 @@
+package rotation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// Archiver stores a completed log file somewhere else, such as an object storage.
+// Implementations for S3 and GCS are in the github.com/wytools/rlog/archive package.
+type Archiver interface {
+	Archive(ctx context.Context, filename string) error
+}
+
+// ArchiverFunc adapts a function to an Archiver.
+type ArchiverFunc func(ctx context.Context, filename string) error
+
+// Archive calls f(ctx, filename).
+func (f ArchiverFunc) Archive(ctx context.Context, filename string) error {
+	return f(ctx, filename)
+}
+
+// WithArchiver hands every completed file to a, after it is rotated. The archiving runs in its own
+// goroutine so Write is never blocked by it. If deleteLocal is true, the file is deleted after it is
+// archived successfully. Errors are passed to the OnError hook.
+func WithArchiver(a Archiver, deleteLocal bool) Option {
+	return func(l *Logger) {
+		l.archiver = a
+		l.bArchiveDeleteLocal = deleteLocal
+	}
+}
+
+// complete compresses the file completed by a rotation, records its checksum and archives it,
+// as set, in a new goroutine.
+func (l *Logger) complete(filename string) {
+	if l.compressor == nil && !l.bChecksums {
+		if l.archiver != nil {
+			l.archive(filename)
+		}
+		return
+	}
+	c, start, end := l.compressor, l.fileStart, l.clock.Now()
+	manifest, _ := l.manifestName()
+	names := []string{filename}
+	if c != nil {
+		names = append(names, filename+c.Ext())
+	}
+	l.startWork(names...)
+	go func() {
+		defer l.endWork(names...)
+		if c != nil {
+			compressed, err := compressFile(c, filename)
+			if err != nil {
+				l.reportError(err)
+				return
+			}
+			if err = l.syncDirs(compressed); err != nil {
+				l.reportError(err)
+			}
+			filename = compressed
+		}
+		if l.bChecksums {
+			if err := l.recordChecksum(manifest, filename, start, end); err != nil {
+				l.reportError(err)
+			}
+		}
+		if l.archiver != nil {
+			l.archive(filename)
+		}
+	}()
+}
+
+// archive archives the completed file in a new goroutine
+func (l *Logger) archive(filename string) {
+	a, deleteLocal := l.archiver, l.bArchiveDeleteLocal
+	l.startWork(filename)
+	go func() {
+		defer l.endWork(filename)
+		if err := a.Archive(context.Background(), filename); err != nil {
+			l.reportError(err)
+			return
+		}
+		if deleteLocal && !l.isHeld(filename) {
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				l.reportError(err)
+			}
+		}
+	}()
+}
+
+// startWork counts a goroutine completing the files names, Close waits for it and the retention
+// skips the files until endWork.
+func (l *Logger) startWork(names ...string) {
+	l.work.Add(1)
+	l.workMu.Lock()
+	defer l.workMu.Unlock()
+	if l.working == nil {
+		l.working = map[string]int{}
+	}
+	for _, name := range names {
+		l.working[filepath.Clean(name)]++
+	}
+}
+
+// endWork ends a goroutine started by startWork.
+func (l *Logger) endWork(names ...string) {
+	l.workMu.Lock()
+	for _, name := range names {
+		name = filepath.Clean(name)
+		if l.working[name]--; l.working[name] <= 0 {
+			delete(l.working, name)
+		}
+	}
+	l.workMu.Unlock()
+	l.work.Done()
+}
+
+// isWorking reports whether the file name is being completed, so the retention must keep it.
+func (l *Logger) isWorking(name string) bool {
+	l.workMu.Lock()
+	defer l.workMu.Unlock()
+	return l.working[filepath.Clean(name)] > 0
+}
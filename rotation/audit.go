@@ -0,0 +1,218 @@
+package rotation
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WithAuditChain makes the log files tamper-evident: every line gets a chain value, the
+// HMAC-SHA256 by key of the line and of the chain value of the line before it, so a line can't
+// be changed, removed or inserted without breaking the chain of all the lines after it. The
+// value is added as a "chain" member of the JSON objects, and as a chain attr at the end of the
+// other lines:
+//
+//	{"time":"2024-05-01T10:00:00Z","level":"INFO","msg":"login","user":"ann","chain":"5e1f…"}
+//	time=2024-05-01T10:00:00Z level=INFO msg=login user=ann chain=5e1f…
+//
+// The chain starts again in every file, and goes on from the last line of the file when the
+// logger reopens it. Without a key, the chain value is the SHA-256, which detects the changes
+// of the tools unaware of the chain only. The writes must be whole lines, as the handlers do.
+// The files are checked by VerifyChain, or the verify command of cmd/rlog; the removal of
+// whole files or of the last lines of a file are detected by WithChecksums.
+func WithAuditChain(key []byte) Option {
+	return func(l *Logger) {
+		l.audit = &auditChain{key: key}
+	}
+}
+
+// auditChain is the state of the chain of WithAuditChain.
+type auditChain struct {
+	key  []byte
+	of   *os.File // the file the chain value belongs to, so a new file starts a new chain
+	prev [sha256.Size]byte
+	buf  []byte
+}
+
+const (
+	chainHexLen     = 2 * sha256.Size
+	chainJSONPrefix = `"chain":"`
+	chainTextPrefix = " chain="
+)
+
+// writeChained writes the lines of p with their chain values.
+func (l *Logger) writeChained(p []byte) (int, error) {
+	a := l.audit
+	// the other processes of WithFileLock may have written since
+	if l.file != a.of || l.bFileLock {
+		a.of = l.file
+		a.prev = [sha256.Size]byte{}
+		if l.file != nil && l.file != os.Stdout {
+			if line, err := lastLine(l.file.Name()); err == nil && len(line) > 0 {
+				if _, v, ok := splitChain(line); ok {
+					a.prev = v
+				}
+			}
+		}
+	}
+	a.buf = a.buf[:0]
+	for rest := p; len(rest) > 0; {
+		line, after, found := bytes.Cut(rest, []byte{'\n'})
+		a.buf = a.appendLine(a.buf, line)
+		if found {
+			a.buf = append(a.buf, '\n')
+		}
+		rest = after
+	}
+	n, err := l.writeFile(a.buf)
+	if n < len(a.buf) {
+		return min(n, len(p)), err
+	}
+	return len(p), err
+}
+
+// appendLine appends line with its chain value to dst, and advances the chain.
+func (a *auditChain) appendLine(dst, line []byte) []byte {
+	a.prev = chainValue(a.key, a.prev, line)
+	var v [chainHexLen]byte
+	hex.Encode(v[:], a.prev[:])
+	if isJSONObject(line) {
+		dst = append(dst, line[:len(line)-1]...)
+		if len(bytes.TrimSpace(line[1:len(line)-1])) > 0 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, chainJSONPrefix...)
+		dst = append(dst, v[:]...)
+		return append(dst, '"', '}')
+	}
+	dst = append(dst, line...)
+	dst = append(dst, chainTextPrefix...)
+	return append(dst, v[:]...)
+}
+
+func chainValue(key []byte, prev [sha256.Size]byte, line []byte) (v [sha256.Size]byte) {
+	if key == nil {
+		h := sha256.New()
+		h.Write(prev[:])
+		h.Write(line)
+		h.Sum(v[:0])
+		return v
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write(prev[:])
+	h.Write(line)
+	h.Sum(v[:0])
+	return v
+}
+
+func isJSONObject(line []byte) bool {
+	return len(line) >= 2 && line[0] == '{' && line[len(line)-1] == '}'
+}
+
+// splitChain returns the line without its chain value, and the chain value.
+func splitChain(line []byte) (content []byte, v [sha256.Size]byte, ok bool) {
+	var hexv []byte
+	if isJSONObject(line) {
+		end := len(line) - 2 // before `"}`
+		start := end - chainHexLen
+		if start < len(chainJSONPrefix)+1 || string(line[start-len(chainJSONPrefix):start]) != chainJSONPrefix || line[end] != '"' {
+			return nil, v, false
+		}
+		hexv = line[start:end]
+		content = line[:start-len(chainJSONPrefix)]
+		if content[len(content)-1] == ',' {
+			content = content[:len(content)-1]
+		}
+		content = append(content[:len(content):len(content)], '}')
+	} else {
+		start := len(line) - chainHexLen
+		if start < len(chainTextPrefix) || string(line[start-len(chainTextPrefix):start]) != chainTextPrefix {
+			return nil, v, false
+		}
+		hexv = line[start:]
+		content = line[:start-len(chainTextPrefix)]
+	}
+	if _, err := hex.Decode(v[:], hexv); err != nil {
+		return nil, v, false
+	}
+	return content, v, true
+}
+
+// lastLine returns the last line of a file, without its newline.
+func lastLine(name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const chunk = 4096
+	var tail []byte
+	for end := fi.Size(); end > 0; {
+		start := max(end-chunk, 0)
+		buf := make([]byte, end-start)
+		if _, err := f.ReadAt(buf, start); err != nil {
+			return nil, err
+		}
+		tail = append(buf, tail...)
+		end = start
+		if i := bytes.LastIndexByte(bytes.TrimSuffix(tail, []byte{'\n'}), '\n'); i >= 0 {
+			return bytes.TrimSuffix(tail[i+1:], []byte{'\n'}), nil
+		}
+	}
+	return bytes.TrimSuffix(tail, []byte{'\n'}), nil
+}
+
+// ChainError is the error of VerifyChain for a broken chain.
+type ChainError struct {
+	Line   int    // the number of the first line breaking the chain, from 1
+	Reason string // "no chain value" or "chain value mismatch"
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("rotation: audit chain broken at line %d: %s", e.Line, e.Reason)
+}
+
+// ErrChainBroken matches the errors of VerifyChain for a broken chain, by errors.Is.
+var ErrChainBroken = errors.New("rotation: audit chain broken")
+
+func (e *ChainError) Is(target error) bool {
+	return target == ErrChainBroken
+}
+
+// VerifyChain checks the chain of the lines of a file written with WithAuditChain and key, and
+// returns the number of lines checked. A broken chain returns a *ChainError with the first line
+// which was changed, inserted or follows a removed one.
+func VerifyChain(r io.Reader, key []byte) (int, error) {
+	br := bufio.NewReader(r)
+	var prev [sha256.Size]byte
+	n := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			n++
+			content, v, ok := splitChain(bytes.TrimSuffix(line, []byte{'\n'}))
+			if !ok {
+				return n - 1, &ChainError{Line: n, Reason: "no chain value"}
+			}
+			if prev = chainValue(key, prev, content); !hmac.Equal(prev[:], v[:]) {
+				return n - 1, &ChainError{Line: n, Reason: "chain value mismatch"}
+			}
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
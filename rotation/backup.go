@@ -0,0 +1,150 @@
+package rotation
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backupTimeFormat is the time format of the backup file names of WithTimestampedBackups.
+const backupTimeFormat = "20060102T150405"
+
+// WithTimestampedBackups changes the file naming of a SizedRotation logger: the current file is
+// always the set file name, such as out.log, and it is renamed to a timestamped backup, such as
+// out-20240501T120000.log, when it is full. The oldest backups are removed to keep the number
+// set by WithMaxFiles. Unlike the default index naming, no file is ever overwritten in place,
+// which suits the ingestion agents tailing the current file.
+func WithTimestampedBackups() Option {
+	return func(l *Logger) {
+		l.bBackups = true
+	}
+}
+
+// openActiveFile opens the current file of a logger with timestamped backups.
+func (l *Logger) openActiveFile() (*os.File, error) {
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return nil, err
+	}
+	logFile, err := l.openFile(path + fn + suffix)
+	if err != nil {
+		return nil, err
+	}
+	fInfo, err := logFile.Stat()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	l.rSize = fInfo.Size()
+	return logFile, nil
+}
+
+// openNewBackupFile renames the current file to a timestamped backup, and opens a new one.
+func (l *Logger) openNewBackupFile() (*os.File, error) {
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return nil, err
+	}
+	active := path + fn + suffix
+	// the file is closed before the rename, which fails for the open files on Windows
+	if l.file != nil && l.file != os.Stdout {
+		l.syncBeforeClose()
+		l.file.Close()
+		l.file = nil
+		l.setCurrentFile("")
+	}
+	stamp := fn + "-" + l.now().Format(backupTimeFormat)
+	backup := path + stamp + suffix
+	// the backups of the same second are indexed after the existing ones, even pruned ones
+	if next := nextBackupIndex(path, stamp, suffix); next > 0 {
+		backup = path + stamp + "_" + strconv.Itoa(next) + suffix
+	}
+	if err = os.Rename(active, backup); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		l.rotatedTo = backup
+		if err = l.syncDirs(backup); err != nil {
+			return nil, err
+		}
+	}
+	logFile, err := l.openActiveFile()
+	if err != nil {
+		return nil, err
+	}
+	l.pruneBackups(path, fn, suffix)
+	return logFile, nil
+}
+
+// nextBackupIndex returns the index of the next backup named stamp, 0 if there is none yet.
+func nextBackupIndex(path, stamp, suffix string) int {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return 0
+	}
+	next := 0
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), suffix)
+		if name == stamp {
+			next = max(next, 1)
+		} else if i, ok := strings.CutPrefix(name, stamp+"_"); ok {
+			if n, err := strconv.Atoi(i); err == nil {
+				next = max(next, n+1)
+			}
+		}
+	}
+	return next
+}
+
+// pruneBackups removes the oldest backups over rMaxNum.
+func (l *Logger) pruneBackups(path, fn, suffix string) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return
+	}
+	type backupFile struct {
+		name  string
+		stamp string
+		index int // the index of the backups with the same stamp
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, fn+"-") || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, fn+"-"), suffix)
+		b := backupFile{name: name, stamp: stamp}
+		if i := strings.IndexByte(stamp, '_'); i >= 0 {
+			if b.index, err = strconv.Atoi(stamp[i+1:]); err != nil {
+				continue
+			}
+			b.stamp = stamp[:i]
+		}
+		if _, err = time.Parse(backupTimeFormat, b.stamp); err != nil {
+			continue
+		}
+		backups = append(backups, b)
+	}
+	if len(backups) <= l.rMaxNum {
+		return
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].stamp != backups[j].stamp {
+			return backups[i].stamp < backups[j].stamp
+		}
+		return backups[i].index < backups[j].index
+	})
+	var held map[string]bool
+	if files, err := l.listLogFiles(); err == nil {
+		held = l.heldFiles(files)
+	}
+	for _, b := range backups[:len(backups)-l.rMaxNum] {
+		if name := filepath.Join(path, b.name); !held[name] && !l.isWorking(name) {
+			os.Remove(name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package rotation
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// syncer is implemented by the writers able to commit their data to the storage, such as os.File.
+type syncer interface {
+	Sync() error
+}
+
+// BufferedWriter wraps a writer, usually a Logger, with a buffer, so small log lines don't cost
+// a syscall each. The buffer is flushed when it is full, every flushEvery, and by Flush, Sync
+// and Close.
+//
+// The buffer only holds whole Writes, a Write is never split between two flushes, so a log line
+// is never split between two files by a rotation. As a flush is a single Write to the Logger,
+// a SizedRotation file can exceed its max size by up to the buffer size. The Writes after Close
+// are not buffered, they are passed to the underlying writer at once.
+type BufferedWriter struct {
+	w    io.WriteCloser
+	size int
+
+	mu     sync.Mutex
+	buf    []byte
+	err    error // the error of the last flush by the timer, returned by the next call
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewBufferedWriter creates a BufferedWriter writing to w with a buffer of size bytes, 4KB if
+// size <= 0, flushed every flushEvery if flushEvery > 0.
+func NewBufferedWriter(w io.WriteCloser, size int, flushEvery time.Duration) *BufferedWriter {
+	if size <= 0 {
+		size = 4096
+	}
+	b := &BufferedWriter{
+		w:    w,
+		size: size,
+		buf:  make([]byte, 0, size),
+	}
+	if flushEvery > 0 {
+		b.stop = make(chan struct{})
+		b.done = make(chan struct{})
+		go b.flushLoop(flushEvery)
+	}
+	return b
+}
+
+func (b *BufferedWriter) flushLoop(d time.Duration) {
+	defer close(b.done)
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.mu.Lock()
+			if err := b.flush(); err != nil {
+				b.err = err
+			}
+			b.mu.Unlock()
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// Write implements io.Writer.
+func (b *BufferedWriter) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return 0, err
+	}
+	if b.closed {
+		return b.w.Write(p)
+	}
+	if len(b.buf)+len(p) > b.size {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+	if len(p) >= b.size {
+		return b.w.Write(p)
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (b *BufferedWriter) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+// Sync flushes the buffer, then commits the data to the storage if the underlying writer
+// can Sync.
+func (b *BufferedWriter) Sync() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.takeErr(); err != nil {
+		return err
+	}
+	if err := b.flush(); err != nil {
+		return err
+	}
+	if s, ok := b.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close stops the periodic flushes, flushes the buffer and closes the underlying writer.
+func (b *BufferedWriter) Close() error {
+	if b.stop != nil {
+		close(b.stop)
+		<-b.done
+		b.stop = nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	err := b.takeErr()
+	if ferr := b.flush(); err == nil {
+		err = ferr
+	}
+	if cerr := b.w.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (b *BufferedWriter) flush() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+	_, err := b.w.Write(b.buf)
+	b.buf = b.buf[:0]
+	return err
+}
+
+func (b *BufferedWriter) takeErr() error {
+	err := b.err
+	b.err = nil
+	return err
+}
@@ -0,0 +1,142 @@
+package rotation
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrChecksumMismatch is the error of VerifyManifest for a file whose size or SHA-256 differs
+// from the ones recorded when it was completed.
+var ErrChecksumMismatch = errors.New("rotation: the file doesn't match its checksum")
+
+// WithChecksums records every file completed by a rotation in the manifest, with its size, its
+// SHA-256 and the time range it was written in, so it can be proven later that the file wasn't
+// modified, see VerifyManifest. With WithCompression, the compressed file is recorded. The
+// checksums are computed in the background, the errors are passed to the OnError hook.
+func WithChecksums() Option {
+	return func(l *Logger) {
+		l.bChecksums = true
+	}
+}
+
+// recordChecksum appends the completed file filename, written from start to end, to the
+// manifest.
+func (l *Logger) recordChecksum(manifest, filename string, start, end time.Time) error {
+	size, sum, err := fileChecksum(filename)
+	if err != nil {
+		return err
+	}
+	return appendManifest(manifest, ManifestEntry{
+		Time:   end,
+		Event:  "completed",
+		File:   filename,
+		Size:   size,
+		SHA256: sum,
+		Start:  &start,
+	}, l.fileMode)
+}
+
+// fileChecksum returns the size and the hex SHA-256 of a file.
+func fileChecksum(filename string) (int64, string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ManifestCheck is the result of the verification of a completed file recorded in a manifest.
+type ManifestCheck struct {
+	File string // the file, found at its recorded name or in the directory of the manifest
+	// Err is nil if the file is unchanged, ErrChecksumMismatch if it was modified, or the error
+	// reading it, such as a fs.ErrNotExist one if it was removed.
+	Err error
+}
+
+// VerifyManifest checks the files recorded as completed in a manifest by WithChecksums against
+// their sizes and SHA-256. A file which isn't at its recorded name is looked for in the
+// directory of the manifest, so the logs can be verified after they were moved elsewhere. The
+// last record of a file is checked, as the names of SizedRotation files are reused, and the
+// files rewritten by EraseSubject or renamed by WithMigration are checked by the checksums and
+// the names of their "erased" and "renamed" records.
+func VerifyManifest(manifest string) ([]ManifestCheck, error) {
+	f, err := os.Open(manifest)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string // the files in the order of their first record
+	last := map[string]*ManifestEntry{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e ManifestEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, err
+		}
+		switch e.Event {
+		case "completed":
+			if last[e.File] == nil {
+				names = append(names, e.File)
+			}
+			last[e.File] = &e
+		case "erased":
+			if c := last[e.File]; c != nil {
+				c.Size, c.SHA256 = e.Size, e.SHA256
+			}
+		case "renamed":
+			if c := last[e.From]; c != nil {
+				delete(last, e.From)
+				if last[e.To] == nil {
+					names = append(names, e.To)
+				}
+				c.File = e.To
+				last[e.To] = c
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	entries := make([]ManifestEntry, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		if e := last[name]; e != nil && !seen[name] {
+			seen[name] = true
+			entries = append(entries, *e)
+		}
+	}
+
+	checks := make([]ManifestCheck, 0, len(entries))
+	for _, e := range entries {
+		name := e.File
+		if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+			if moved := filepath.Join(filepath.Dir(manifest), filepath.Base(name)); fileExists(moved) {
+				name = moved
+			}
+		}
+		size, sum, err := fileChecksum(name)
+		if err == nil && (size != e.Size || sum != e.SHA256) {
+			err = ErrChecksumMismatch
+		}
+		checks = append(checks, ManifestCheck{File: name, Err: err})
+	}
+	return checks, nil
+}
+
+func fileExists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
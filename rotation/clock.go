@@ -0,0 +1,48 @@
+package rotation
+
+import "time"
+
+// Clock is the source of time of a Logger: its rotation schedule, its file names and its
+// retention. It can be replaced by WithClock, so the time rotations can be tested without
+// sleeping:
+//
+//	clock := &fakeClock{now: time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)}
+//	l, _ := rotation.New("app.log", rotation.WithDailyAt(0, 0), rotation.WithClock(clock))
+//	clock.now = clock.now.Add(time.Minute) // the next Write opens the file of May 2
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer created by a Clock, like time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// WithClock sets the Clock of the logger, the system clock by default.
+func WithClock(c Clock) Option {
+	return func(l *Logger) {
+		l.clock = c
+	}
+}
+
+// systemClock is the Clock of the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
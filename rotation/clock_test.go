@@ -0,0 +1,167 @@
+package rotation
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata" // the DST test needs America/New_York on every system
+)
+
+func TestDailyRotationUTC(t *testing.T) {
+	// 2 hours ahead of UTC: the local midnight is 22:00 UTC
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want map[string]string
+	}{
+		{"local", nil, map[string]string{
+			"app_2024_05_01.log": "a\n",
+			"app_2024_05_02.log": "b\nc\n",
+		}},
+		{"UTC", []Option{WithUTC()}, map[string]string{
+			"app_2024_05_01.log": "a\nb\n",
+			"app_2024_05_02.log": "c\n",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			clock := &fakeClock{now: time.Date(2024, 5, 1, 23, 59, 59, 0, zone)}
+			opts := append([]Option{WithDailyAt(0, 0), WithClock(clock), WithTimeFormat("_2006_01_02")}, tc.opts...)
+			l, err := New(filepath.Join(dir, "app.log"), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeString(t, l, "a\n")
+			// the local midnight
+			clock.add(time.Second)
+			writeString(t, l, "b\n")
+			// the UTC midnight
+			clock.set(time.Date(2024, 5, 2, 2, 0, 0, 0, zone))
+			writeString(t, l, "c\n")
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			checkFiles(t, dir, tc.want)
+		})
+	}
+}
+
+func TestDailyRotationDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	// the clocks go forward at 2:00 on March 10, which has 23 hours
+	clock := &fakeClock{now: time.Date(2024, 3, 9, 12, 0, 0, 0, loc)}
+	l, err := New(filepath.Join(dir, "app.log"), WithDailyAt(6, 0), WithClock(clock), WithTimeFormat("_2006_01_02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "a\n")
+	clock.set(time.Date(2024, 3, 10, 5, 59, 59, 0, loc))
+	writeString(t, l, "b\n")
+	// 23 hours after the last rotation time, the rotation stays at 6:00
+	clock.set(time.Date(2024, 3, 10, 6, 0, 0, 0, loc))
+	writeString(t, l, "c\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app_2024_03_09.log": "a\nb\n",
+		"app_2024_03_10.log": "c\n",
+	})
+}
+
+func TestHybridRotation(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 23, 58, 0, 0, time.UTC)}
+	l, err := New(filepath.Join(dir, "app.log"), WithDailyAt(0, 0), WithMaxSize(4), WithClock(clock),
+		WithTimeFormat("_2006_01_02"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.rType != HybridRotation {
+		t.Fatalf("rotation type = %d, want HybridRotation", l.rType)
+	}
+	writeString(t, l, "aaaa")
+	// the file is full, the index is incremented within the day
+	writeString(t, l, "bb")
+	clock.set(time.Date(2024, 5, 1, 23, 59, 59, 0, time.UTC))
+	writeString(t, l, "cc")
+	// a new day starts from the index 0, though the file isn't full
+	clock.add(time.Second)
+	writeString(t, l, "dd")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app_2024_05_01_0.log": "aaaa",
+		"app_2024_05_01_1.log": "bbcc",
+		"app_2024_05_02_0.log": "dd",
+	})
+}
+
+func TestIntervalRotation(t *testing.T) {
+	// a half-hour offset: the hours of the files are the local ones
+	zone := time.FixedZone("UTC+5:30", (5*60+30)*60)
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		want map[string]string
+	}{
+		{"local", nil, map[string]string{
+			"app_2024_05_01_10.log": "a\n",
+			"app_2024_05_01_11.log": "b\nc\n",
+		}},
+		{"UTC", []Option{WithUTC()}, map[string]string{
+			"app_2024_05_01_05.log": "a\nb\n",
+			"app_2024_05_01_06.log": "c\n",
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 59, 59, 0, zone)}
+			opts := append([]Option{WithInterval(time.Hour), WithClock(clock)}, tc.opts...)
+			l, err := New(filepath.Join(dir, "app.log"), opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeString(t, l, "a\n")
+			// the local hour
+			clock.add(time.Second)
+			writeString(t, l, "b\n")
+			// the UTC hour, 06:00 UTC
+			clock.set(time.Date(2024, 5, 1, 11, 30, 0, 0, zone))
+			writeString(t, l, "c\n")
+			if err := l.Close(); err != nil {
+				t.Fatal(err)
+			}
+			checkFiles(t, dir, tc.want)
+		})
+	}
+}
+
+func TestIntervalRotationSkipsIdlePeriods(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 14, 0, 0, time.UTC)}
+	l, err := New(filepath.Join(dir, "app.log"), WithInterval(15*time.Minute), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "a\n")
+	clock.add(time.Minute)
+	writeString(t, l, "b\n")
+	// no file is created for the periods without writes
+	clock.set(time.Date(2024, 5, 1, 11, 5, 0, 0, time.UTC))
+	writeString(t, l, "c\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app_2024_05_01_10_00.log": "a\n",
+		"app_2024_05_01_10_15.log": "b\n",
+		"app_2024_05_01_11_00.log": "c\n",
+	})
+}
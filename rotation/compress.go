@@ -0,0 +1,162 @@
+package rotation
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Compressor compresses the completed log files, see WithCompression. GzipCompressor and
+// ZstdCompressor are built in, other formats can be plugged in.
+type Compressor interface {
+	// Ext returns the extension appended to the names of the compressed files, such as ".gz".
+	Ext() string
+	// NewWriter returns a writer compressing the bytes written to it into w, Close flushes it
+	// without closing w.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+}
+
+// GzipCompressor compresses the files with gzip.
+type GzipCompressor struct {
+	Level int // the compression level of compress/gzip, gzip.DefaultCompression if 0
+}
+
+func (c GzipCompressor) Ext() string {
+	return ".gz"
+}
+
+func (c GzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.Level == 0 {
+		return gzip.NewWriter(w), nil
+	}
+	return gzip.NewWriterLevel(w, c.Level)
+}
+
+// ZstdCompressor compresses the files with zstd, about twice as fast as gzip. The frames are
+// standard ones with a 1MB window, read by the zstd tool and libraries, but the encoder is a
+// simple one: the files are somewhat larger than the ones of gzip or of the zstd tool.
+type ZstdCompressor struct{}
+
+func (c ZstdCompressor) Ext() string {
+	return ".zst"
+}
+
+func (c ZstdCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return newZstdWriter(w), nil
+}
+
+// ErrZstdUnsupported is returned by OpenLogFile for the zstd files: the package writes them,
+// but it has no zstd decoder, they can be read once decompressed by zstd -d.
+var ErrZstdUnsupported = errors.New("rotation: reading zstd files isn't supported, decompress them with zstd -d first")
+
+// OpenLogFile opens a log file for reading, decompressing it if it's a .gz file. It fails with
+// an error wrapping ErrZstdUnsupported for a .zst file.
+func OpenLogFile(filename string) (io.ReadCloser, error) {
+	if filepath.Ext(filename) == ".zst" {
+		return nil, fmt.Errorf("%s: %w", filename, ErrZstdUnsupported)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(filename) != ".gz" {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &gzipFile{Reader: zr, f: f}, nil
+}
+
+// gzipFile is a gzip file opened by OpenLogFile.
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	return errors.Join(g.Reader.Close(), g.f.Close())
+}
+
+// WithCompression compresses the files completed by the rotations with c in the background,
+// replacing them by the files with the extension of c, such as app_2024_05_01.log.gz, with
+// the same modification time. The retention applies to the compressed files, and the archiver
+// of WithArchiver gets them instead. The errors are passed to the OnError hook.
+func WithCompression(c Compressor) Option {
+	return func(l *Logger) {
+		l.compressor = c
+	}
+}
+
+// compressFile writes the compressed copy of filename, then removes it. The copy is written to
+// a temporary file renamed when it's complete, so a crash doesn't leave a truncated one.
+func compressFile(c Compressor, filename string) (string, error) {
+	src, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return "", err
+	}
+	compressed := filename + c.Ext()
+	tmp := compressed + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return "", err
+	}
+	err = copyCompressed(c, dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tmp, compressed)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return compressed, os.Remove(filename)
+}
+
+func copyCompressed(c Compressor, dst *os.File, src io.Reader) error {
+	w, err := c.NewWriter(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, src); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return dst.Sync()
+}
+
+// trimCompressedExt returns name without the extension of the compressor, if any.
+func (l *Logger) trimCompressedExt(name string) string {
+	if l.compressor == nil {
+		return name
+	}
+	return strings.TrimSuffix(name, l.compressor.Ext())
+}
+
+// isCompressed reports whether the compressed file of filename exists, so its name isn't
+// reused.
+func (l *Logger) isCompressed(filename string) bool {
+	if l.compressor == nil {
+		return false
+	}
+	_, err := os.Stat(filename + l.compressor.Ext())
+	return err == nil
+}
@@ -0,0 +1,52 @@
+package rotation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithDatedDirs puts the files of DailyRotation, HybridRotation and IntervalRotation loggers
+// into subdirectories of the log directory named by the time layout, such as "2006/01/02" for
+// logs/2024/05/01/app.log, so a long-lived host doesn't pile thousands of files in one
+// directory. A file goes to the directory of the start of its rotation period.
+//
+// The file names of DailyRotation and HybridRotation loggers have no time then, unless it's set
+// by WithTimeFormat. The retention, the holds and the drains look into the subdirectories, and
+// the subdirectories emptied by the retention are removed.
+func WithDatedDirs(layout string) Option {
+	return func(l *Logger) {
+		l.dirLayout = strings.Trim(layout, "/")
+	}
+}
+
+// datedPath returns the directory of a new file under the log directory path, creating it.
+func (l *Logger) datedPath(path string) (string, error) {
+	if l.dirLayout == "" || l.rType == SizedRotation {
+		return path, nil
+	}
+	path += filepath.FromSlash(l.currentFileTime.Format(l.dirLayout)) + string(filepath.Separator)
+	return path, os.MkdirAll(path, l.dirMode)
+}
+
+// removeLogFile removes the log file name, and then its parent directories up to the log
+// directory as long as they are empty.
+func (l *Logger) removeLogFile(name string) error {
+	if err := os.Remove(name); err != nil {
+		return err
+	}
+	if l.dirLayout == "" {
+		return nil
+	}
+	path, _, _, err := getPathFileName(l.currentFilename(), l.dirMode)
+	if err != nil {
+		return nil
+	}
+	path = filepath.Clean(path)
+	for dir := filepath.Dir(name); len(dir) > len(path) && strings.HasPrefix(dir, path); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package rotation
+
+import "os"
+
+// syncDir fsyncs the directory so the entries created in it survive a power loss
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,54 @@
+package rotation
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// BenchmarkRotateDirSync measures a rotation renaming the full file to a backup and creating
+// the next one, with and without WithDirSync.
+func BenchmarkRotateDirSync(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"off", nil},
+		{"on", []Option{WithDirSync()}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := append([]Option{WithMaxSize(1 << 20), WithMaxFiles(3), WithTimestampedBackups()}, bc.opts...)
+			l, err := New(filepath.Join(b.TempDir(), "app.log"), opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer l.Close()
+			line := []byte("a log line\n")
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := l.Write(line); err != nil {
+					b.Fatal(err)
+				}
+				if err := l.Rotate(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDirSyncRotation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10), WithMaxFiles(2), WithDirSync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "line 0...\n")
+	writeString(t, l, "line 1...\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app0.log": "line 0...\n",
+		"app1.log": "line 1...\n",
+	})
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package rotation
+
+// syncDir does nothing on Windows, where the directories can't be opened for FlushFileBuffers,
+// which fails with an access denied error. NTFS journals the entries of the directories.
+func syncDir(dir string) error {
+	return nil
+}
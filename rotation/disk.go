@@ -0,0 +1,205 @@
+package rotation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DiskPolicy decides what a Logger does while the free space of the disk of its files is low,
+// see WithDiskGuard.
+type DiskPolicy int
+
+const (
+	// DiskPrune deletes the oldest log files, except the current and the held ones, until the
+	// free space is over the threshold again. If it's not enough, the logger rotates, so the
+	// current file is deleted by the next check, and keeps writing.
+	DiskPrune DiskPolicy = iota
+	// DiskDropLow drops the lines at the INFO level and below, keeping the warnings and the
+	// errors. The level is read from the "level" field of the JSON lines or the level attr of
+	// the text lines, the lines without a level are kept.
+	DiskDropLow
+	// DiskStop stops writing, Write returns ErrLowDisk.
+	DiskStop
+)
+
+// ErrLowDisk is returned by Write under the DiskStop policy while the free space is low, and
+// passed to the OnError hook when the free space gets low under any policy.
+var ErrLowDisk = errors.New("rotation: disk space is low")
+
+// diskCheckInterval is the max interval between the checks of the free space.
+const diskCheckInterval = 10 * time.Second
+
+// WithDiskGuard checks the free space of the disk of the log files every 10 seconds, or after
+// a tenth of minFree bytes was written, and applies policy while it's under minFree bytes. The
+// error hook gets an error wrapping ErrLowDisk when the free space gets low, and CheckHealth
+// reports it meanwhile. The guard is disabled, after an error passed to the hook, where the
+// free space can't be read.
+func WithDiskGuard(minFree int64, policy DiskPolicy) Option {
+	return func(l *Logger) {
+		l.diskMinFree = minFree
+		l.diskPolicy = policy
+	}
+}
+
+// checkDisk checks the free space if it's due, and applies the policy while it's low.
+func (l *Logger) checkDisk() {
+	now := l.clock.Now()
+	written := l.metrics.bytesWritten.Load()
+	if now.Before(l.diskCheckAt) && written-l.diskCheckBytes < l.diskMinFree/10 {
+		return
+	}
+	l.diskCheckAt, l.diskCheckBytes = now.Add(diskCheckInterval), written
+	path, _, _, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return
+	}
+	free, err := freeSpace(path)
+	if err != nil {
+		l.reportError(fmt.Errorf("rotation: disk guard disabled: %w", err))
+		l.diskMinFree = 0
+		return
+	}
+	if free < l.diskMinFree && l.diskPolicy == DiskPrune {
+		l.pruneForSpace()
+		if free, err = freeSpace(path); err != nil {
+			return
+		}
+	}
+	low := free < l.diskMinFree
+	if low && !l.bLowDisk {
+		l.reportError(fmt.Errorf("rotation: %s: %d bytes free, under %d: %w", filepath.Clean(path), free, l.diskMinFree, ErrLowDisk))
+	}
+	l.bLowDisk = low
+	l.metrics.lowDisk.Store(low)
+}
+
+// pruneForSpace deletes the oldest log files, except the current and the held ones, until the
+// free space is over diskMinFree. If it's still under, the current file is rotated so the
+// next check can delete it.
+func (l *Logger) pruneForSpace() {
+	path, _, _, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return
+	}
+	files, err := l.listLogFiles()
+	if err != nil {
+		return
+	}
+	current := ""
+	if l.file != nil {
+		current = filepath.Clean(l.file.Name())
+	}
+	held := l.heldFiles(files)
+	for _, f := range files {
+		if free, err := freeSpace(path); err != nil || free >= l.diskMinFree {
+			return
+		}
+		if f.path != current && !held[f.path] && !l.isWorking(f.path) {
+			l.removeLogFile(f.path)
+		}
+	}
+	if l.file == nil || l.file == os.Stdout || l.rSize == 0 {
+		return
+	}
+	old := l.file.Name()
+	if f, err := l.openNext(); err == nil {
+		renamed := l.rotatedTo != ""
+		l.setFile(f)
+		// the rotated file can be deleted at once, unless the same file was opened again
+		if renamed || f.Name() != old {
+			l.diskCheckAt = time.Time{}
+		}
+	} else {
+		l.reportError(err)
+	}
+}
+
+// writeHighLevels writes the lines of p which are not at a low level, under the DiskDropLow
+// policy.
+func (l *Logger) writeHighLevels(p []byte) (int, error) {
+	var q []byte
+	dropped := false
+	for rest := p; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line = rest[:i+1]
+		}
+		rest = rest[len(line):]
+		if isLowLevel(line) {
+			l.metrics.dropped.Add(1)
+			dropped = true
+			continue
+		}
+		q = append(q, line...)
+	}
+	if !dropped {
+		return l.writeLines(p)
+	}
+	if len(q) == 0 {
+		return len(p), nil
+	}
+	n, err := l.writeLines(q)
+	if n < len(q) {
+		return min(n, len(p)), err
+	}
+	return len(p), err
+}
+
+// isLowLevel reports whether the line is at the INFO level or below, as read from the top-level
+// level field of the JSON lines of slog and zerolog, or from the level attr of the text lines,
+// not from the nested objects nor the quoted values.
+func isLowLevel(line []byte) bool {
+	v := levelValue(line)
+	if v == nil {
+		return false
+	}
+	for _, level := range zerologLevels[:3] {
+		if len(v) < len(level) || !bytes.EqualFold(v[:len(level)], []byte(level)) {
+			continue
+		}
+		// such as INFO+2, but not INFORMATIONAL
+		if len(v) == len(level) || !isLetter(v[len(level)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// levelValue returns the rest of the line from the value of its level field, nil if it has none.
+func levelValue(line []byte) []byte {
+	json := bytes.HasPrefix(bytes.TrimLeft(line, " \t"), []byte("{"))
+	depth := 0
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if quoted {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				quoted = false
+			}
+			continue
+		}
+		switch {
+		case json && depth == 1 && bytes.HasPrefix(line[i:], []byte(`"level":"`)):
+			return line[i+len(`"level":"`):]
+		case !json && (i == 0 || line[i-1] == ' ') && bytes.HasPrefix(line[i:], []byte("level=")):
+			return line[i+len("level="):]
+		case c == '"':
+			quoted = true
+		case json && (c == '{' || c == '['):
+			depth++
+		case json && (c == '}' || c == ']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
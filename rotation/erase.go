@@ -0,0 +1,241 @@
+package rotation
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// EraseMode decides what EraseSubject does with the records of a subject.
+type EraseMode int
+
+const (
+	EraseRecords EraseMode = iota // remove the whole records mentioning the subject
+	HashSubject                   // replace the subject by its hash, keeping the records
+)
+
+// EraseSubject rewrites a completed log file without the subject identifier, such as a user ID
+// or an email address, for a data deletion request, without destroying the whole file. The
+// file is replaced atomically, keeping its mode and modification time. It returns the number
+// of the records which mentioned the subject, the file is not rewritten if there is none.
+//
+// The subject is only matched as a whole token, delimited by the quotes, the spaces and the
+// punctuation of the text and JSON records and of the URLs, such as user=42, "user":"42",
+// /users/42 or ?user=42&, so erasing 42 doesn't touch 1427. A dot delimits the tokens unless
+// it's between two letters or digits, so a subject ending a sentence is erased, but erasing
+// bob@example.com doesn't touch alice.bob@example.com, nor erasing 42 touch 42.5.
+//
+// The .gz files are decompressed and compressed again. The .zst files can't be read, an error
+// wrapping ErrZstdUnsupported is returned for them.
+//
+// The manifests of the directory of the file which record it, see WithChecksums, get an "erased"
+// record with its new size and SHA-256, so VerifyManifest still checks it.
+//
+// HashSubject replaces the subject by "hmac-sha256:" and the first 16 hex digits of its
+// HMAC-SHA256 keyed by key, so the records of the subject can still be correlated without
+// identifying it. The key is required, as the unkeyed hashes of the guessable identifiers, such
+// as the user IDs and the emails, could be reversed by hashing the guesses. It should be kept
+// secret, and the same for all the files of a subject.
+func EraseSubject(filename, subject string, mode EraseMode, key []byte) (int, error) {
+	if subject == "" {
+		return 0, nil
+	}
+	if mode == HashSubject && len(key) == 0 {
+		return 0, errors.New("rotation: HashSubject needs a key")
+	}
+	src, err := OpenLogFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	fInfo, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".erase")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(subject))
+	hashed := []byte("hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:8]))
+	needle := []byte(subject)
+	n := 0
+	r := bufio.NewReader(src)
+	var dst io.Writer = tmp
+	var zw io.WriteCloser
+	if filepath.Ext(filename) == ".gz" {
+		zw = gzip.NewWriter(tmp)
+		dst = zw
+	}
+	w := bufio.NewWriter(dst)
+	for {
+		line, rerr := r.ReadBytes('\n')
+		if len(line) > 0 {
+			if at := tokenIndexes(line, needle); len(at) > 0 {
+				n++
+				if mode == HashSubject {
+					line = replaceAt(line, at, len(needle), hashed)
+				} else {
+					line = nil
+				}
+			}
+			if _, err = w.Write(line); err != nil {
+				return 0, err
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return 0, rerr
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if err = w.Flush(); err != nil {
+		return 0, err
+	}
+	if zw != nil {
+		if err = zw.Close(); err != nil {
+			return 0, err
+		}
+	}
+	if err = tmp.Chmod(fInfo.Mode()); err != nil {
+		return 0, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err = os.Chtimes(tmp.Name(), fInfo.ModTime(), fInfo.ModTime()); err != nil {
+		return 0, err
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return 0, err
+	}
+	return n, recordErased(filename, fInfo.Mode())
+}
+
+// recordErased appends an "erased" record of the rewritten file filename to the manifests of
+// its directory which record it.
+func recordErased(filename string, mode os.FileMode) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	manifests, err := filepath.Glob(filepath.Join(filepath.Dir(abs), "*.manifest"))
+	if err != nil || len(manifests) == 0 {
+		return err
+	}
+	size, sum, err := fileChecksum(abs)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, manifest := range manifests {
+		if name, ok := recordedName(manifest, abs); ok {
+			e := ManifestEntry{Time: time.Now(), Event: "erased", File: name, Size: size, SHA256: sum}
+			errs = append(errs, appendManifest(manifest, e, mode.Perm()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordedName returns the name under which the manifest records the file abs, last completed
+// or renamed there, possibly in another directory if the logs were moved.
+func recordedName(manifest, abs string) (string, bool) {
+	f, err := os.Open(manifest)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+	name, found := "", false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e ManifestEntry
+		if json.Unmarshal(sc.Bytes(), &e) != nil {
+			continue
+		}
+		recorded := e.File
+		if e.Event == "renamed" {
+			recorded = e.To
+		} else if e.Event != "completed" {
+			continue
+		}
+		if recorded == abs || filepath.Base(recorded) == filepath.Base(abs) {
+			name, found = recorded, true
+		}
+	}
+	return name, found
+}
+
+// tokenIndexes returns the indexes of the occurrences of the token in line, which are delimited
+// by the start or the end of line, or a byte of isTokenBoundary.
+func tokenIndexes(line, token []byte) []int {
+	var at []int
+	for i := 0; i+len(token) <= len(line); {
+		j := bytes.Index(line[i:], token)
+		if j < 0 {
+			break
+		}
+		j += i
+		end := j + len(token)
+		if isTokenBoundary(line, j-1) && isTokenBoundary(line, end) {
+			at = append(at, j)
+			i = end
+		} else {
+			i = j + 1
+		}
+	}
+	return at
+}
+
+// isTokenBoundary reports whether the byte at i delimits the keys and the values of the records,
+// the start and the end of line, at -1 and len(line), being boundaries too.
+func isTokenBoundary(line []byte, i int) bool {
+	if i < 0 || i >= len(line) {
+		return true
+	}
+	switch line[i] {
+	case ' ', '\t', '\r', '\n', '"', '\'', '`', '=', ':', ',', ';', '(', ')', '[', ']', '{', '}', '<', '>',
+		'/', '&', '?':
+		return true
+	case '.':
+		// the dots of the dotted names and of the numbers, such as alice.bob or 42.5, are not
+		return i == 0 || i == len(line)-1 || !isAlnum(line[i-1]) || !isAlnum(line[i+1])
+	}
+	return false
+}
+
+func isAlnum(c byte) bool {
+	return isLetter(c) || '0' <= c && c <= '9'
+}
+
+// replaceAt returns line with the n bytes at the indexes at replaced by repl.
+func replaceAt(line []byte, at []int, n int, repl []byte) []byte {
+	out := make([]byte, 0, len(line)+len(at)*(len(repl)-n))
+	prev := 0
+	for _, i := range at {
+		out = append(out, line[prev:i]...)
+		out = append(out, repl...)
+		prev = i + n
+	}
+	return append(out, line[prev:]...)
+}
@@ -0,0 +1,101 @@
+package rotation_test
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/wytools/rlog/rotation"
+)
+
+// printFiles prints the names and the contents of the log files of dir.
+func printFiles(dir string) {
+	names, _ := filepath.Glob(filepath.Join(dir, "*.log"))
+	for _, name := range names {
+		b, _ := os.ReadFile(name)
+		fmt.Printf("%s: %q\n", filepath.Base(name), b)
+	}
+}
+
+func ExampleNew() {
+	dir, _ := os.MkdirTemp("", "example")
+	defer os.RemoveAll(dir)
+
+	l, err := rotation.New(filepath.Join(dir, "app.log"), rotation.WithMaxSize(20), rotation.WithMaxFiles(3))
+	if err != nil {
+		panic(err)
+	}
+	// the time is removed, so the output is the same on every run
+	logger := slog.New(slog.NewTextHandler(l, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("started", "port", 8080)
+	logger.Info("request", "id", 1)
+	logger.Info("request", "id", 2)
+	l.Close()
+	printFiles(dir)
+	// Output:
+	// app0.log: "level=INFO msg=started port=8080\n"
+	// app1.log: "level=INFO msg=request id=1\n"
+	// app2.log: "level=INFO msg=request id=2\n"
+}
+
+// The loggers writing to an io.Writer, such as the log package or logrus, take the Logger
+// directly, and sync it before exiting.
+func ExampleLogger_Write() {
+	dir, _ := os.MkdirTemp("", "example")
+	defer os.RemoveAll(dir)
+
+	l, err := rotation.New(filepath.Join(dir, "app.log"), rotation.WithMaxSize(10), rotation.WithMaxFiles(2))
+	if err != nil {
+		panic(err)
+	}
+	logger := log.New(l, "app: ", 0)
+	logger.Print("first")
+	logger.Print("second")
+	l.Sync()
+	l.Close()
+	printFiles(dir)
+	// Output:
+	// app0.log: "app: first\n"
+	// app1.log: "app: second\n"
+}
+
+// The Logger is a WriteSyncer, as zapcore.NewCore takes it, and other writers are adapted by
+// NewWriteSyncer.
+func ExampleNewWriteSyncer() {
+	w := bufio.NewWriter(os.Stdout)
+	ws := rotation.NewWriteSyncer(w)
+	fmt.Fprintln(ws, "buffered until Sync")
+	ws.Sync()
+	// Output:
+	// buffered until Sync
+}
+
+// The JSON lines of zerolog are split by their level: all of them go to the rotating file, the
+// warnings and above to the console too.
+func ExampleNewLevelSplitWriter() {
+	dir, _ := os.MkdirTemp("", "example")
+	defer os.RemoveAll(dir)
+
+	file, err := rotation.New(filepath.Join(dir, "app.log"), rotation.WithMaxSize(1<<20), rotation.WithLock())
+	if err != nil {
+		panic(err)
+	}
+	w := rotation.NewLevelSplitWriter(file, os.Stdout, "warn")
+	fmt.Fprintln(w, `{"level":"info","message":"started"}`)
+	fmt.Fprintln(w, `{"level":"error","message":"failed"}`)
+	file.Close()
+	printFiles(dir)
+	// Output:
+	// {"level":"error","message":"failed"}
+	// app0.log: "{\"level\":\"info\",\"message\":\"started\"}\n{\"level\":\"error\",\"message\":\"failed\"}\n"
+}
@@ -0,0 +1,122 @@
+package rotation
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// FallbackPolicy decides what the Logger does with the logs when a new log file can't be opened,
+// or a write to the log file fails.
+type FallbackPolicy int
+
+const (
+	// FallbackStdout switches to os.Stdout until the next rotation when a file can't be opened,
+	// and returns write errors from Write. It is the default.
+	FallbackStdout FallbackPolicy = iota
+	// FallbackError returns an error from Write while the log file can't be opened.
+	FallbackError
+	// FallbackWriter writes the logs to the writer set by WithFallbackWriter while the log file
+	// can't be opened or written.
+	FallbackWriter
+	// FallbackBuffer keeps the logs in memory while the log file can't be opened or written, and
+	// writes them once it recovers. The logs over the buffer size are dropped.
+	FallbackBuffer
+)
+
+// ErrNoLogFile is returned by Write under the FallbackError policy while the log file can't be opened.
+var ErrNoLogFile = errors.New("rotation: log file is not available")
+
+const (
+	minRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff = time.Minute
+)
+
+// WithFallback sets the fallback policy. Except for FallbackStdout, opening the log file is retried
+// on the following writes, with an exponential backoff from 100ms up to 1 minute.
+func WithFallback(policy FallbackPolicy) Option {
+	return func(l *Logger) {
+		l.fallback = policy
+	}
+}
+
+// WithFallbackWriter sets the FallbackWriter policy, writing to w while the log file is not available.
+func WithFallbackWriter(w io.Writer) Option {
+	return func(l *Logger) {
+		l.fallback = FallbackWriter
+		l.fallbackWriter = w
+	}
+}
+
+// WithFallbackBuffer sets the FallbackBuffer policy, keeping at most size bytes in memory while the
+// log file is not available. The size is 1MB if it is not positive.
+func WithFallbackBuffer(size int) Option {
+	return func(l *Logger) {
+		l.fallback = FallbackBuffer
+		l.pendingMax = size
+	}
+}
+
+// setBroken closes the current file after a failed rotation, and schedules the retry
+func (l *Logger) setBroken() {
+	l.setFile(nil)
+	l.bBroken = true
+	l.metrics.broken.Store(true)
+	l.retryBackoff = minRetryBackoff
+	l.retryAt = l.clock.Now().Add(l.retryBackoff)
+}
+
+// tryReopen opens the log file again if the backoff has passed
+func (l *Logger) tryReopen() {
+	if l.clock.Now().Before(l.retryAt) {
+		return
+	}
+	f, err := l.openNext()
+	if err != nil {
+		f, err = l.failover(err)
+	}
+	if err != nil {
+		l.reportError(err)
+		l.retryBackoff = min(2*l.retryBackoff, maxRetryBackoff)
+		l.retryAt = l.clock.Now().Add(l.retryBackoff)
+		return
+	}
+	l.setFile(f)
+}
+
+// writeFallback writes p according to the fallback policy while the log file is not available
+func (l *Logger) writeFallback(p []byte) (int, error) {
+	switch l.fallback {
+	case FallbackWriter:
+		if l.fallbackWriter != nil {
+			return l.fallbackWriter.Write(p)
+		}
+	case FallbackBuffer:
+		if len(l.pending)+len(p) <= l.pendingMax {
+			l.pending = append(l.pending, p...)
+			l.metrics.pending.Store(int64(len(l.pending)))
+		} else {
+			l.metrics.dropped.Add(1)
+		}
+		return len(p), nil
+	case FallbackStdout:
+		return os.Stdout.Write(p)
+	}
+	l.metrics.dropped.Add(1)
+	return 0, ErrNoLogFile
+}
+
+// flushPending writes the bytes kept by the FallbackBuffer policy to the recovered log file
+func (l *Logger) flushPending() {
+	n, err := l.file.Write(l.pending)
+	l.rSize += int64(n)
+	l.countWrite(l.pending[:n])
+	l.pending = l.pending[n:]
+	l.metrics.pending.Store(int64(len(l.pending)))
+	if err != nil {
+		l.reportError(err)
+		return
+	}
+	l.pending = nil
+}
@@ -0,0 +1,102 @@
+package rotation
+
+import (
+	"os"
+	"time"
+)
+
+// WithFileLock makes the logger share its files with the loggers of other processes, such as
+// the workers of a prefork server, by an advisory lock of the lock file named like the log
+// files with the .lock extension, such as app.lock. Every Write and rotation holds the lock, so
+// the lines don't interleave, and a logger follows the rotations of the others instead of
+// rotating again. It costs the lock, the unlock and a stat of the file per Write, the rotations
+// of the others are looked for once a second, or when the file is full, so a logger may write
+// to the file rotated by another one for a second. A Write fails, writing nothing, with the
+// error of the lock if it can't be taken.
+//
+// The lock is flock on Unix and LockFileEx on Windows, it's only advisory: the processes must
+// all use it. Some network file systems don't support it.
+func WithFileLock() Option {
+	return func(l *Logger) {
+		l.bFileLock = true
+	}
+}
+
+// lockFile takes the lock of the lock file, opening it if needed.
+func (l *Logger) lockFile() error {
+	if l.lock == nil {
+		path, fn, _, err := getPathFileName(l.filename, l.dirMode)
+		if err != nil {
+			return err
+		}
+		if l.lock, err = os.OpenFile(path+fn+".lock", os.O_RDWR|os.O_CREATE, l.fileMode); err != nil {
+			return err
+		}
+	}
+	return lockFile(l.lock)
+}
+
+// unlockFile releases the lock of the lock file.
+func (l *Logger) unlockFile() {
+	if l.lock != nil {
+		unlockFile(l.lock)
+	}
+}
+
+// followInterval is the interval between the checks of the rotations of the other processes.
+const followInterval = time.Second
+
+// followOthers catches up with the writes and the rotations of the other processes sharing the
+// files, with the lock held.
+func (l *Logger) followOthers() {
+	if l.file == nil || l.file == os.Stdout {
+		return
+	}
+	if fInfo, err := l.file.Stat(); err == nil {
+		l.rSize = fInfo.Size()
+	}
+	// a full file may have been rotated by another process already, it's checked before rotating
+	now := l.clock.Now()
+	full := l.rType != DailyRotation && l.rType != IntervalRotation && l.rSize >= l.rMaxSize
+	if now.Before(l.followAt) && !full {
+		return
+	}
+	l.followAt = now.Add(followInterval)
+	if l.rType == SizedRotation && !l.bBackups {
+		l.followNewestIndex()
+	}
+	// the current file of the timestamped backups was renamed by another process
+	l.reopenIfMoved()
+	if fInfo, err := l.file.Stat(); err == nil {
+		l.rSize = fInfo.Size()
+	}
+}
+
+// followNewestIndex switches to the index file written last, which another process may have
+// rotated to.
+func (l *Logger) followNewestIndex() {
+	newest := l.fnRotateIndex
+	var newestTime time.Time
+	for i, name := range l.fnRotate {
+		fInfo, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		l.fnRotateUsed[i] = true
+		if fInfo.ModTime().After(newestTime) {
+			newest, newestTime = i, fInfo.ModTime()
+		}
+	}
+	if newest == l.fnRotateIndex {
+		return
+	}
+	f, err := l.openFile(l.fnRotate[newest])
+	if err != nil {
+		l.reportError(err)
+		return
+	}
+	l.file.Close()
+	l.file = f
+	l.setCurrentFile(f.Name())
+	l.fnRotateIndex = newest
+}
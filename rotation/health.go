@@ -0,0 +1,59 @@
+package rotation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// nearCapacity is the ratio of a capacity over which a logger is degraded.
+const nearCapacity = 0.9
+
+// CheckHealth returns an error describing why the logger is degraded, nil if it's healthy: the
+// log file can't be opened, the free space of WithDiskGuard is low, the buffer of FallbackBuffer
+// is nearly full, or the held files and the current one nearly exhaust the budget of
+// WithMaxTotalSize, which the retention can't free.
+// It implements rlog.HealthChecker, and reads no state guarded by the write lock only.
+func (l *Logger) CheckHealth() error {
+	filename := l.currentFilename()
+	var errs []error
+	if l.metrics.broken.Load() {
+		errs = append(errs, fmt.Errorf("rotation: %s: log file is not available", filename))
+	}
+	if l.metrics.lowDisk.Load() {
+		errs = append(errs, fmt.Errorf("rotation: %s: disk space is low", filename))
+	}
+	if l.fallback == FallbackBuffer && float64(l.metrics.pending.Load()) >= nearCapacity*float64(l.pendingMax) {
+		errs = append(errs, fmt.Errorf("rotation: %s: fallback buffer is nearly full", filename))
+	}
+	if l.rMaxTotalSize > 0 {
+		if files, err := l.listLogFiles(); err == nil {
+			used := l.metrics.fileSize.Load()
+			held := l.heldFiles(files)
+			for _, f := range files {
+				if held[f.path] {
+					used += f.Size()
+				}
+			}
+			if float64(used) >= nearCapacity*float64(l.rMaxTotalSize) {
+				errs = append(errs, fmt.Errorf("rotation: %s: held files nearly exhaust the size budget", filename))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckHealth returns an error while the NetworkWriter is disconnected, joined with the errors
+// of its spill logger. It implements rlog.HealthChecker.
+func (w *NetworkWriter) CheckHealth() error {
+	w.mu.Lock()
+	down := w.conn == nil && !w.closed
+	w.mu.Unlock()
+	var errs []error
+	if down {
+		errs = append(errs, fmt.Errorf("rotation: disconnected from %s", w.addr))
+	}
+	if w.spill != nil {
+		errs = append(errs, w.spill.CheckHealth())
+	}
+	return errors.Join(errs...)
+}
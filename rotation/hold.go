@@ -0,0 +1,230 @@
+package rotation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Hold keeps log files from being deleted by the retention, such as for a litigation hold or an
+// incident investigation. It holds either a file, or the files holding the logs of a period.
+type Hold struct {
+	ID   string    `json:"id"`
+	File string    `json:"file,omitempty"` // the held file
+	From time.Time `json:"from,omitempty"` // the start of the held period
+	To   time.Time `json:"to,omitempty"`   // the end of the held period
+}
+
+// HoldFile holds the log file filename until the hold id is released.
+func (l *Logger) HoldFile(id, filename string) error {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	return l.addHold(Hold{ID: id, File: abs})
+}
+
+// HoldRange holds the log files holding logs written between from and to, until the hold id is
+// released. A file holds the logs written between the modification of the previous file and
+// its own, so the files written after from are held until the first one modified after to.
+func (l *Logger) HoldRange(id string, from, to time.Time) error {
+	return l.addHold(Hold{ID: id, From: from, To: to})
+}
+
+// Release releases the hold id, its files are deleted by the next retention if they are due.
+func (l *Logger) Release(id string) error {
+	l.holdMu.Lock()
+	defer l.holdMu.Unlock()
+	holds := l.holds[:0:0]
+	for _, h := range l.holds {
+		if h.ID != id {
+			holds = append(holds, h)
+		}
+	}
+	l.holds = holds
+	return l.saveHolds()
+}
+
+// Holds returns the active holds.
+func (l *Logger) Holds() []Hold {
+	l.holdMu.Lock()
+	defer l.holdMu.Unlock()
+	return append([]Hold(nil), l.holds...)
+}
+
+func (l *Logger) addHold(h Hold) error {
+	l.holdMu.Lock()
+	defer l.holdMu.Unlock()
+	l.holds = append(l.holds, h)
+	return l.saveHolds()
+}
+
+// holdsFile returns the file keeping the holds across restarts, such as app.holds next to the
+// app.log files. It doesn't have the suffix of the log files, so it's never taken for one. It's
+// in the current directory of the logger, the holds move along with the failover and failback.
+func (l *Logger) holdsFile() (string, error) {
+	return holdsFileOf(l.currentFilename(), l.dirMode)
+}
+
+func holdsFileOf(filename string, dirMode os.FileMode) (string, error) {
+	path, fn, _, err := getPathFileName(filename, dirMode)
+	if err != nil {
+		return "", err
+	}
+	return path + fn + ".holds", nil
+}
+
+// loadHolds reads the holds saved by an earlier run, and the ones left in the secondary
+// directory by a run which didn't fail back, which are moved to the primary one.
+func (l *Logger) loadHolds() error {
+	fn, err := l.holdsFile()
+	if err != nil {
+		return err
+	}
+	if err = readHolds(fn, &l.holds); err != nil || l.secondaryDir == "" {
+		return err
+	}
+	left, err := holdsFileOf(filepath.Join(l.secondaryDir, filepath.Base(l.filename)), l.dirMode)
+	if err != nil {
+		return nil
+	}
+	var holds []Hold
+	if readHolds(left, &holds) != nil || len(holds) == 0 {
+		return nil
+	}
+	ids := map[string]bool{}
+	for _, h := range l.holds {
+		ids[h.ID] = true
+	}
+	for _, h := range holds {
+		if !ids[h.ID] {
+			l.holds = append(l.holds, h)
+		}
+	}
+	if l.saveHolds() == nil {
+		os.Remove(left)
+	}
+	return nil
+}
+
+// readHolds reads the holds of the file fn into holds, none if it doesn't exist.
+func readHolds(fn string, holds *[]Hold) error {
+	data, err := os.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, holds)
+}
+
+// moveHolds saves the holds in the directory the logger switched to, and removes them from the
+// directory of from, so they are kept in one place.
+func (l *Logger) moveHolds(from string) {
+	l.holdMu.Lock()
+	defer l.holdMu.Unlock()
+	if len(l.holds) == 0 {
+		return
+	}
+	if err := l.saveHolds(); err != nil {
+		l.reportError(err)
+		return
+	}
+	if fn, err := holdsFileOf(from, l.dirMode); err == nil {
+		os.Remove(fn)
+	}
+}
+
+// saveHolds writes the holds atomically, the file is removed when there is none.
+func (l *Logger) saveHolds() error {
+	fn, err := l.holdsFile()
+	if err != nil {
+		return err
+	}
+	if len(l.holds) == 0 {
+		if err = os.Remove(fn); os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(l.holds, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err = os.WriteFile(tmp, data, l.fileMode); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, fn); err != nil {
+		return err
+	}
+	return l.syncDirs(fn)
+}
+
+// heldFiles returns the paths of the held files among files, sorted from the oldest.
+func (l *Logger) heldFiles(files []logFileInfo) map[string]bool {
+	l.holdMu.Lock()
+	defer l.holdMu.Unlock()
+	if len(l.holds) == 0 {
+		return nil
+	}
+	held := map[string]bool{}
+	for i, f := range files {
+		var prev time.Time
+		if i > 0 {
+			prev = files[i-1].ModTime()
+		}
+		for _, h := range l.holds {
+			if h.File != "" {
+				if abs, err := filepath.Abs(f.path); err == nil && abs == h.File {
+					held[f.path] = true
+				}
+			} else if !f.ModTime().Before(h.From) && prev.Before(h.To) {
+				held[f.path] = true
+			}
+		}
+	}
+	return held
+}
+
+// isHeld reports whether the log file filename is held.
+func (l *Logger) isHeld(filename string) bool {
+	files, err := l.listLogFiles()
+	if err != nil {
+		return false
+	}
+	return l.heldFiles(files)[filepath.Clean(filename)]
+}
+
+// moveHeld renames the held file filename out of the way of a file name to be reused, and
+// updates the holds of the file. The moved name has "_held_" and the Unix time before the
+// extensions, such as out3_held_1714521600.log.gz, so it's still a log file of the logger,
+// kept by the retention until the hold is released.
+func (l *Logger) moveHeld(filename string) error {
+	name := l.trimCompressedExt(filename)
+	ext := filepath.Ext(name)
+	moved := name[:len(name)-len(ext)] + heldMark + strconv.FormatInt(l.clock.Now().Unix(), 10) + ext +
+		filename[len(name):]
+	return l.renameLogFile(filename, moved)
+}
+
+// heldMark marks the names of the files moved by moveHeld.
+const heldMark = "_held_"
+
+// heldNameRe matches the mark of the moved held files and the time following it.
+var heldNameRe = regexp.MustCompile(heldMark + "[0-9]+$")
+
+// trimHeldMark returns the name rel, whose compressed extension is trimmed, without the mark of
+// the moved held files.
+func trimHeldMark(rel string) string {
+	ext := filepath.Ext(rel)
+	stem := rel[:len(rel)-len(ext)]
+	if loc := heldNameRe.FindStringIndex(stem); loc != nil {
+		return stem[:loc[0]] + ext
+	}
+	return rel
+}
@@ -0,0 +1,65 @@
+package rotation
+
+import (
+	"bytes"
+	"expvar"
+	"sync/atomic"
+)
+
+// Metrics is a snapshot of the counters of a Logger, for alerting when logging degrades.
+type Metrics struct {
+	BytesWritten int64 `json:"bytes_written"` // the bytes written to the log files
+	LinesWritten int64 `json:"lines_written"` // the lines written to the log files
+	Rotations    int64 `json:"rotations"`     // the switches to a new log file
+	Errors       int64 `json:"errors"`        // the failed opens and writes of the log files
+	Dropped      int64 `json:"dropped"`       // the writes, or lines, dropped while the log file was not available or the disk space low
+	FileSize     int64 `json:"file_size"`     // the size of the current log file
+	Pending      int64 `json:"pending"`       // the bytes kept by FallbackBuffer
+	Broken       bool  `json:"broken"`        // the log file can't be opened, the logger is retrying
+	LowDisk      bool  `json:"low_disk"`      // the free space is under the threshold of WithDiskGuard
+}
+
+// metrics holds the counters of a Logger, they are read without the lock of the Logger.
+type metrics struct {
+	bytesWritten atomic.Int64
+	linesWritten atomic.Int64
+	rotations    atomic.Int64
+	errors       atomic.Int64
+	dropped      atomic.Int64
+	fileSize     atomic.Int64
+	pending      atomic.Int64
+	broken       atomic.Bool
+	lowDisk      atomic.Bool
+	filename     atomic.Pointer[string] // the name of the current file
+	lastRotation atomic.Int64           // the unix nanoseconds of the last rotation
+	lastError    atomic.Pointer[string] // the message of the last error
+}
+
+// Metrics returns a snapshot of the counters of the logger.
+func (l *Logger) Metrics() Metrics {
+	return Metrics{
+		BytesWritten: l.metrics.bytesWritten.Load(),
+		LinesWritten: l.metrics.linesWritten.Load(),
+		Rotations:    l.metrics.rotations.Load(),
+		Errors:       l.metrics.errors.Load(),
+		Dropped:      l.metrics.dropped.Load(),
+		FileSize:     l.metrics.fileSize.Load(),
+		Pending:      l.metrics.pending.Load(),
+		Broken:       l.metrics.broken.Load(),
+		LowDisk:      l.metrics.lowDisk.Load(),
+	}
+}
+
+// PublishExpvar publishes the metrics of the logger as the expvar name, served as JSON on
+// /debug/vars. A Prometheus collector can read them from Metrics likewise. Like
+// expvar.Publish, it panics if the name is already used.
+func (l *Logger) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return l.Metrics() }))
+}
+
+// countWrite counts the bytes of p written to the log file.
+func (l *Logger) countWrite(p []byte) {
+	l.metrics.bytesWritten.Add(int64(len(p)))
+	l.metrics.linesWritten.Add(int64(bytes.Count(p, []byte{'\n'})))
+	l.metrics.fileSize.Store(l.rSize)
+}
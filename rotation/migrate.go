@@ -0,0 +1,205 @@
+package rotation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithMigration renames the files left by an earlier naming scheme to the current one when the
+// logger is created, so the retention and the readers see a consistent history:
+//
+//   - With WithTimestampedBackups, the index files such as out0.log and out1.log become
+//     timestamped backups named by their modification time, the newest one becoming the
+//     current file if there is none.
+//   - With WithNameTemplate, WithHostname or WithPID, or WithDatedDirs, the files of the default
+//     naming in the log directory, such as app_2024_05_01_00_00.log, app_2024_05_01_00_00_3.log
+//     or app3.log for the rotation type of the logger, are renamed to the names given by the
+//     template, in the dated subdirectories, by the time and the index of their names.
+//
+// New returns an error for the other loggers, which have no earlier scheme to migrate from.
+// The holds of the renamed files follow them, and the renames are recorded in the manifest, if
+// any, so VerifyManifest finds the files.
+func WithMigration() Option {
+	return func(l *Logger) {
+		l.bMigrate = true
+	}
+}
+
+// migrateNames renames the files of the default naming to the names of the template and the
+// dated subdirectories.
+func (l *Logger) migrateNames() error {
+	if l.nameTmpl == nil && (l.dirLayout == "" || l.rType == SizedRotation) {
+		return errors.New("rotation: WithMigration needs WithTimestampedBackups, a name template or dated directories")
+	}
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	// the files were named by the time format of the default naming
+	format := l.timeFormat
+	if format == "" {
+		format = defaultTimeFormat(l.rType, l.rInterval)
+	}
+	loc := time.Local
+	if l.bUTC {
+		loc = time.UTC
+	}
+	for _, e := range entries {
+		name := e.Name()
+		ext := ""
+		if l.compressor != nil && strings.HasSuffix(name, l.compressor.Ext()) {
+			ext = l.compressor.Ext()
+		}
+		stamp, ok := strings.CutPrefix(strings.TrimSuffix(name, ext), fn)
+		if stamp, ok = strings.CutSuffix(stamp, suffix); !e.Type().IsRegular() || !ok || stamp == "" {
+			continue
+		}
+		var t time.Time
+		index := 0
+		switch l.rType {
+		case SizedRotation:
+			index, err = strconv.Atoi(stamp)
+		case HybridRotation:
+			j := strings.LastIndexByte(stamp, '_')
+			if j < 0 {
+				continue
+			}
+			if index, err = strconv.Atoi(stamp[j+1:]); err == nil {
+				t, err = time.ParseInLocation(format, stamp[:j], loc)
+			}
+		default:
+			t, err = time.ParseInLocation(format, stamp, loc)
+		}
+		if err != nil || index < 0 {
+			continue
+		}
+		to, err := l.migratedName(path, fn, suffix, t, index)
+		if err != nil {
+			return err
+		}
+		from := filepath.Join(path, name)
+		if to += ext; to == from || fileExists(to) {
+			continue
+		}
+		if err = l.renameLogFile(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migratedName returns the current name of the file of the time t and the index, creating its
+// directory.
+func (l *Logger) migratedName(path, fn, suffix string, t time.Time, index int) (string, error) {
+	if l.rType == SizedRotation {
+		return l.logFileName(path, fn+strconv.Itoa(index)+suffix, t, index)
+	}
+	if l.dirLayout != "" {
+		path += filepath.FromSlash(t.Format(l.dirLayout)) + string(filepath.Separator)
+		if err := os.MkdirAll(path, l.dirMode); err != nil {
+			return "", err
+		}
+	}
+	flat := fn + t.Format(l.timeFormat)
+	if l.rType == HybridRotation {
+		flat += "_" + strconv.Itoa(index)
+	}
+	return l.logFileName(path, flat+suffix, t, index)
+}
+
+// migrateToBackups renames the index files of a SizedRotation logger to timestamped backups.
+func (l *Logger) migrateToBackups() error {
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	var files []logFileInfo
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || !strings.HasPrefix(name, fn) || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		index := strings.TrimSuffix(strings.TrimPrefix(name, fn), suffix)
+		if _, err := strconv.Atoi(index); err != nil || index[0] == '-' {
+			continue
+		}
+		fInfo, err := e.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, logFileInfo{path: filepath.Join(path, name), FileInfo: fInfo})
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	active := path + fn + suffix
+	if _, err = os.Stat(active); os.IsNotExist(err) {
+		newest := files[len(files)-1]
+		if err = l.renameLogFile(newest.path, active); err != nil {
+			return err
+		}
+		files = files[:len(files)-1]
+	}
+	for _, f := range files {
+		t := f.ModTime()
+		if l.bUTC {
+			t = t.UTC()
+		}
+		stamp := fn + "-" + t.Format(backupTimeFormat)
+		backup := path + stamp + suffix
+		if next := nextBackupIndex(path, stamp, suffix); next > 0 {
+			backup = path + stamp + "_" + strconv.Itoa(next) + suffix
+		}
+		if err = l.renameLogFile(f.path, backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameLogFile renames the log file from to to, and updates the holds of the file and the
+// manifest.
+func (l *Logger) renameLogFile(from, to string) error {
+	if err := os.Rename(from, to); err != nil {
+		return err
+	}
+	if err := l.syncDirs(from, to); err != nil {
+		return err
+	}
+	fromAbs, _ := filepath.Abs(from)
+	toAbs, _ := filepath.Abs(to)
+	if manifest, err := l.manifestName(); err == nil && fileExists(manifest) {
+		e := ManifestEntry{Time: l.clock.Now(), Event: "renamed", From: fromAbs, To: toAbs}
+		if err = appendManifest(manifest, e, l.fileMode); err != nil {
+			l.reportError(err)
+		}
+	}
+	l.holdMu.Lock()
+	defer l.holdMu.Unlock()
+	changed := false
+	for i := range l.holds {
+		if l.holds[i].File == fromAbs {
+			l.holds[i].File = toAbs
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return l.saveHolds()
+}
@@ -0,0 +1,222 @@
+package rotation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NameFields are the fields of the file name template set by WithNameTemplate.
+type NameFields struct {
+	Name     string    // the set file name without its directory and extension, such as app
+	Ext      string    // the extension of the set file name, such as .log
+	Time     time.Time // the start of the rotation period, zero for SizedRotation
+	Index    int       // the index of a SizedRotation file, or of a HybridRotation file within the day
+	Hostname string    // the host name
+	PID      int       // the process ID
+	Level    string    // the level set by WithNameLevel
+}
+
+// WithNameTemplate names the log files by the text/template text executed with NameFields,
+// instead of the name, the time and the index appended to each other. The result is relative to
+// the directory of the set file name, it may contain subdirectories:
+//
+//	rotation.New("/var/log/app/app.log",
+//		rotation.WithNameTemplate(`{{.Hostname}}/{{.Name}}.{{.Time.Format "2006-01-02"}}.{{.PID}}{{.Ext}}`))
+//
+// The files of the logger are recognized by the name the template gives with any numbers in
+// place of the time and the index, so the times must be formatted by numbers only. The template
+// doesn't apply to the backups of WithTimestampedBackups. New returns the errors of the template.
+func WithNameTemplate(text string) Option {
+	return func(l *Logger) {
+		l.nameText = text
+	}
+}
+
+// WithNameLevel sets the Level field of the name template, for a logger getting the records of
+// some levels only, such as "error".
+func WithNameLevel(level string) Option {
+	return func(l *Logger) {
+		l.nameLevel = level
+	}
+}
+
+// pidMarker is the PID of the sample name of the template, whose digits no time nor index of
+// the sample gives.
+const pidMarker = 918273645
+
+// parseNameTemplate parses the template of WithNameTemplate, and the pattern of the file names
+// it gives.
+func (l *Logger) parseNameTemplate() error {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(l.nameText)
+	if err != nil {
+		return fmt.Errorf("rotation: name template: %w", err)
+	}
+	l.nameTmpl = tmpl
+	// the constant fields are replaced by markers, so only the numbers of the time and the index vary
+	fields := l.nameFields(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC), 0)
+	constants := []string{fields.Name, fields.Ext, fields.Hostname, fields.Level, strconv.Itoa(fields.PID)}
+	fields.Name, fields.Ext, fields.Hostname, fields.Level = "\x00n\x00", "\x00e\x00", "\x00h\x00", "\x00l\x00"
+	fields.PID = pidMarker
+	sample, err := l.execNameTemplate(fields)
+	if err != nil {
+		return err
+	}
+	sample = strings.ReplaceAll(sample, strconv.Itoa(pidMarker), "\x00p\x00")
+	pattern := regexp.MustCompile("[0-9]+").ReplaceAllLiteralString(regexp.QuoteMeta(sample), "[0-9]+")
+	pattern = strings.NewReplacer(
+		"\x00n\x00", regexp.QuoteMeta(constants[0]),
+		"\x00e\x00", regexp.QuoteMeta(constants[1]),
+		"\x00h\x00", regexp.QuoteMeta(constants[2]),
+		"\x00l\x00", regexp.QuoteMeta(constants[3]),
+		"\x00p\x00", regexp.QuoteMeta(constants[4]),
+	).Replace(pattern)
+	l.nameRe = regexp.MustCompile("^" + pattern + "$")
+	return nil
+}
+
+// nameFields returns the fields of the name template of a file.
+func (l *Logger) nameFields(t time.Time, index int) NameFields {
+	_, fn, suffix, _ := getPathFileName(l.filename, l.dirMode)
+	return NameFields{
+		Name:     fn,
+		Ext:      suffix,
+		Time:     t,
+		Index:    index,
+		Hostname: l.hostname,
+		PID:      os.Getpid(),
+		Level:    l.nameLevel,
+	}
+}
+
+// execNameTemplate returns the file name given by the template, relative to the log directory
+// and with slashes.
+func (l *Logger) execNameTemplate(fields NameFields) (string, error) {
+	var b strings.Builder
+	if err := l.nameTmpl.Execute(&b, fields); err != nil {
+		return "", fmt.Errorf("rotation: name template: %w", err)
+	}
+	name := strings.TrimLeft(filepath.ToSlash(b.String()), "/")
+	if name == "" {
+		return "", fmt.Errorf("rotation: name template: empty file name")
+	}
+	return name, nil
+}
+
+// logFileName returns the name of a log file in the directory path, flat without a template,
+// creating the subdirectories given by the template.
+func (l *Logger) logFileName(path, flat string, t time.Time, index int) (string, error) {
+	if l.nameTmpl == nil {
+		return path + flat, nil
+	}
+	name, err := l.execNameTemplate(l.nameFields(t, index))
+	if err != nil {
+		return "", err
+	}
+	name = path + filepath.FromSlash(name)
+	return name, os.MkdirAll(filepath.Dir(name), l.dirMode)
+}
+
+// isLogFileName reports whether the file rel, relative to the log directory, is a log file of
+// the logger, compressed or not, or moved by moveHeld.
+func (l *Logger) isLogFileName(rel string) bool {
+	rel = trimHeldMark(l.trimCompressedExt(rel))
+	if l.nameRe != nil {
+		rel = filepath.ToSlash(rel)
+		if l.dirLayout != "" && l.rType != SizedRotation {
+			// the template names the files within the dated subdirectories
+			for i := strings.Count(l.dirLayout, "/"); i >= 0; i-- {
+				if _, rel, _ = strings.Cut(rel, "/"); rel == "" {
+					return false
+				}
+			}
+		}
+		return l.nameRe.MatchString(rel)
+	}
+	return l.flatRe.MatchString(filepath.Base(rel))
+}
+
+// flatNameRe returns the pattern of the file names without a template, which are the name
+// followed by the time and the index of the rotation type, and the extension, so the files of
+// app.log don't include the files of application.log.
+func (l *Logger) flatNameRe() *regexp.Regexp {
+	_, fn, suffix, _ := getPathFileName(l.filename, l.dirMode)
+	var pattern string
+	switch {
+	case l.rType == SizedRotation && l.bBackups:
+		pattern = "(-[0-9]{8}T[0-9]{6}(_[0-9]+)?)?"
+	case l.rType == SizedRotation:
+		pattern = "[0-9]+"
+	case l.rType == HybridRotation:
+		pattern = timePattern(l.timeFormat) + "_[0-9]+"
+	default:
+		pattern = timePattern(l.timeFormat)
+	}
+	return regexp.MustCompile("^" + regexp.QuoteMeta(fn) + pattern + regexp.QuoteMeta(suffix) + "$")
+}
+
+// timePattern returns the pattern of the times formatted by layout, whose numbers and names vary.
+func timePattern(layout string) string {
+	sample := regexp.QuoteMeta(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(layout))
+	sample = regexp.MustCompile("[0-9]+").ReplaceAllLiteralString(sample, "[0-9]+")
+	return regexp.MustCompile("[A-Za-z]+").ReplaceAllLiteralString(sample, "[A-Za-z]+")
+}
+
+// WithHostname adds the host name to the file names, such as app_web-1_2024_05_01_00_00.log,
+// so the loggers of several hosts or pods writing to a shared volume don't write to the same
+// files. Each logger manages the files of its host only. It's ignored with WithNameTemplate,
+// whose template has the Hostname field.
+func WithHostname() Option {
+	return func(l *Logger) {
+		l.bHostname = true
+	}
+}
+
+// WithPID adds the process ID to the file names, such as app_1234_2024_05_01_00_00.log, so
+// several processes of a host don't write to the same files. Each logger manages the files of
+// its process only, the files of the earlier processes are left as they are. It's ignored with
+// WithNameTemplate, whose template has the PID field.
+func WithPID() Option {
+	return func(l *Logger) {
+		l.bPID = true
+	}
+}
+
+// hostPIDNameText returns the template of the default file names with the host name and the
+// process ID, as set by WithHostname and WithPID.
+func (l *Logger) hostPIDNameText() string {
+	text := "{{.Name}}"
+	if l.bHostname {
+		text += "_{{.Hostname}}"
+	}
+	if l.bPID {
+		text += "_{{.PID}}"
+	}
+	switch l.rType {
+	case SizedRotation:
+		return text + "_{{.Index}}{{.Ext}}"
+	case HybridRotation:
+		return text + fmt.Sprintf("{{.Time.Format %q}}_{{.Index}}{{.Ext}}", l.timeFormat)
+	default:
+		return text + fmt.Sprintf("{{.Time.Format %q}}{{.Ext}}", l.timeFormat)
+	}
+}
+
+// hostPIDFileName returns filename with the host name and the process ID before its extension,
+// for the current file of WithTimestampedBackups, which the template doesn't name.
+func (l *Logger) hostPIDFileName(filename string) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filename, ext)
+	if l.bHostname {
+		base += "_" + l.hostname
+	}
+	if l.bPID {
+		base += "_" + strconv.Itoa(os.Getpid())
+	}
+	return base + ext
+}
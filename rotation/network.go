@@ -0,0 +1,313 @@
+package rotation
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotConnected is returned by NetworkWriter.Write while the connection is down and there is
+// no spill logger.
+var ErrNotConnected = errors.New("rotation: not connected")
+
+// NetworkOption configures a NetworkWriter created by NewNetworkWriter.
+type NetworkOption func(*NetworkWriter)
+
+// WithSpill writes the logs to l while the connection is down. They are sent from its files on
+// reconnect, and the files are deleted once sent, except the held ones, which are kept without
+// being sent again. A file cut by a failure is sent from where it was cut on the next
+// reconnect, the offsets being kept in a file with the .sent extension, such as app.sent. The
+// compressed files can't be sent as lines, they are left in place: l shouldn't compress its
+// files. l is not closed by the NetworkWriter.
+func WithSpill(l *Logger) NetworkOption {
+	return func(w *NetworkWriter) {
+		w.spill = l
+	}
+}
+
+// WithReconnectBackoff sets the delays between the attempts to reconnect, doubling from min up
+// to max. They are 100ms and 1 minute by default.
+func WithReconnectBackoff(min, max time.Duration) NetworkOption {
+	return func(w *NetworkWriter) {
+		w.minBackoff = min
+		w.maxBackoff = max
+	}
+}
+
+// WithNetworkTimeout sets the timeout of dialing and of every write, 5 seconds by default.
+func WithNetworkTimeout(d time.Duration) NetworkOption {
+	return func(w *NetworkWriter) {
+		w.timeout = d
+	}
+}
+
+// NetworkWriter streams the logs to a remote collector, such as a TCP log shipper. When the
+// connection breaks, it reconnects in the background with an exponential backoff, and the logs
+// are written to the spill logger meanwhile. On reconnect, the spilled logs are replayed before
+// the new ones, the writes waiting for the replay so the order is kept. A write cut by a failure
+// is spilled again whole, but like with any TCP stream, the writes accepted by the system before
+// the broken connection is noticed are lost.
+type NetworkWriter struct {
+	network, addr          string
+	spill                  *Logger
+	minBackoff, maxBackoff time.Duration
+	timeout                time.Duration
+
+	mu           sync.Mutex
+	conn         net.Conn // nil while the connection is down
+	spilled      bool     // the spill logger may hold logs not sent yet
+	reconnecting bool
+	closed       bool
+	done         chan struct{} // closed by Close to stop reconnecting
+}
+
+// ensure implement io.Write and io.Closer
+var _ io.WriteCloser = (*NetworkWriter)(nil)
+
+// NewNetworkWriter creates a NetworkWriter sending to addr on network, such as "tcp" and
+// "collector:5170". If the first dial fails, it keeps reconnecting in the background. The logs
+// left in the spill logger by an earlier run are replayed on connect.
+func NewNetworkWriter(network, addr string, opts ...NetworkOption) *NetworkWriter {
+	w := &NetworkWriter{
+		network:    network,
+		addr:       addr,
+		minBackoff: minRetryBackoff,
+		maxBackoff: maxRetryBackoff,
+		timeout:    5 * time.Second,
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.spilled = w.spill != nil
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	conn, err := net.DialTimeout(network, addr, w.timeout)
+	if err == nil {
+		err = w.connect(conn)
+	}
+	if err != nil {
+		w.reconnecting = true
+		go w.reconnect()
+	}
+	return w
+}
+
+// Write implements io.Writer.
+func (w *NetworkWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.conn != nil {
+		if _, err := w.deadline(w.conn).Write(p); err == nil {
+			return len(p), nil
+		}
+		w.conn.Close()
+		w.conn = nil
+		if !w.reconnecting {
+			w.reconnecting = true
+			go w.reconnect()
+		}
+	}
+	if w.spill == nil {
+		return 0, ErrNotConnected
+	}
+	w.spilled = true
+	return w.spill.Write(p)
+}
+
+// Close closes the connection and stops reconnecting.
+func (w *NetworkWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// reconnect dials until it succeeds or the writer is closed.
+func (w *NetworkWriter) reconnect() {
+	backoff := w.minBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-w.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		conn, err := net.DialTimeout(w.network, w.addr, w.timeout)
+		if err == nil {
+			w.mu.Lock()
+			err = w.connect(conn)
+			w.mu.Unlock()
+			if err == nil {
+				return
+			}
+		}
+		backoff = min(2*backoff, w.maxBackoff)
+	}
+}
+
+// connect replays the spilled logs on conn and switches to it, it's called with w.mu held.
+func (w *NetworkWriter) connect(conn net.Conn) error {
+	if w.closed {
+		return conn.Close()
+	}
+	if w.spilled {
+		err := w.spill.drain(func(r io.Reader) (int64, error) {
+			return io.Copy(w.deadline(conn), r)
+		})
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		w.spilled = false
+	}
+	w.conn = conn
+	w.reconnecting = false
+	return nil
+}
+
+// deadline returns conn setting the write timeout before every write.
+func (w *NetworkWriter) deadline(conn net.Conn) io.Writer {
+	return &deadlineConn{conn: conn, timeout: w.timeout}
+}
+
+type deadlineConn struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	if c.timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	return c.conn.Write(p)
+}
+
+// drain closes the current file, and passes the log files to send from the oldest, from the
+// offset they were sent up to, deleting every file once it's sent unless it's held. It skips
+// the compressed files and the ones being completed. A new file is opened by the next Write.
+func (l *Logger) drain(send func(r io.Reader) (int64, error)) error {
+	l.lockState()
+	defer l.unlockState()
+	l.setFile(nil)
+	l.bBroken = true
+	l.retryBackoff = minRetryBackoff
+	l.retryAt = time.Time{}
+	files, err := l.listLogFiles()
+	if err != nil {
+		return err
+	}
+	sentName, err := l.sentFile()
+	if err != nil {
+		return err
+	}
+	sent := readSent(sentName)
+	offsets := map[string]int64{} // the offsets of the files left, to save
+	for _, fi := range files {
+		if abs, err := filepath.Abs(fi.path); err == nil && sent[abs] > 0 {
+			offsets[abs] = sent[abs]
+		}
+	}
+	held := l.heldFiles(files)
+	for _, fi := range files {
+		if l.trimCompressedExt(fi.path) != fi.path || l.isWorking(fi.path) {
+			continue
+		}
+		abs, err := filepath.Abs(fi.path)
+		if err != nil {
+			return err
+		}
+		off := offsets[abs]
+		if off > fi.Size() {
+			// a smaller file was written under the same name since
+			off = 0
+		}
+		if off < fi.Size() {
+			n, err := sendFrom(fi.path, off, send)
+			off += n
+			if err != nil {
+				offsets[abs] = off
+				return errors.Join(err, saveSent(sentName, offsets, l.fileMode))
+			}
+		}
+		if held[fi.path] {
+			offsets[abs] = off
+			continue
+		}
+		if err = os.Remove(fi.path); err != nil {
+			offsets[abs] = off
+			return errors.Join(err, saveSent(sentName, offsets, l.fileMode))
+		}
+		delete(offsets, abs)
+	}
+	return saveSent(sentName, offsets, l.fileMode)
+}
+
+// sendFrom passes the file filename from the offset off to send, returning the bytes sent.
+func sendFrom(filename string, off int64, send func(r io.Reader) (int64, error)) (int64, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err = f.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return send(f)
+}
+
+// sentFile returns the name of the file of the offsets of the log files sent partly, or whole
+// if they are held.
+func (l *Logger) sentFile() (string, error) {
+	path, fn, _, err := getPathFileName(l.currentFilename(), l.dirMode)
+	if err != nil {
+		return "", err
+	}
+	return path + fn + ".sent", nil
+}
+
+// readSent reads the offsets saved by saveSent, by the absolute names of the files.
+func readSent(fn string) map[string]int64 {
+	sent := map[string]int64{}
+	if data, err := os.ReadFile(fn); err == nil {
+		json.Unmarshal(data, &sent)
+	}
+	return sent
+}
+
+// saveSent writes the offsets atomically, the file is removed when there is none.
+func saveSent(fn string, offsets map[string]int64, mode os.FileMode) error {
+	if len(offsets) == 0 {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return err
+	}
+	tmp := fn + ".tmp"
+	if err = os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fn)
+}
@@ -0,0 +1,108 @@
+package rotation
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// cutSender sends up to limit bytes in total, then fails.
+type cutSender struct {
+	buf   bytes.Buffer
+	limit int64
+}
+
+func (s *cutSender) send(r io.Reader) (int64, error) {
+	n, err := io.Copy(&s.buf, io.LimitReader(r, s.limit-int64(s.buf.Len())))
+	if err == nil && int64(s.buf.Len()) >= s.limit {
+		if _, err = r.Read(make([]byte, 1)); err == io.EOF {
+			return n, nil
+		}
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestDrainResumes(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10), WithMaxFiles(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, s := range []string{"line 0...\n", "line 1...\n", "line 2...\n"} {
+		writeString(t, l, s)
+	}
+	// the connection breaks in the middle of the second file
+	s := &cutSender{limit: 15}
+	if err := l.drain(s.send); err == nil {
+		t.Fatal("drain succeeded, want the error of the connection")
+	}
+	if got := s.buf.String(); got != "line 0...\nline " {
+		t.Errorf("sent %q before the failure", got)
+	}
+	s.limit = 1 << 20
+	if err := l.drain(s.send); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.buf.String(), "line 0...\nline 1...\nline 2...\n"; got != want {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+	checkFiles(t, dir, map[string]string{})
+	if _, err := os.Stat(filepath.Join(dir, "app.sent")); !os.IsNotExist(err) {
+		t.Errorf("the offsets are left: %v", err)
+	}
+}
+
+func TestDrainKeepsHeld(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10), WithMaxFiles(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	writeString(t, l, "line 0...\n")
+	writeString(t, l, "line 1...\n")
+	if err := l.HoldFile("case", filepath.Join(dir, "app0.log")); err != nil {
+		t.Fatal(err)
+	}
+	s := &cutSender{limit: 1 << 20}
+	if err := l.drain(s.send); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{"app0.log": "line 0...\n"})
+	// the held file isn't sent again
+	writeString(t, l, "line 2...\n")
+	if err := l.drain(s.send); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.buf.String(), "line 0...\nline 1...\nline 2...\n"; got != want {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+	checkFiles(t, dir, map[string]string{"app0.log": "line 0...\n"})
+}
+
+func TestDrainSkipsCompressed(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10), WithMaxFiles(5), WithCompression(GzipCompressor{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	writeString(t, l, "line 0...\n")
+	writeString(t, l, "line 1...\n")
+	l.work.Wait()
+	s := &cutSender{limit: 1 << 20}
+	if err := l.drain(s.send); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.buf.String(), "line 1...\n"; got != want {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app0.log.gz")); err != nil {
+		t.Errorf("the compressed file isn't kept: %v", err)
+	}
+}
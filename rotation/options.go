@@ -0,0 +1,402 @@
+package rotation
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Option configures a Logger created by New.
+type Option func(*Logger)
+
+// WithDailyAt rotates the file everyday at the set hour and minute. Combined with WithMaxSize,
+// the logger becomes a HybridRotation one.
+func WithDailyAt(rHour, rMinute int) Option {
+	return func(l *Logger) {
+		l.rHour = rHour
+		l.rMinute = rMinute
+		if l.rType == SizedRotation || l.rType == HybridRotation {
+			l.rType = HybridRotation
+		} else {
+			l.rType = DailyRotation
+		}
+	}
+}
+
+// WithMaxSize rotates the file when its size exceeds rMaxSize bytes. Combined with WithDailyAt,
+// the logger becomes a HybridRotation one.
+func WithMaxSize(rMaxSize int64) Option {
+	return func(l *Logger) {
+		l.rMaxSize = rMaxSize
+		if l.rType == DailyRotation || l.rType == HybridRotation {
+			l.rType = HybridRotation
+		} else {
+			l.rType = SizedRotation
+		}
+	}
+}
+
+// WithMaxFiles sets the max number of the file rotations of a SizedRotation logger.
+func WithMaxFiles(rMaxNum int) Option {
+	return func(l *Logger) {
+		l.rMaxNum = rMaxNum
+		if l.rType == 0 {
+			l.rType = SizedRotation
+		}
+	}
+}
+
+// WithInterval rotates the file every interval d, see NewIntervalLogger.
+func WithInterval(d time.Duration) Option {
+	return func(l *Logger) {
+		l.rInterval = d
+		l.rType = IntervalRotation
+	}
+}
+
+// WithDirSync fsyncs the parent directory after a log file is created or renamed, such as to a
+// timestamped backup, a compressed file or a migrated name, so the rotations survive a power
+// loss. It costs one extra fsync per created or renamed file, only when rotating, never on a
+// normal Write, see BenchmarkRotateDirSync. It does nothing on Windows, whose directories
+// can't be fsynced.
+func WithDirSync() Option {
+	return func(l *Logger) {
+		l.bSyncDir = true
+	}
+}
+
+// WithSymlink maintains a symlink at linkname pointing to the current log file, which is updated
+// atomically on every rotation, so `tail -F` keeps following the logs. If linkname is empty, the
+// symlink is placed at the configured filename, e.g. app.log -> app_2024_05_01_00_00.log, except
+// with WithTimestampedBackups whose current file has that name. A file which isn't a symlink is
+// never replaced by the symlink.
+func WithSymlink(linkname string) Option {
+	return func(l *Logger) {
+		l.symlink = linkname
+		l.bSymlink = true
+	}
+}
+
+// WithFileMode sets the mode of created log files, such as 0640. Unlike the default 0666,
+// an explicit mode is not affected by the umask of the process.
+func WithFileMode(mode os.FileMode) Option {
+	return func(l *Logger) {
+		l.fileMode = mode
+		l.bFileMode = true
+	}
+}
+
+// WithDirMode sets the mode of created log directories, os.ModePerm by default.
+func WithDirMode(mode os.FileMode) Option {
+	return func(l *Logger) {
+		l.dirMode = mode
+	}
+}
+
+// WithBaseDir resolves a relative file name against the directory dir, instead of the working
+// directory of the process when the logger is created.
+func WithBaseDir(dir string) Option {
+	return func(l *Logger) {
+		l.baseDir = dir
+		l.bExeBase = false
+	}
+}
+
+// WithExecutableBaseDir resolves a relative file name against the directory of the executable,
+// instead of the working directory of the process, as the earlier versions did.
+func WithExecutableBaseDir() Option {
+	return func(l *Logger) {
+		l.baseDir = ""
+		l.bExeBase = true
+	}
+}
+
+// resolve returns the file or directory name resolved against the base directory, it's already
+// absolute if it's rooted or absolute.
+func (l *Logger) resolve(name string) (string, error) {
+	if name == "" || filepath.IsAbs(name) || os.IsPathSeparator(name[0]) {
+		return name, nil
+	}
+	base := l.baseDir
+	if l.bExeBase {
+		exe, err := os.Executable()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Dir(exe)
+	}
+	if base == "" {
+		return filepath.Abs(name)
+	}
+	return filepath.Abs(filepath.Join(base, name))
+}
+
+// WithOwner changes the owner and group of created log files, it only works on Unix.
+// A uid or gid of -1 keeps the current value.
+func WithOwner(uid, gid int) Option {
+	return func(l *Logger) {
+		l.uid = uid
+		l.gid = gid
+		l.bOwner = true
+	}
+}
+
+// WithMaxTotalSize limits the total size of all the log files to n bytes. After every rotation, the
+// oldest files are deleted until the total size is under the budget. The current file is never deleted.
+func WithMaxTotalSize(n int64) Option {
+	return func(l *Logger) {
+		l.rMaxTotalSize = n
+	}
+}
+
+// WithMaxAge deletes the log files older than d, by their modification time. It is checked after
+// every rotation. The current file is never deleted.
+func WithMaxAge(d time.Duration) Option {
+	return func(l *Logger) {
+		l.rMaxAge = d
+	}
+}
+
+// WithOnRotate sets a hook called after every rotation with the names of the completed file and
+// the new one, e.g. to upload the completed file. It runs while the logger is locked, so slow work
+// such as uploading should be done in another goroutine.
+func WithOnRotate(fn func(old, new string)) Option {
+	return func(l *Logger) {
+		l.onRotate = fn
+	}
+}
+
+// WithOnError sets a hook called when opening a new file or writing fails, including when the
+// logger falls back to stdout because the new file can't be opened.
+func WithOnError(fn func(err error)) Option {
+	return func(l *Logger) {
+		l.onError = fn
+	}
+}
+
+// WithOnClose sets a hook called after the logger is closed.
+func WithOnClose(fn func()) Option {
+	return func(l *Logger) {
+		l.onClose = fn
+	}
+}
+
+// WithUTC makes the rotation schedule and the time in file names use UTC instead of the local
+// time, so the files are the same whatever the timezone of the host or container is.
+func WithUTC() Option {
+	return func(l *Logger) {
+		l.bUTC = true
+	}
+}
+
+// WithReopenCheck makes the logger check every n writes whether the current file was removed or
+// renamed by someone else, such as the system logrotate, and reopen it by its name if so. Without it,
+// the logger keeps writing to the deleted file.
+func WithReopenCheck(n int) Option {
+	return func(l *Logger) {
+		l.reopenEvery = n
+	}
+}
+
+// WithLock makes every Write hold the mutex lock of the logger, which the callers can hold
+// too. Without it, the Writes hold an inner mutex instead, which Rotate, Sync and Close take
+// too: the concurrent Writes never interleave, nor rotate twice.
+func WithLock() Option {
+	return withLock(true)
+}
+
+func withLock(bLock bool) Option {
+	return func(l *Logger) {
+		l.bLock = bLock
+	}
+}
+
+// WithTimeFormat sets the time format for the file name of DailyRotation and HybridRotation loggers.
+func WithTimeFormat(format string) Option {
+	return func(l *Logger) {
+		l.timeFormat = format
+	}
+}
+
+// New creates a file logger configured by opts. Without any rotation option, the logger
+// rotates everyday at 00:00.
+func New(filename string, opts ...Option) (*Logger, error) {
+	l := &Logger{
+		filename: filename,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.clock == nil {
+		l.clock = systemClock{}
+	}
+	var err error
+	if l.filename, err = l.resolve(l.filename); err != nil {
+		return nil, err
+	}
+	if l.secondaryDir, err = l.resolve(l.secondaryDir); err != nil {
+		return nil, err
+	}
+	if l.rType == 0 {
+		l.rType = DailyRotation
+	}
+	if l.fallback == FallbackBuffer && l.pendingMax <= 0 {
+		l.pendingMax = 1024 * 1024
+	}
+	if l.fileMode == 0 {
+		l.fileMode = 0666
+	}
+	if l.dirMode == 0 {
+		l.dirMode = os.ModePerm
+	}
+	if l.rType == IntervalRotation && l.rInterval < time.Minute {
+		l.rInterval = time.Minute
+	}
+	if l.timeFormat == "" && (l.dirLayout == "" || l.rType == IntervalRotation) {
+		l.timeFormat = defaultTimeFormat(l.rType, l.rInterval)
+	}
+	if l.rMaxSize <= 0 {
+		l.rMaxSize = 1024 * 1024
+	}
+	if l.rMaxNum < 1 {
+		l.rMaxNum = 10
+	}
+	if l.nameText != "" || l.bHostname || l.bPID {
+		l.hostname, _ = os.Hostname()
+	}
+	if l.nameText == "" && (l.bHostname || l.bPID) {
+		if l.rType == SizedRotation && l.bBackups {
+			l.filename = l.hostPIDFileName(l.filename)
+		} else {
+			l.nameText = l.hostPIDNameText()
+		}
+	}
+	if l.nameText != "" {
+		if err := l.parseNameTemplate(); err != nil {
+			return nil, err
+		}
+	}
+	l.flatRe = l.flatNameRe()
+
+	// the holds can't be read from a failed primary directory, the logger fails over then
+	if err := l.loadHolds(); err != nil && l.secondaryDir == "" {
+		return nil, err
+	}
+
+	// the current file of WithTimestampedBackups has the set file name already
+	if l.symlink == "" && l.bSymlink && !(l.rType == SizedRotation && l.bBackups) {
+		path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+		if err != nil {
+			return l, err
+		}
+		l.symlink = path + fn + suffix
+	}
+
+	if l.bMigrate && !(l.rType == SizedRotation && l.bBackups) {
+		if err = l.migrateNames(); err != nil {
+			return nil, err
+		}
+	}
+
+	var f *os.File
+	switch l.rType {
+	case DailyRotation:
+		f, err = l.openNewDailyFile()
+	case SizedRotation:
+		if l.bBackups {
+			if l.bMigrate {
+				if err = l.migrateToBackups(); err != nil {
+					return nil, err
+				}
+			}
+			f, err = l.openActiveFile()
+			break
+		}
+		if err = l.initSizeFileNames(); err != nil {
+			break
+		}
+		f, err = l.openLatestSizeFile()
+	case HybridRotation:
+		f, err = l.openNewHybridFile(true)
+	case IntervalRotation:
+		f, err = l.openNewIntervalFile()
+	}
+	if err != nil {
+		f, err = l.failover(err)
+	}
+	if err != nil {
+		return l, err
+	}
+	l.setFile(f)
+	return l, nil
+}
+
+// defaultTimeFormat returns the time format of the file names of the rotation type, which follows
+// the interval of an IntervalRotation logger.
+func defaultTimeFormat(rType RotationType, interval time.Duration) string {
+	if rType == IntervalRotation {
+		if interval%(24*time.Hour) == 0 {
+			return "_2006_01_02"
+		} else if interval%time.Hour == 0 {
+			return "_2006_01_02_15"
+		}
+	}
+	return "_2006_01_02_15_04"
+}
+
+// initSizeFileNames prepares the file names of every index for SizedRotation logger
+func (l *Logger) initSizeFileNames() error {
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return err
+	}
+	l.fnRotateIndex = -1
+	l.rSize = l.rMaxSize
+	l.fnRotate = make([]string, l.rMaxNum)
+	l.fnRotateUsed = make([]bool, l.rMaxNum)
+	for i := 0; i < l.rMaxNum; i++ {
+		if l.fnRotate[i], err = l.logFileName(path, fn+strconv.Itoa(i)+suffix, time.Time{}, i); err != nil {
+			return err
+		}
+		l.fnRotateUsed[i] = false
+	}
+	return nil
+}
+
+// openLatestSizeFile opens the most recently modified file of a SizedRotation logger to go on
+// appending to it after a restart, or the next index if it's full or compressed. The existing
+// files are marked used, so the rotations overwrite them from the oldest one.
+func (l *Logger) openLatestSizeFile() (*os.File, error) {
+	var latest time.Time
+	full := false
+	for i, name := range l.fnRotate {
+		fInfo, err := os.Stat(name)
+		compressed := err != nil && l.isCompressed(name)
+		if compressed {
+			fInfo, err = os.Stat(name + l.compressor.Ext())
+		}
+		if err != nil {
+			continue
+		}
+		l.fnRotateUsed[i] = true
+		if l.fnRotateIndex < 0 || fInfo.ModTime().After(latest) {
+			l.fnRotateIndex, latest = i, fInfo.ModTime()
+			full = compressed || fInfo.Size() >= l.rMaxSize
+		}
+	}
+	if l.fnRotateIndex < 0 || full {
+		return l.openNewSizeFile()
+	}
+	logFile, err := l.openFile(l.fnRotate[l.fnRotateIndex])
+	if err != nil {
+		return nil, err
+	}
+	fInfo, err := logFile.Stat()
+	if err != nil {
+		logFile.Close()
+		return nil, err
+	}
+	l.rSize = fInfo.Size()
+	return logFile, nil
+}
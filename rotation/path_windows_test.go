@@ -0,0 +1,69 @@
+package rotation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveWindows(t *testing.T) {
+	tests := []struct {
+		base, name, want string
+	}{
+		{`D:\base`, `C:\logs\app.log`, `C:\logs\app.log`},
+		{`D:\base`, `C:/logs/app.log`, `C:/logs/app.log`},
+		{`D:\base`, `\\server\share\logs\app.log`, `\\server\share\logs\app.log`},
+		{`D:\base`, `\logs\app.log`, `\logs\app.log`},
+		{`D:\base`, `app.log`, `D:\base\app.log`},
+		{`D:\base`, `logs/app.log`, `D:\base\logs\app.log`},
+		{`\\server\share\base`, `logs\app.log`, `\\server\share\base\logs\app.log`},
+	}
+	for _, tt := range tests {
+		l := &Logger{baseDir: tt.base}
+		got, err := l.resolve(tt.name)
+		if err != nil {
+			t.Errorf("resolve(%q) with base %q: %v", tt.name, tt.base, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolve(%q) with base %q = %q, want %q", tt.name, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestGetPathFileNameWindows(t *testing.T) {
+	dir := t.TempDir()
+	if vol := filepath.VolumeName(dir); len(vol) != 2 || vol[1] != ':' {
+		t.Skipf("the temporary directory %q has no drive letter", dir)
+	}
+	// the drive letter is kept, and the slashes are turned into backslashes
+	for _, fn := range []string{dir + `\app.log`, filepath.ToSlash(dir) + "/app.log", strings.ToLower(dir[:1]) + dir[1:] + `\app.log`} {
+		path, name, suffix, err := getPathFileName(fn, os.ModePerm)
+		if err != nil {
+			t.Errorf("getPathFileName(%q): %v", fn, err)
+			continue
+		}
+		if !strings.EqualFold(path, dir+`\`) || name != "app" || suffix != ".log" {
+			t.Errorf("getPathFileName(%q) = %q, %q, %q, want %q, app, .log", fn, path, name, suffix, dir+`\`)
+		}
+	}
+}
+
+func TestRotationWindowsSlashes(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)}
+	l, err := New(filepath.ToSlash(dir)+"/app.log", WithDailyAt(0, 0), WithClock(clock), WithMaxTotalSize(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "a\n")
+	clock.add(time.Minute)
+	writeString(t, l, "b\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the retention finds the first file by its Windows path, and deletes it
+	checkFiles(t, dir, map[string]string{"app_2024_05_02_00_00.log": "b\n"})
+}
@@ -0,0 +1,94 @@
+package rotation
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// TornRecord describes a truncated final record of a log file, which is left when the
+// process crashes in the middle of a Write.
+type TornRecord struct {
+	Filename string // the log file
+	Offset   int64  // the offset where the truncated record starts
+	Size     int64  // the number of bytes of the truncated record
+}
+
+// FindTornRecord scans the end of a log file for a final record without the trailing newline.
+// It returns nil if the file ends with a complete record or is empty.
+func FindTornRecord(filename string) (*TornRecord, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fInfo, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	// read backwards chunk by chunk until a newline is found
+	const chunkSize = 4096
+	end := fInfo.Size()
+	buf := make([]byte, chunkSize)
+	for pos := end; pos > 0; {
+		n := int64(chunkSize)
+		if pos < n {
+			n = pos
+		}
+		pos -= n
+		if _, err := f.ReadAt(buf[:n], pos); err != nil && err != io.EOF {
+			return nil, err
+		}
+		i := bytes.LastIndexByte(buf[:n], '\n')
+		if i < 0 {
+			continue
+		}
+		offset := pos + int64(i) + 1
+		if offset == end {
+			return nil, nil
+		}
+		return &TornRecord{Filename: filename, Offset: offset, Size: end - offset}, nil
+	}
+	if end == 0 {
+		return nil, nil
+	}
+	// the whole file is one truncated record
+	return &TornRecord{Filename: filename, Offset: 0, Size: end}, nil
+}
+
+// RepairTornRecord trims the truncated final record of a log file, if any. If quarantine is true,
+// the trimmed bytes are appended to filename + ".torn" first, so nothing is lost.
+// It returns the repaired record, or nil if the file doesn't need repairing.
+func RepairTornRecord(filename string, quarantine bool) (*TornRecord, error) {
+	t, err := FindTornRecord(filename)
+	if err != nil || t == nil {
+		return nil, err
+	}
+	if quarantine {
+		if err = quarantineBytes(filename, t); err != nil {
+			return nil, err
+		}
+	}
+	return t, os.Truncate(filename, t.Offset)
+}
+
+// quarantineBytes copies the bytes of the torn record to filename + ".torn", ending with a newline.
+func quarantineBytes(filename string, t *TornRecord) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(filename+".torn", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, io.NewSectionReader(src, t.Offset, t.Size)); err == nil {
+		_, err = dst.Write([]byte{'\n'})
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,106 @@
+package rotation
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// logFileInfo is a log file found in the log directory.
+type logFileInfo struct {
+	path string
+	os.FileInfo
+}
+
+// listLogFiles returns the log files of the logger, which are the regular files in its directory
+// named as its files, by its template or not, and in its subdirectories if any,
+// sorted from the oldest to the newest.
+func (l *Logger) listLogFiles() ([]logFileInfo, error) {
+	path, _, _, err := getPathFileName(l.currentFilename(), l.dirMode)
+	if err != nil {
+		return nil, err
+	}
+	var files []logFileInfo
+	add := func(dir string, e fs.DirEntry) {
+		name := e.Name()
+		rel, _ := filepath.Rel(path, filepath.Join(dir, name))
+		if !e.Type().IsRegular() || !l.isLogFileName(rel) {
+			return
+		}
+		fInfo, err := e.Info()
+		if err != nil {
+			return
+		}
+		files = append(files, logFileInfo{path: filepath.Join(dir, name), FileInfo: fInfo})
+	}
+	if l.dirLayout != "" || l.nameRe != nil {
+		err = filepath.WalkDir(path, func(name string, e fs.DirEntry, err error) error {
+			if err == nil && !e.IsDir() {
+				add(filepath.Dir(name), e)
+			}
+			return nil
+		})
+	} else {
+		var entries []fs.DirEntry
+		entries, err = os.ReadDir(path)
+		for _, e := range entries {
+			add(path, e)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	return files, nil
+}
+
+// enforceTotalSize deletes the oldest log files until their total size is under rMaxTotalSize.
+func (l *Logger) enforceTotalSize() {
+	files, err := l.listLogFiles()
+	if err != nil {
+		return
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size()
+	}
+	current := ""
+	if l.file != nil {
+		current = filepath.Clean(l.file.Name())
+	}
+	held := l.heldFiles(files)
+	for _, f := range files {
+		if total <= l.rMaxTotalSize {
+			break
+		}
+		if f.path == current || held[f.path] || l.isWorking(f.path) {
+			continue
+		}
+		if err := l.removeLogFile(f.path); err == nil {
+			total -= f.Size()
+		}
+	}
+}
+
+// enforceMaxAge deletes the log files modified more than rMaxAge ago.
+func (l *Logger) enforceMaxAge() {
+	files, err := l.listLogFiles()
+	if err != nil {
+		return
+	}
+	current := ""
+	if l.file != nil {
+		current = filepath.Clean(l.file.Name())
+	}
+	held := l.heldFiles(files)
+	cutoff := l.clock.Now().Add(-l.rMaxAge)
+	for _, f := range files {
+		if !f.ModTime().Before(cutoff) {
+			break
+		}
+		if f.path != current && !held[f.path] && !l.isWorking(f.path) {
+			l.removeLogFile(f.path)
+		}
+	}
+}
@@ -15,22 +15,29 @@
 package rotation
 
 import (
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 )
 
 // RotationType is the type of log file name rotating. If it is DailyRotation, the log file will change everyday at a set time.
 // If it is SizedRotation, the log file will change when the size of file has grown over the MaxSize.
+// If it is HybridRotation, the log file will change at the set time or when the size of file has grown over the MaxSize,
+// whichever comes first. If it is IntervalRotation, the log file will change every set interval, such as every hour.
 type RotationType int
 
 const (
-	DailyRotation RotationType = 1 // rotated everyday at the set time
-	SizedRotation RotationType = 2 // rotated when file exceeds the setting size
+	DailyRotation    RotationType = 1 // rotated everyday at the set time
+	SizedRotation    RotationType = 2 // rotated when file exceeds the setting size
+	HybridRotation   RotationType = 3 // rotated everyday at the set time or when file exceeds the setting size
+	IntervalRotation RotationType = 4 // rotated every set interval, such as hourly or weekly
 )
 
 // ensure implement io.Write and io.Closer
@@ -42,13 +49,25 @@ type Logger struct {
 	// format string file names. Size logger files will alse have the same prefix and suffix but different indexes number
 	// format file names. All the files are retained in the same directory.
 	filename string
-
-	rType RotationType // DailyRotation or SizedRotation
-
-	rHour           int       // the hour of the set time of DailyRotation logger
-	rMinute         int       // the minute of the set time of RotatedDaily logger
-	currentFileTime time.Time // the opening or creating time of the current log file.
-	timeFormat      string    // the timeformat for the file name
+	nameMu   sync.Mutex // guards the switches of filename, which are read without the write lock too
+
+	rType RotationType // DailyRotation, SizedRotation, HybridRotation or IntervalRotation
+
+	rHour           int                // the hour of the set time of DailyRotation logger
+	rMinute         int                // the minute of the set time of RotatedDaily logger
+	currentFileTime time.Time          // the opening or creating time of the current log file.
+	nextRotation    time.Time          // the time of the next scheduled rotation
+	timeFormat      string             // the timeformat for the file name
+	dirLayout       string             // the time layout of the dated subdirectories of the files, empty if none
+	nameText        string             // the template of the file names, empty for the default naming
+	nameTmpl        *template.Template // the parsed nameText
+	nameRe          *regexp.Regexp     // matches the file names given by nameTmpl
+	flatRe          *regexp.Regexp     // matches the file names without nameTmpl
+	nameLevel       string             // the Level field of the name template
+	hostname        string             // the host name of the name template
+	bHostname       bool               // add the host name to the file names
+	bPID            bool               // add the process ID to the file names
+	bUTC            bool               // use UTC instead of the local time for the schedule and the file names
 
 	rMaxSize      int64    // the max size of per file, it represents the number of bytes. 1024 * 1024 * 1 = 1Mbytes
 	rSize         int64    // the bytes size of current log file
@@ -56,26 +75,94 @@ type Logger struct {
 	fnRotateIndex int      // the index of current log file, it can be 0, 1, 2 ... rMaxNum-1
 	fnRotate      []string // the file name of every log file for SizedRotation type, using fnRotateIndex can get a file name
 	fnRotateUsed  []bool   // the index of file name has been used or not
+	bBackups      bool     // rename the full files to timestamped backups instead of rotating the indexes
+	rotatedTo     string   // the backup name of the file closed by the last rotation, if it was renamed
+	bMigrate      bool     // rename the files of an earlier naming scheme when the logger is created
+
+	rInterval time.Duration // the rotation interval of IntervalRotation logger
+
+	dayStamp    string // the formatted time of the current day for HybridRotation logger
+	hybridIndex int    // the index of current log file within the day for HybridRotation logger
 
 	file *os.File // the current Writer
 
-	bLock      bool // write with a lock or not
-	sync.Mutex      // mutex lock for writing bytes
+	fileMode  os.FileMode // the mode of created log files, 0666 by default
+	bFileMode bool        // the file mode is set explicitly, so it is enforced regardless of umask
+	dirMode   os.FileMode // the mode of created directories, os.ModePerm by default
+	bOwner    bool        // change the owner of created log files
+	uid, gid  int         // the owner of created log files
+
+	rMaxTotalSize int64         // the byte budget of all the log files, 0 if unlimited
+	rMaxAge       time.Duration // the max age of the log files, 0 if unlimited
+
+	diskMinFree    int64      // the free space under which diskPolicy applies, 0 if unguarded
+	diskPolicy     DiskPolicy // what to do while the free space is low
+	diskCheckAt    time.Time  // the time of the next check of the free space
+	diskCheckBytes int64      // the bytes written at the last check of the free space
+	bLowDisk       bool       // the free space was low at the last check
+
+	holds  []Hold     // the files kept from the retention
+	holdMu sync.Mutex // guards holds, it's independent of the write lock
+
+	fallback       FallbackPolicy // what to do when the log file can't be opened or written
+	fallbackWriter io.Writer      // the writer of FallbackWriter policy
+	pending        []byte         // the bytes kept by FallbackBuffer policy
+	pendingMax     int            // the max number of pending bytes
+	bBroken        bool           // the log file couldn't be opened, retrying with backoff
+	secondaryDir   string         // the directory switched to when the primary one fails
+	primary        string         // the filename in the primary directory while switched, empty if not
+	failbackAt     time.Time      // the time of the next check of the primary directory
+	retryBackoff   time.Duration  // the current delay before retrying to open the log file
+	retryAt        time.Time      // the time of the next retry
+
+	onRotate func(old, new string) // called after rotating from the old file to the new one
+	onError  func(err error)       // called when opening or writing a file fails
+	onClose  func()                // called after the logger is closed
+
+	archiver            Archiver    // archives the completed files, nil if disabled
+	bArchiveDeleteLocal bool        // delete the completed files after they are archived
+	compressor          Compressor  // compresses the completed files, nil if disabled
+	audit               *auditChain // chains the lines of WithAuditChain, nil if disabled
+	bChecksums          bool        // record the SHA-256 of the completed files in the manifest
+	fileStart           time.Time   // when the logger started writing the current file
+
+	work    sync.WaitGroup // the goroutines completing the files, which Close waits for
+	workMu  sync.Mutex     // guards working
+	working map[string]int // the files being completed, which the retention skips
+
+	reopenEvery int // check whether the file was moved or deleted every reopenEvery writes, 0 if disabled
+	writeCount  int // the number of writes since the last check
+
+	bSyncDir bool // fsync the parent directory after creating a log file
+
+	syncPolicy   SyncPolicy    // when the file is synced
+	syncInterval time.Duration // the interval of SyncInterval policy
+	lastSync     time.Time     // the time of the last sync
+	bSymlink     bool          // maintain a symlink to the current file
+	symlink      string        // the path of the symlink to the current file
+
+	metrics metrics // the counters of Metrics
+
+	bFileLock bool      // hold the lock of the lock file, shared with other processes, while writing
+	lock      *os.File  // the lock file of bFileLock, opened by the first Write
+	followAt  time.Time // the time of the next check of the rotations of the other processes
+
+	baseDir  string // the directory the relative file names are resolved against, the working directory if empty
+	bExeBase bool   // resolve the relative file names against the directory of the executable
+
+	clock     Clock         // the source of time
+	bTimer    bool          // rotate by a timer at the scheduled times
+	timer     Timer         // the timer of bTimer
+	timerDone chan struct{} // closed to stop the goroutine of the timer
+
+	bLock      bool       // write with a lock or not
+	sync.Mutex            // mutex lock for writing bytes
+	writeMu    sync.Mutex // serializes the Writes without bLock, so they never interleave or rotate twice
 }
 
 // Create a daily roation file logger, rotating at the set hour and minute
 func NewDailyLogger(filename string, rHour, rMinute int, bLock bool) (*Logger, error) {
-	l := &Logger{
-		filename:   filename,
-		rType:      DailyRotation,
-		rHour:      rHour,
-		rMinute:    rMinute,
-		timeFormat: "_2006_01_02_15_04",
-		bLock:      bLock,
-	}
-	var err error
-	l.file, err = l.openNewDailyFile()
-	return l, err
+	return New(filename, WithDailyAt(rHour, rMinute), withLock(bLock))
 }
 
 // Create a daily roation file logger, rotating at the set hour and minute, without lock
@@ -92,35 +179,7 @@ func NewDailyWithLockLogger(filename string, rHour, rMinute int) (*Logger, error
 // The maximum number of file rotations refers to the set limit on how many log files can be created
 // and stored in a rotation cycle before the oldest file is overwritten to make room for new files.
 func NewSizeLogger(filename string, rMaxSize int64, rMaxNum int, bLock bool) (*Logger, error) {
-	if rMaxSize <= 0 {
-		rMaxSize = 1024 * 1024
-	}
-	if rMaxNum < 1 {
-		rMaxNum = 10
-	}
-	l := &Logger{
-		filename:      filename,
-		rType:         SizedRotation,
-		rMaxSize:      rMaxSize,
-		rMaxNum:       rMaxNum,
-		fnRotateIndex: -1,
-		rSize:         rMaxSize,
-		bLock:         bLock,
-	}
-	path, fn, suffix, err := getPathFileName(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	l.fnRotate = make([]string, l.rMaxNum)
-	l.fnRotateUsed = make([]bool, l.rMaxNum)
-	for i := 0; i < l.rMaxNum; i++ {
-		l.fnRotate[i] = path + fn + strconv.Itoa(i) + suffix
-		l.fnRotateUsed[i] = false
-	}
-
-	l.file, err = l.openNewSizeFile()
-	return l, err
+	return New(filename, WithMaxSize(rMaxSize), WithMaxFiles(rMaxNum), withLock(bLock))
 }
 
 // Create a size rotation file logger, rotating when file size exceeds rMaxSize bytes.
@@ -139,30 +198,196 @@ func NewSizeWithLockLogger(filename string, rMaxSize int64, rMaxNum int) (*Logge
 	return NewSizeLogger(filename, rMaxSize, rMaxNum, true)
 }
 
+// Create a hybrid rotation file logger, rotating at the set hour and minute everyday, or when file size
+// exceeds rMaxSize bytes, whichever comes first. The file names combine the date and an index which
+// starts from 0 every day, such as out_2006_01_02_15_04_0.log, out_2006_01_02_15_04_1.log.
+func NewHybridLogger(filename string, rHour, rMinute int, rMaxSize int64, bLock bool) (*Logger, error) {
+	return New(filename, WithDailyAt(rHour, rMinute), WithMaxSize(rMaxSize), withLock(bLock))
+}
+
+// Create an interval rotation file logger, rotating every interval d, such as time.Hour or 7 * 24 * time.Hour.
+// The periods are aligned to the local time, an hourly logger rotates at the start of every hour. The default
+// time format of the file name follows the interval: _2006_01_02 for whole days, _2006_01_02_15 for whole hours
+// and _2006_01_02_15_04 otherwise. The interval should not be less than one minute.
+func NewIntervalLogger(filename string, d time.Duration) (*Logger, error) {
+	return New(filename, WithInterval(d))
+}
+
 // Set the time format for file name, it can be used when RotationType = DailyRotate
 func (l *Logger) SetTimeFormat(format string) {
 	l.timeFormat = format
+	l.flatRe = l.flatNameRe()
+}
+
+// openFile opens the log file for appending, creating it if it does not exist
+func (l *Logger) openFile(filename string) (*os.File, error) {
+	_, statErr := os.Stat(filename)
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, l.fileMode)
+	if err != nil {
+		return nil, err
+	}
+	if !os.IsNotExist(statErr) {
+		return f, nil
+	}
+	// the file is just created, the mode is set again as umask may have changed it
+	if l.bFileMode {
+		if err = f.Chmod(l.fileMode); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if l.bOwner {
+		if err = f.Chown(l.uid, l.gid); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if l.bSyncDir {
+		if err = syncDir(filepath.Dir(filename)); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// syncDirs fsyncs the directories of the files with WithDirSync, after they were renamed to or
+// from them, so the renames survive a power loss.
+func (l *Logger) syncDirs(files ...string) error {
+	if !l.bSyncDir {
+		return nil
+	}
+	var errs []error
+	synced := map[string]bool{}
+	for _, f := range files {
+		dir := filepath.Dir(f)
+		if !synced[dir] {
+			synced[dir] = true
+			errs = append(errs, syncDir(dir))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // open a new daily file
 func (l *Logger) openNewDailyFile() (*os.File, error) {
-	path, fn, suffix, err := getPathFileName(l.filename)
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return nil, err
+	}
+
+	l.resetDailyTime()
+	ts := l.now().Format(l.timeFormat)
+	if path, err = l.datedPath(path); err != nil {
+		return nil, err
+	}
+	filename, err := l.logFileName(path, fn+ts+suffix, l.currentFileTime, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.openFile(filename)
+}
+
+// now returns the current time in the location of the logger, which is used for the rotation schedule
+// and the file names
+func (l *Logger) now() time.Time {
+	if l.bUTC {
+		return l.clock.Now().UTC()
+	}
+	return l.clock.Now()
+}
+
+// resetDailyTime sets currentFileTime to the latest passed rotation time, and nextRotation to the
+// next one. They are computed by the calendar, so a rotation stays at its set time across the
+// daylight saving changes.
+func (l *Logger) resetDailyTime() {
+	now := l.now()
+	y, m, d := now.Date()
+	l.currentFileTime = time.Date(y, m, d, l.rHour, l.rMinute, 0, 0, now.Location())
+	if l.currentFileTime.After(now) {
+		d--
+		l.currentFileTime = time.Date(y, m, d, l.rHour, l.rMinute, 0, 0, now.Location())
+	}
+	l.nextRotation = time.Date(y, m, d+1, l.rHour, l.rMinute, 0, 0, now.Location())
+}
+
+// open a new interval file, named by the start time of the current period
+func (l *Logger) openNewIntervalFile() (*os.File, error) {
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
 	if err != nil {
 		return nil, err
 	}
 
-	l.currentFileTime = time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), l.rHour, l.rMinute, 0, 0, time.Local)
-	if l.currentFileTime.After(time.Now()) {
-		l.currentFileTime = l.currentFileTime.AddDate(0, 0, -1)
+	now := l.now()
+	_, offset := now.Zone()
+	shift := time.Duration(offset) * time.Second
+	l.currentFileTime = now.Add(shift).Truncate(l.rInterval).Add(-shift)
+	l.nextRotation = l.currentFileTime.Add(l.rInterval)
+	ts := l.currentFileTime.Format(l.timeFormat)
+	if path, err = l.datedPath(path); err != nil {
+		return nil, err
+	}
+	filename, err := l.logFileName(path, fn+ts+suffix, l.currentFileTime, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	ts := time.Now().Format(l.timeFormat)
+	return l.openFile(filename)
+}
+
+// open a new hybrid file, starting from index 0 if it is a new day, or else the next index
+func (l *Logger) openNewHybridFile(newDay bool) (*os.File, error) {
+	path, fn, suffix, err := getPathFileName(l.filename, l.dirMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if newDay {
+		l.resetDailyTime()
+		l.dayStamp = l.now().Format(l.timeFormat)
+		l.hybridIndex = 0
+	} else {
+		l.hybridIndex++
+	}
+	if path, err = l.datedPath(path); err != nil {
+		return nil, err
+	}
 
-	return os.OpenFile(path+fn+ts+suffix, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	for {
+		filename, err := l.logFileName(path, fn+l.dayStamp+"_"+strconv.Itoa(l.hybridIndex)+suffix, l.currentFileTime, l.hybridIndex)
+		if err != nil {
+			return nil, err
+		}
+		// skip the files which are already compressed, e.g. after a restart
+		if l.isCompressed(filename) {
+			l.hybridIndex++
+			continue
+		}
+		logFile, err := l.openFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		fInfo, err := logFile.Stat()
+		if err != nil {
+			logFile.Close()
+			return nil, err
+		}
+		// skip the files which are already full, e.g. after a restart
+		if fInfo.Size() < l.rMaxSize {
+			l.rSize = fInfo.Size()
+			return logFile, nil
+		}
+		logFile.Close()
+		l.hybridIndex++
+	}
 }
 
 // open a new size limit file
 func (l *Logger) openNewSizeFile() (*os.File, error) {
+	if l.bBackups {
+		return l.openNewBackupFile()
+	}
 	var logFile *os.File
 	var err error
 	for l.rSize >= l.rMaxSize {
@@ -171,19 +396,25 @@ func (l *Logger) openNewSizeFile() (*os.File, error) {
 		l.fnRotateIndex %= l.rMaxNum
 		filename := l.fnRotate[l.fnRotateIndex]
 
-		// if the new filename is used, the old file needs to be removed.
+		// if the new filename is used, the old file needs to be removed, or moved if it's held.
 		if l.fnRotateUsed[l.fnRotateIndex] {
-			if err = os.Remove(filename); err != nil {
+			if l.isHeld(filename) {
+				err = l.moveHeld(filename)
+			} else {
+				err = os.Remove(filename)
+			}
+			if err != nil && !os.IsNotExist(err) {
 				return nil, err
 			}
 		}
 
-		logFile, err = os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+		logFile, err = l.openFile(filename)
 		if err != nil {
 			return nil, err
 		}
 		fInfo, err := logFile.Stat()
 		if err != nil {
+			logFile.Close()
 			return nil, err
 		}
 		l.rSize = fInfo.Size()
@@ -193,16 +424,116 @@ func (l *Logger) openNewSizeFile() (*os.File, error) {
 	return logFile, nil
 }
 
+// lockState takes the locks excluding the Writes: the mutex lock, and the inner one of the
+// Writes without WithLock.
+func (l *Logger) lockState() {
+	l.Lock()
+	if !l.bLock {
+		l.writeMu.Lock()
+	}
+}
+
+func (l *Logger) unlockState() {
+	if !l.bLock {
+		l.writeMu.Unlock()
+	}
+	l.Unlock()
+}
+
 // Write implements io.Writer.
 func (l *Logger) Write(p []byte) (n int, err error) {
 	if l.bLock {
 		l.Lock()
 		defer l.Unlock()
+	} else {
+		l.writeMu.Lock()
+		defer l.writeMu.Unlock()
+	}
+	if l.bFileLock {
+		if err = l.lockFile(); err != nil {
+			// writing without the lock would interleave with the others
+			l.reportError(err)
+			l.metrics.dropped.Add(1)
+			return 0, err
+		}
+		defer l.unlockFile()
+		l.followOthers()
+	}
+	if l.bBroken {
+		l.tryReopen()
+	} else {
+		l.rotate()
+	}
+	if l.primary != "" {
+		l.tryFailback()
+	}
+	if l.reopenEvery > 0 {
+		if l.writeCount++; l.writeCount >= l.reopenEvery {
+			l.writeCount = 0
+			l.reopenIfMoved()
+		}
+	}
+	if l.diskMinFree > 0 {
+		l.checkDisk()
+		if l.bLowDisk {
+			switch l.diskPolicy {
+			case DiskStop:
+				l.metrics.dropped.Add(1)
+				return 0, ErrLowDisk
+			case DiskDropLow:
+				return l.writeHighLevels(p)
+			}
+		}
+	}
+	return l.writeLines(p)
+}
+
+// writeLines writes p to the current file, chained if WithAuditChain is set.
+func (l *Logger) writeLines(p []byte) (int, error) {
+	if l.audit != nil {
+		return l.writeChained(p)
+	}
+	return l.writeFile(p)
+}
+
+// writeFile writes p to the current file, or to the fallback.
+func (l *Logger) writeFile(p []byte) (n int, err error) {
+	if l.file == nil {
+		return l.writeFallback(p)
+	}
+	if len(l.pending) > 0 {
+		l.flushPending()
 	}
-	l.rotate()
 	n, err = l.file.Write(p)
 	l.rSize += int64(n)
-	return n, err
+	l.countWrite(p[:n])
+	if err != nil {
+		l.reportError(err)
+		if f, ferr := l.failover(err); ferr == nil {
+			l.setFile(f)
+			m, werr := l.file.Write(p[n:])
+			l.rSize += int64(m)
+			l.countWrite(p[n : n+m])
+			return n + m, werr
+		}
+		if l.fallback == FallbackWriter || l.fallback == FallbackBuffer {
+			m, ferr := l.writeFallback(p[n:])
+			return n + m, ferr
+		}
+		return n, err
+	}
+	l.syncAfterWrite()
+	return n, nil
+}
+
+// reportError passes err to the OnError hook, if any
+func (l *Logger) reportError(err error) {
+	l.metrics.errors.Add(1)
+	msg := err.Error()
+	l.metrics.lastError.Store(&msg)
+	if l.onError != nil {
+		l.onError(err)
+	}
 }
 
 // the file will be rotated if the rotation condition is met, do it before writing bytes.
@@ -212,7 +543,7 @@ func (l *Logger) rotate() {
 	bNeedRotate := false
 	switch l.rType {
 	case DailyRotation:
-		if time.Now().AddDate(0, 0, -1).After(l.currentFileTime) {
+		if !l.now().Before(l.nextRotation) {
 			logFile, err = l.openNewDailyFile()
 			bNeedRotate = true
 		}
@@ -221,67 +552,225 @@ func (l *Logger) rotate() {
 			logFile, err = l.openNewSizeFile()
 			bNeedRotate = true
 		}
+	case HybridRotation:
+		if !l.now().Before(l.nextRotation) {
+			logFile, err = l.openNewHybridFile(true)
+			bNeedRotate = true
+		} else if l.rSize >= l.rMaxSize {
+			logFile, err = l.openNewHybridFile(false)
+			bNeedRotate = true
+		}
+	case IntervalRotation:
+		if !l.now().Before(l.nextRotation) {
+			logFile, err = l.openNewIntervalFile()
+			bNeedRotate = true
+		}
 	}
 	if bNeedRotate {
-		l.file.Close()
 		if err != nil {
-			l.file = os.Stdout
-		} else {
-			l.file = logFile
+			logFile, err = l.failover(err)
+		}
+		if err != nil {
+			l.reportError(err)
+			if l.fallback != FallbackStdout {
+				l.setBroken()
+				return
+			}
+			logFile = os.Stdout
 		}
+		l.setFile(logFile)
 	}
 }
 
-// Close implements io.Closer, and closes the current file.
+// Close implements io.Closer, and closes the current file. It waits for the compressions, the
+// checksums and the archives of the completed files in progress.
 func (l *Logger) Close() error {
-	l.Lock()
-	defer l.Unlock()
+	closed, err := l.closeFile()
+	l.work.Wait()
+	if closed && l.onClose != nil {
+		l.onClose()
+	}
+	return err
+}
+
+// closeFile closes the current file, it reports whether there was one.
+func (l *Logger) closeFile() (bool, error) {
+	l.lockState()
+	defer l.unlockState()
+	l.stopTimer()
+	if l.lock != nil {
+		l.lock.Close()
+		l.lock = nil
+	}
 	if l.file == nil {
-		return nil
+		return false, nil
 	}
+	l.syncBeforeClose()
 	err := l.file.Close()
 	l.file = nil
-	return err
+	l.setCurrentFile("")
+	return true, err
 }
 
 // Rotate causes Logger to close the existing log file and immediately create a
 // new one.  This is a helper function for applications that want to initiate
 // rotations outside of the normal rotation rules, such as in response to
-// SIGHUP.  After rotating, this initiates compression and removal of old log
-// files according to the configuration.
+// SIGHUP.
+func (l *Logger) Rotate() error {
+	l.lockState()
+	defer l.unlockState()
+	if l.bFileLock {
+		if err := l.lockFile(); err != nil {
+			return err
+		}
+		defer l.unlockFile()
+	}
+
+	logFile, err := l.openNext()
+	if err != nil {
+		return err
+	}
+	l.setFile(logFile)
+	return nil
+}
+
+// openNext opens the next log file regardless of the rotation condition
+func (l *Logger) openNext() (logFile *os.File, err error) {
+	switch l.rType {
+	case DailyRotation:
+		logFile, err = l.openNewDailyFile()
+	case SizedRotation:
+		// pretend the current file is full so the next index is taken
+		l.rSize = l.rMaxSize
+		logFile, err = l.openNewSizeFile()
+	case HybridRotation:
+		logFile, err = l.openNewHybridFile(false)
+	case IntervalRotation:
+		logFile, err = l.openNewIntervalFile()
+	}
+	return logFile, err
+}
 
-// getPathFileName return the filename's fullpath, prefix filename and the suffix
-func getPathFileName(fn string) (string, string, string, error) {
+// setFile closes the current file and switches to f
+func (l *Logger) setFile(f *os.File) {
+	old := ""
+	if l.file != nil && l.file != os.Stdout {
+		old = l.file.Name()
+		l.syncBeforeClose()
+		l.file.Close()
+	}
+	if l.rotatedTo != "" {
+		old, l.rotatedTo = l.rotatedTo, ""
+	}
+	l.file = f
+	if f == nil {
+		l.setCurrentFile("")
+		return
+	}
+	l.setCurrentFile(f.Name())
+	if f == os.Stdout {
+		return
+	}
+	if old != "" {
+		l.metrics.rotations.Add(1)
+		l.metrics.lastRotation.Store(l.clock.Now().UnixNano())
+	}
+	l.metrics.fileSize.Store(l.rSize)
+	l.bBroken = false
+	l.metrics.broken.Store(false)
+	if l.symlink != "" {
+		l.updateSymlink(f.Name())
+	}
+	if l.onRotate != nil && old != "" {
+		l.onRotate(old, f.Name())
+	}
+	if old != "" && old != f.Name() {
+		l.complete(old)
+	}
+	l.fileStart = l.clock.Now()
+	if l.rMaxAge > 0 {
+		l.enforceMaxAge()
+	}
+	if l.rMaxTotalSize > 0 {
+		l.enforceTotalSize()
+	}
+	l.armTimer()
+}
+
+// updateSymlink points the symlink to target atomically, by creating a temporary link and renaming it.
+// Errors are ignored as the symlink is only a convenience for readers, but a file which isn't a
+// symlink, such as the current file of WithTimestampedBackups, is never replaced.
+func (l *Logger) updateSymlink(target string) {
+	if filepath.Clean(target) == filepath.Clean(l.symlink) {
+		return
+	}
+	if fInfo, err := os.Lstat(l.symlink); err == nil && fInfo.Mode()&os.ModeSymlink == 0 {
+		return
+	}
+	if filepath.Dir(target) == filepath.Dir(l.symlink) {
+		target = filepath.Base(target)
+	}
+	tmp := l.symlink + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, l.symlink); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// getPathFileName return the filename's fullpath, prefix filename and the suffix. The fullpath
+// ends with a path separator, a relative path is resolved against the working directory, New
+// already resolved the file name of a Logger against its base directory.
+func getPathFileName(fn string, dirMode os.FileMode) (string, string, string, error) {
 	var path, prefix, suffix string
 	if len(fn) > 0 {
-		indexFile := strings.LastIndex(fn, "/")
-		indexLastDot := strings.LastIndex(fn, ".")
-
-		if indexLastDot > 0 && indexFile < indexLastDot {
-			prefix = fn[indexFile+1 : indexLastDot]
-			if indexLastDot < (len(fn) - 1) {
-				suffix = fn[indexLastDot:]
-			}
-		} else if indexLastDot == -1 {
-			prefix = fn[indexFile+1:]
-		}
+		dir, base := filepath.Split(fn)
+		suffix = filepath.Ext(base)
+		prefix = strings.TrimSuffix(base, suffix)
 		if len(prefix) == 0 {
 			prefix = "out"
 		}
-		if len(suffix) == 0 {
+		if len(suffix) <= 1 {
 			suffix = ".log"
 		}
-		path = fn[0:(indexFile + 1)]
-		var dir string
-		var err error
-		if (len(path) > 0 && path[0] != '/') || (len(path) == 0) {
-			if dir, err = filepath.Abs(filepath.Dir(os.Args[0])); err != nil {
+		if !filepath.IsAbs(dir) {
+			var err error
+			if dir, err = filepath.Abs(dir); err != nil {
 				return "", "", "", err
 			}
+		}
+		path = filepath.Clean(dir)
+		if !os.IsPathSeparator(path[len(path)-1]) {
+			path += string(filepath.Separator)
+		}
+	}
+	return path, prefix, suffix, os.MkdirAll(path, dirMode)
+}
 
-			dir += "/"
+// reopenIfMoved reopens the current file by its name if the path no longer refers to it
+func (l *Logger) reopenIfMoved() {
+	if l.file == nil || l.file == os.Stdout {
+		return
+	}
+	name := l.file.Name()
+	pathInfo, err := os.Stat(name)
+	if err == nil {
+		fInfo, err := l.file.Stat()
+		if err == nil && os.SameFile(pathInfo, fInfo) {
+			return
 		}
-		path = dir + path
 	}
-	return path, prefix, suffix, os.MkdirAll(path, os.ModePerm)
+	f, err := l.openFile(name)
+	if err != nil {
+		l.reportError(err)
+		return
+	}
+	l.file.Close()
+	l.file = f
+	l.setCurrentFile(f.Name())
+	if fInfo, err := f.Stat(); err == nil {
+		l.rSize = fInfo.Size()
+	}
 }
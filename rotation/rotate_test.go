@@ -0,0 +1,259 @@
+package rotation
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only changes when the test sets it. Its timers never fire.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.set(c.Now().Add(d))
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	return fakeTimer{}
+}
+
+type fakeTimer struct{}
+
+func (fakeTimer) C() <-chan time.Time {
+	return nil
+}
+
+func (fakeTimer) Stop() bool {
+	return true
+}
+
+func (fakeTimer) Reset(d time.Duration) bool {
+	return true
+}
+
+// readDir returns the contents of the log files of dir by their names.
+func readDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".log" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[e.Name()] = string(b)
+	}
+	return files
+}
+
+// checkFiles checks the log files of dir against want.
+func checkFiles(t *testing.T, dir string, want map[string]string) {
+	t.Helper()
+	got := readDir(t, dir)
+	var names []string
+	for name := range got {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %d files", names, len(want))
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("%s = %q, want %q (files %v)", name, got[name], content, names)
+		}
+	}
+}
+
+func writeString(t *testing.T, l *Logger, s string) {
+	t.Helper()
+	if _, err := l.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetPathFileName(t *testing.T) {
+	dir := t.TempDir()
+	sep := string(filepath.Separator)
+	tests := []struct {
+		fn                 string
+		path, name, suffix string
+	}{
+		{filepath.Join(dir, "app.log"), dir + sep, "app", ".log"},
+		{filepath.Join(dir, "app.txt"), dir + sep, "app", ".txt"},
+		{filepath.Join(dir, "app"), dir + sep, "app", ".log"},
+		{filepath.Join(dir, "app."), dir + sep, "app", ".log"},
+		{filepath.Join(dir, ".log"), dir + sep, "out", ".log"},
+		{filepath.Join(dir, "sub", "app.log"), filepath.Join(dir, "sub") + sep, "app", ".log"},
+		{filepath.ToSlash(dir) + "/app.log", dir + sep, "app", ".log"},
+	}
+	for _, tt := range tests {
+		path, name, suffix, err := getPathFileName(tt.fn, os.ModePerm)
+		if err != nil {
+			t.Errorf("getPathFileName(%q): %v", tt.fn, err)
+			continue
+		}
+		if path != tt.path || name != tt.name || suffix != tt.suffix {
+			t.Errorf("getPathFileName(%q) = %q, %q, %q, want %q, %q, %q", tt.fn, path, name, suffix, tt.path, tt.name, tt.suffix)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); err != nil {
+		t.Errorf("the directory isn't created: %v", err)
+	}
+}
+
+func TestGetPathFileNameRelative(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, name, suffix, err := getPathFileName("app.log", os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Clean(wd) + string(filepath.Separator); path != want || name != "app" || suffix != ".log" {
+		t.Errorf("getPathFileName(app.log) = %q, %q, %q, want %q, app, .log", path, name, suffix, want)
+	}
+}
+
+func TestResolveBaseDir(t *testing.T) {
+	dir := t.TempDir()
+	l := &Logger{baseDir: dir}
+	got, err := l.resolve(filepath.Join("logs", "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "logs", "app.log"); got != want {
+		t.Errorf("resolve = %q, want %q", got, want)
+	}
+	abs := filepath.Join(dir, "app.log")
+	if got, err = l.resolve(abs); err != nil || got != abs {
+		t.Errorf("resolve(%q) = %q, %v, want it unchanged", abs, got, err)
+	}
+}
+
+func TestSizedRotation(t *testing.T) {
+	dir := t.TempDir()
+	l, err := New(filepath.Join(dir, "app.log"), WithMaxSize(10), WithMaxFiles(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"line 0...\n", "line 1...\n", "line 2...\n"} {
+		writeString(t, l, s)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app0.log": "line 0...\n",
+		"app1.log": "line 1...\n",
+		"app2.log": "line 2...\n",
+	})
+	// the oldest file is reused
+	writeString(t, l, "line 3...\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app0.log": "line 3...\n",
+		"app1.log": "line 1...\n",
+		"app2.log": "line 2...\n",
+	})
+}
+
+func TestSizedRotationRestart(t *testing.T) {
+	dir := t.TempDir()
+	fn := filepath.Join(dir, "app.log")
+	l, err := New(fn, WithMaxSize(20), WithMaxFiles(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "line 0...\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the file which isn't full is appended to
+	if l, err = New(fn, WithMaxSize(20), WithMaxFiles(3)); err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "line 1...\n")
+	writeString(t, l, "line 2...\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app0.log": "line 0...\nline 1...\n",
+		"app1.log": "line 2...\n",
+	})
+}
+
+func TestDailyRotation(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)}
+	l, err := New(filepath.Join(dir, "app.log"), WithDailyAt(0, 0), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "a\n")
+	clock.add(59 * time.Second)
+	writeString(t, l, "b\n")
+	clock.add(time.Second)
+	writeString(t, l, "c\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app_2024_05_01_23_59.log": "a\nb\n",
+		"app_2024_05_02_00_00.log": "c\n",
+	})
+}
+
+func TestDailyRotationOnRotate(t *testing.T) {
+	dir := t.TempDir()
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	var rotations []string
+	l, err := New(filepath.Join(dir, "app.log"), WithDailyAt(6, 30), WithClock(clock),
+		WithTimeFormat("_2006_01_02"), WithOnRotate(func(old, new string) {
+			rotations = append(rotations, filepath.Base(old)+" "+filepath.Base(new))
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeString(t, l, "a\n")
+	clock.set(time.Date(2024, 5, 2, 6, 29, 59, 0, time.UTC))
+	writeString(t, l, "b\n")
+	clock.set(time.Date(2024, 5, 2, 6, 30, 0, 0, time.UTC))
+	writeString(t, l, "c\n")
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, map[string]string{
+		"app_2024_05_01.log": "a\nb\n",
+		"app_2024_05_02.log": "c\n",
+	})
+	if got := strings.Join(rotations, ","); got != "app_2024_05_01.log app_2024_05_02.log" {
+		t.Errorf("rotations = %q", got)
+	}
+}
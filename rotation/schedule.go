@@ -0,0 +1,67 @@
+package rotation
+
+// WithRotationTimer rotates the files of DailyRotation, HybridRotation and IntervalRotation
+// loggers by a timer at the scheduled times, instead of by the first Write after them, so every
+// period has its file even if nothing is logged, and the files are complete for the readers
+// and the archiver on time. As the timer rotates from its own goroutine, it implies WithLock.
+func WithRotationTimer() Option {
+	return func(l *Logger) {
+		l.bTimer = true
+		l.bLock = true
+	}
+}
+
+// armTimer sets the timer to the next scheduled rotation.
+func (l *Logger) armTimer() {
+	if !l.bTimer || l.rType == SizedRotation || l.nextRotation.IsZero() {
+		return
+	}
+	d := l.nextRotation.Sub(l.clock.Now())
+	if l.timer != nil {
+		l.timer.Reset(d)
+		return
+	}
+	l.timer = l.clock.NewTimer(d)
+	l.timerDone = make(chan struct{})
+	go l.timerLoop(l.timer, l.timerDone)
+}
+
+func (l *Logger) timerLoop(t Timer, done chan struct{}) {
+	for {
+		select {
+		case <-t.C():
+			l.onTimer()
+		case <-done:
+			return
+		}
+	}
+}
+
+// stopTimer stops the timer for good, as the logger is closed.
+func (l *Logger) stopTimer() {
+	l.bTimer = false
+	if l.timer != nil {
+		l.timer.Stop()
+		close(l.timerDone)
+		l.timer = nil
+	}
+}
+
+func (l *Logger) onTimer() {
+	l.lockState()
+	defer l.unlockState()
+	if !l.bTimer || l.bBroken {
+		return
+	}
+	if l.bFileLock {
+		if err := l.lockFile(); err != nil {
+			l.reportError(err)
+			return
+		}
+		defer l.unlockFile()
+		l.followOthers()
+	}
+	l.rotate()
+	// the timer may fire a bit early, it's set again if the logger didn't rotate
+	l.armTimer()
+}
@@ -0,0 +1,152 @@
+package rotation
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// failbackInterval is the delay between the checks whether the primary directory is writable
+// again.
+const failbackInterval = time.Minute
+
+// WithSecondaryDir sets a directory, preferably on another disk or volume, which the logger
+// switches to when a log file of the primary directory can't be opened or written. Every
+// minute, the logger checks whether the primary directory is writable again, and switches back
+// if so. The switches are recorded in the manifest of the directory switched to, see ManifestEntry.
+func WithSecondaryDir(dir string) Option {
+	return func(l *Logger) {
+		l.secondaryDir = dir
+	}
+}
+
+// ManifestEntry is an event recorded in the manifest of a log directory, the JSON lines file
+// named like the log files with the .manifest extension, such as app.manifest.
+type ManifestEntry struct {
+	Time  time.Time `json:"time"`
+	Event string    `json:"event"`          // "failover", "failback", "completed", "erased" or "renamed"
+	From  string    `json:"from,omitempty"` // the file name of the logger before the switch, or of a renamed file
+	To    string    `json:"to,omitempty"`   // the file name of the logger after the switch, or of a renamed file
+	Error string    `json:"error,omitempty"`
+
+	// the file completed by a rotation, see WithChecksums, or rewritten by EraseSubject
+	File   string     `json:"file,omitempty"`
+	Size   int64      `json:"size,omitempty"`
+	SHA256 string     `json:"sha256,omitempty"`
+	Start  *time.Time `json:"start,omitempty"` // when the logger started writing the file, Time when it completed it
+}
+
+// failover switches to the secondary directory after the primary one failed with cause, and
+// returns the log file opened there. It returns cause if there is no secondary directory, or
+// the logger already switched.
+func (l *Logger) failover(cause error) (*os.File, error) {
+	if l.secondaryDir == "" || l.primary != "" {
+		return nil, cause
+	}
+	l.primary = l.filename
+	l.setFilename(filepath.Join(l.secondaryDir, filepath.Base(l.filename)))
+	f, err := l.openSwitched()
+	if err != nil {
+		l.setFilename(l.primary)
+		l.primary = ""
+		return nil, err
+	}
+	l.failbackAt = l.clock.Now().Add(failbackInterval)
+	l.moveHolds(l.primary)
+	l.recordSwitch("failover", l.primary, l.filename, cause)
+	return f, nil
+}
+
+// tryFailback switches back to the primary directory if it's writable again.
+func (l *Logger) tryFailback() {
+	if l.clock.Now().Before(l.failbackAt) {
+		return
+	}
+	l.failbackAt = l.clock.Now().Add(failbackInterval)
+	path, _, _, err := getPathFileName(l.primary, l.dirMode)
+	if err != nil {
+		return
+	}
+	probe, err := os.CreateTemp(path, ".rlog-probe-*")
+	if err != nil {
+		return
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	secondary := l.filename
+	l.setFilename(l.primary)
+	f, err := l.openSwitched()
+	if err != nil {
+		l.setFilename(secondary)
+		return
+	}
+	l.primary = ""
+	l.setFile(f)
+	l.moveHolds(secondary)
+	l.recordSwitch("failback", secondary, l.filename, nil)
+}
+
+// setFilename switches the file name of the logger, under the write lock.
+func (l *Logger) setFilename(filename string) {
+	l.nameMu.Lock()
+	defer l.nameMu.Unlock()
+	l.filename = filename
+}
+
+// currentFilename returns the file name of the logger, it's the only way to read it without
+// the write lock, such as from CheckHealth, the holds or the archive goroutine.
+func (l *Logger) currentFilename() string {
+	l.nameMu.Lock()
+	defer l.nameMu.Unlock()
+	return l.filename
+}
+
+// openSwitched opens a log file after the directory of the logger switched.
+func (l *Logger) openSwitched() (*os.File, error) {
+	if l.rType == SizedRotation {
+		if l.bBackups {
+			return l.openActiveFile()
+		}
+		if err := l.initSizeFileNames(); err != nil {
+			return nil, err
+		}
+	}
+	return l.openNext()
+}
+
+// recordSwitch appends a switch to the manifest of the current directory of the logger. Errors
+// are ignored, as the switch must not fail for its record.
+func (l *Logger) recordSwitch(event, from, to string, cause error) {
+	e := ManifestEntry{Time: l.clock.Now(), Event: event, From: from, To: to}
+	if cause != nil {
+		e.Error = cause.Error()
+	}
+	if manifest, err := l.manifestName(); err == nil {
+		appendManifest(manifest, e, l.fileMode)
+	}
+}
+
+// manifestName returns the name of the manifest of the current directory of the logger.
+func (l *Logger) manifestName() (string, error) {
+	path, fn, _, err := getPathFileName(l.filename, l.dirMode)
+	return path + fn + ".manifest", err
+}
+
+// appendManifest appends e to the manifest, creating it with mode if needed.
+func appendManifest(manifest string, e ManifestEntry, mode os.FileMode) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(manifest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, mode)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
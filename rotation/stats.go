@@ -0,0 +1,58 @@
+package rotation
+
+import "time"
+
+// Stats describes where a Logger is writing, for dashboards.
+type Stats struct {
+	Filename     string       `json:"filename"`             // the current file, empty if none is open
+	Size         int64        `json:"size"`                 // the size of the current file
+	RotationType RotationType `json:"rotation_type"`        // the rotation type of the logger
+	Rotations    int64        `json:"rotations"`            // the rotations since the logger was created
+	LastRotation time.Time    `json:"last_rotation"`        // the time of the last rotation, zero if none
+	LastError    string       `json:"last_error,omitempty"` // the last error of opening or writing a file
+}
+
+// Stats returns the current state of the logger. Like Metrics, it doesn't take the lock of the
+// logger.
+func (l *Logger) Stats() Stats {
+	s := Stats{
+		Size:         l.metrics.fileSize.Load(),
+		RotationType: l.rType,
+		Rotations:    l.metrics.rotations.Load(),
+	}
+	if name := l.metrics.filename.Load(); name != nil {
+		s.Filename = *name
+	}
+	if t := l.metrics.lastRotation.Load(); t != 0 {
+		s.LastRotation = time.Unix(0, t)
+	}
+	if err := l.metrics.lastError.Load(); err != nil {
+		s.LastError = *err
+	}
+	return s
+}
+
+// String returns the name of the rotation type, such as "daily".
+func (t RotationType) String() string {
+	switch t {
+	case DailyRotation:
+		return "daily"
+	case SizedRotation:
+		return "size"
+	case HybridRotation:
+		return "hybrid"
+	case IntervalRotation:
+		return "interval"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler, the rotation types are written by their names.
+func (t RotationType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// setCurrentFile records the name of the current file for Stats.
+func (l *Logger) setCurrentFile(name string) {
+	l.metrics.filename.Store(&name)
+}
@@ -0,0 +1,68 @@
+package rotation
+
+import (
+	"bytes"
+	"io"
+	"unsafe"
+)
+
+var (
+	_ io.StringWriter = (*Logger)(nil)
+	_ io.ReaderFrom   = (*Logger)(nil)
+)
+
+// readFromSize is the size of the chunks written by ReadFrom.
+const readFromSize = 64 * 1024
+
+// WriteString implements io.StringWriter. It writes s as Write does, without copying it to a
+// byte slice.
+func (l *Logger) WriteString(s string) (int, error) {
+	if s == "" {
+		return l.Write(nil)
+	}
+	// the writes never modify p, nor keep it after they return
+	return l.Write(unsafe.Slice(unsafe.StringData(s), len(s)))
+}
+
+// ReadFrom implements io.ReaderFrom, so io.Copy streams r to the logger, such as to import
+// the logs of a file. The bytes are written by chunks of whole lines of up to 64KB, each one a
+// Write, so the lines are not split by the rotations, except the ones longer than a chunk.
+func (l *Logger) ReadFrom(r io.Reader) (int64, error) {
+	buf := make([]byte, readFromSize)
+	var written int64
+	start, end := 0, 0
+	for {
+		if end == len(buf) {
+			// move the partial line to the start of the buffer
+			end = copy(buf, buf[start:end])
+			start = 0
+		}
+		n, rerr := r.Read(buf[end:])
+		end += n
+		// the chunk ends at the last newline, or at the end of a full buffer without newline
+		chunk := end
+		if rerr == nil {
+			if i := bytes.LastIndexByte(buf[start:end], '\n'); i >= 0 {
+				chunk = start + i + 1
+			} else if start > 0 || end < len(buf) {
+				chunk = start
+			}
+		}
+		if chunk > start {
+			m, err := l.Write(buf[start:chunk])
+			written += int64(m)
+			if err != nil {
+				return written, err
+			}
+			if start = chunk; start == end {
+				start, end = 0, 0
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
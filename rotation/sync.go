@@ -0,0 +1,71 @@
+package rotation
+
+import (
+	"os"
+	"time"
+)
+
+// SyncPolicy decides when the Logger commits the written logs to the storage with fsync.
+type SyncPolicy int
+
+const (
+	// SyncNever leaves the logs to the page cache of the OS, except for the calls of Sync.
+	// It is the default, and the fastest.
+	SyncNever SyncPolicy = iota
+	// SyncOnClose syncs the file before it is closed, by Close or by a rotation.
+	SyncOnClose
+	// SyncInterval syncs the file on the first write after the interval since the last sync,
+	// as well as before it is closed. There is no timer, the logs written before a quiet
+	// period stay unsynced until the next write or Sync.
+	SyncInterval
+	// SyncEveryWrite syncs the file after every write, for the durability of audit trails.
+	SyncEveryWrite
+)
+
+// WithSync sets the sync policy, the interval is only used by SyncInterval.
+func WithSync(policy SyncPolicy, interval time.Duration) Option {
+	return func(l *Logger) {
+		l.syncPolicy = policy
+		l.syncInterval = interval
+	}
+}
+
+// Sync commits the current file to the storage.
+func (l *Logger) Sync() error {
+	l.lockState()
+	defer l.unlockState()
+	return l.syncFile()
+}
+
+func (l *Logger) syncFile() error {
+	if l.file == nil || l.file == os.Stdout {
+		return nil
+	}
+	l.lastSync = l.clock.Now()
+	return l.file.Sync()
+}
+
+// syncAfterWrite syncs the file after a write according to the sync policy.
+func (l *Logger) syncAfterWrite() {
+	switch l.syncPolicy {
+	case SyncEveryWrite:
+	case SyncInterval:
+		if l.clock.Now().Sub(l.lastSync) < l.syncInterval {
+			return
+		}
+	default:
+		return
+	}
+	if err := l.syncFile(); err != nil {
+		l.reportError(err)
+	}
+}
+
+// syncBeforeClose syncs the file before it is closed according to the sync policy.
+func (l *Logger) syncBeforeClose() {
+	if l.syncPolicy != SyncNever {
+		if err := l.syncFile(); err != nil {
+			l.reportError(err)
+		}
+	}
+}
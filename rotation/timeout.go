@@ -0,0 +1,94 @@
+package rotation
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrWriteTimeout is returned by TimeoutWriter when a Write does not finish in time.
+var ErrWriteTimeout = errors.New("rotation: write timeout")
+
+// deadlineWriter is implemented by net.Conn and by os.File for pipes and sockets.
+type deadlineWriter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// TimeoutWriter wraps an io.Writer and enforces a hard timeout for every Write, converting
+// hangs of the underlying writer (e.g. a dead NFS mount) into ErrWriteTimeout.
+//
+// If the writer supports write deadlines, they are used. Otherwise the Write runs in a
+// watchdog goroutine, one at a time. While a timed out Write is still hanging, the following
+// Writes fail immediately with ErrWriteTimeout, writing nothing, instead of piling up goroutines.
+//
+// The Write timing out returns 0, but the number of the bytes written is unknown: the hanging
+// Write may still write them, partly or whole, so the callers must not retry it, or the bytes
+// may be written twice.
+type TimeoutWriter struct {
+	w       io.Writer
+	timeout time.Duration
+
+	mu      sync.Mutex    // held by the Writes of the watchdog goroutine until they return
+	pending chan struct{} // closed when the hanging Write returns, nil if none
+}
+
+// NewTimeoutWriter returns a TimeoutWriter writing to w with the timeout for every Write.
+func NewTimeoutWriter(w io.Writer, timeout time.Duration) *TimeoutWriter {
+	return &TimeoutWriter{w: w, timeout: timeout}
+}
+
+// Write implements io.Writer.
+func (t *TimeoutWriter) Write(p []byte) (int, error) {
+	if t.timeout <= 0 {
+		return t.w.Write(p)
+	}
+	if dw, ok := t.w.(deadlineWriter); ok {
+		if err := dw.SetWriteDeadline(time.Now().Add(t.timeout)); err == nil {
+			return t.w.Write(p)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending != nil {
+		select {
+		case <-t.pending:
+			t.pending = nil
+		default:
+			return 0, ErrWriteTimeout
+		}
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	// the watchdog goroutine may outlive this call, so it must own its bytes
+	b := append([]byte(nil), p...)
+	done := make(chan struct{})
+	res := make(chan result, 1)
+	go func() {
+		n, err := t.w.Write(b)
+		res <- result{n, err}
+		close(done)
+	}()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-res:
+		return r.n, r.err
+	case <-timer.C:
+		t.pending = done
+		return 0, ErrWriteTimeout
+	}
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (t *TimeoutWriter) Close() error {
+	if c, ok := t.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,814 @@
+package rotation
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// The zstd encoder of ZstdCompressor writes standard zstd frames (RFC 8878), read by the zstd
+// tool and every zstd library. It finds the matches by a hash table, as the fastest levels of
+// zstd, and encodes them by FSE tables built for every block. The literals are Huffman coded if their
+// bytes are below 128, as in most logs, or stored raw.
+
+const (
+	zstdMagic      = 0xFD2FB528
+	zstdWindowLog  = 20 // a 1MB window
+	zstdWindowSize = 1 << zstdWindowLog
+	zstdBlockSize  = 128 << 10 // the max block size
+	zstdHashLog    = 16
+	zstdMinMatch   = 4
+)
+
+// zstdWriter compresses the bytes written to it into a zstd frame written to w.
+type zstdWriter struct {
+	w       io.Writer
+	started bool   // the frame header is written
+	hist    []byte // the window before the pending block, then the pending block
+	pending int    // the number of bytes of the pending block at the end of hist
+	table   [1 << zstdHashLog]int32
+	reps    [3]uint32 // the repeated offsets, as the decoder updates them
+	out     []byte
+	err     error
+}
+
+func newZstdWriter(w io.Writer) *zstdWriter {
+	return &zstdWriter{w: w, reps: [3]uint32{1, 4, 8}}
+}
+
+func (z *zstdWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && z.err == nil {
+		m := min(len(p), zstdBlockSize-z.pending)
+		z.hist = append(z.hist, p[:m]...)
+		z.pending += m
+		p = p[m:]
+		if z.pending == zstdBlockSize {
+			z.writeBlock(false)
+		}
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+	return n, nil
+}
+
+// Close writes the last block, it doesn't close w.
+func (z *zstdWriter) Close() error {
+	if z.err == nil {
+		z.writeBlock(true)
+	}
+	return z.err
+}
+
+// writeBlock compresses the pending block and writes it, with the frame header first.
+func (z *zstdWriter) writeBlock(last bool) {
+	z.out = z.out[:0]
+	if !z.started {
+		z.started = true
+		z.out = binary.LittleEndian.AppendUint32(z.out, zstdMagic)
+		// no content size, no checksum, no dictionary, and the window descriptor
+		z.out = append(z.out, 0, (zstdWindowLog-10)<<3)
+	}
+	start := len(z.hist) - z.pending
+	src := z.hist[start:]
+	header := len(z.out)
+	z.out = append(z.out, 0, 0, 0)
+	reps := z.reps
+	z.out = z.compressBlock(z.out, start)
+	blockType := 2 // compressed
+	if size := len(z.out) - header - 3; size >= len(src) || len(src) == 0 {
+		z.out = append(z.out[:header+3], src...)
+		blockType = 0 // raw
+		z.reps = reps // the raw blocks don't change the repeated offsets
+	}
+	bh := uint32(len(z.out)-header-3)<<3 | uint32(blockType)<<1
+	if last {
+		bh |= 1
+	}
+	z.out[header], z.out[header+1], z.out[header+2] = byte(bh), byte(bh>>8), byte(bh>>16)
+	_, z.err = z.w.Write(z.out)
+
+	z.pending = 0
+	if len(z.hist) >= 2*zstdWindowSize {
+		// keep the window only
+		shift := len(z.hist) - zstdWindowSize
+		z.hist = z.hist[:copy(z.hist, z.hist[shift:])]
+		for i, v := range z.table {
+			z.table[i] = max(v-int32(shift), 0)
+		}
+	}
+}
+
+// appendZstdLiterals appends the literals section of lits, Huffman coded if it's smaller.
+func appendZstdLiterals(dst, lits []byte) []byte {
+	if len(lits) >= 64 {
+		n := len(dst)
+		if out, ok := appendHuffmanLiterals(dst, lits); ok && len(out)-n < len(lits) {
+			return out
+		}
+		dst = dst[:n]
+	}
+	switch n := len(lits); {
+	case n < 32:
+		dst = append(dst, byte(n<<3))
+	case n < 4096:
+		dst = append(dst, byte(n<<4|1<<2), byte(n>>4))
+	default:
+		dst = append(dst, byte(n<<4|3<<2), byte(n>>4), byte(n>>12))
+	}
+	return append(dst, lits...)
+}
+
+// zstdSeq is a sequence of a compressed block: litLen literals, then matchLen bytes copied
+// from an offset, coded by offValue: 1 to 3 for the repeated offsets, or the offset plus 3.
+type zstdSeq struct {
+	litLen, matchLen, offValue uint32
+}
+
+// offValue returns the code of the offset of a sequence with litLen literals, and updates the
+// repeated offsets as the decoder does.
+func (z *zstdWriter) offValue(offset, litLen uint32) uint32 {
+	r := &z.reps
+	idx := 0 // the index of offset in the repeated offsets, 0 if it's a new one
+	switch {
+	case litLen > 0 && offset == r[0]:
+		return 1
+	case offset == r[1]:
+		idx = 1
+		r[0], r[1] = r[1], r[0]
+	case offset == r[2]:
+		idx = 2
+		r[0], r[1], r[2] = r[2], r[0], r[1]
+	default:
+		r[0], r[1], r[2] = offset, r[0], r[1]
+		return offset + 3
+	}
+	// the codes are shifted by one without literals
+	if litLen == 0 {
+		return uint32(idx)
+	}
+	return uint32(idx) + 1
+}
+
+// compressBlock appends the literals and the sequences section of the block at hist[start:].
+func (z *zstdWriter) compressBlock(dst []byte, start int) []byte {
+	hist := z.hist
+	var seqs []zstdSeq
+	var lits []byte
+	litStart, i := start, start
+	for i+zstdMinMatch <= len(hist) {
+		cur := binary.LittleEndian.Uint32(hist[i:])
+		h := cur * 2654435761 >> (32 - zstdHashLog)
+		cand := int(z.table[h]) - 1
+		z.table[h] = int32(i + 1)
+		// the last offset is tried first, as the lines of the logs often repeat it
+		if rc := i - int(z.reps[0]); i > litStart && rc >= 0 && binary.LittleEndian.Uint32(hist[rc:]) == cur {
+			cand = rc
+		} else if cand < 0 || i-cand >= zstdWindowSize || binary.LittleEndian.Uint32(hist[cand:]) != cur {
+			i += 1 + (i-litStart)>>6 // skip faster through the incompressible data
+			continue
+		}
+		for i > litStart && cand > 0 && hist[i-1] == hist[cand-1] {
+			i--
+			cand--
+		}
+		n := zstdMinMatch + matchLen(hist[cand+zstdMinMatch:], hist[i+zstdMinMatch:])
+		lits = append(lits, hist[litStart:i]...)
+		litLen := uint32(i - litStart)
+		seqs = append(seqs, zstdSeq{litLen: litLen, matchLen: uint32(n), offValue: z.offValue(uint32(i-cand), litLen)})
+		i += n
+		litStart = i
+		if i-2 >= start && i+2 <= len(hist) {
+			z.table[binary.LittleEndian.Uint32(hist[i-2:])*2654435761>>(32-zstdHashLog)] = int32(i - 2 + 1)
+		}
+	}
+	lits = append(lits, hist[litStart:]...)
+
+	dst = appendZstdLiterals(dst, lits)
+
+	// the sequences section, with the predefined tables
+	switch n := len(seqs); {
+	case n < 128:
+		dst = append(dst, byte(n))
+	case n < 0x7F00:
+		dst = append(dst, byte(n>>8+128), byte(n))
+	default:
+		dst = append(dst, 255, byte(n-0x7F00), byte((n-0x7F00)>>8))
+	}
+	if len(seqs) == 0 {
+		return dst
+	}
+	return encodeZstdSeqs(dst, seqs)
+}
+
+// encodeZstdSeqs appends the modes and the tables of the codes of the sequences, then their
+// bitstream, which is read backwards.
+func encodeZstdSeqs(dst []byte, seqs []zstdSeq) []byte {
+	type seqCodes struct {
+		ll, ml, of       uint8
+		llBits, mlBits   uint
+		llExtra, mlExtra uint32
+	}
+	codes := make([]seqCodes, len(seqs))
+	var llCount [len(zstdLLBase)]int
+	var mlCount [len(zstdMLBase)]int
+	var ofCount [32]int
+	for i, s := range seqs {
+		c := &codes[i]
+		c.ll, c.llBits, c.llExtra = zstdLitLenCode(s.litLen)
+		c.ml, c.mlBits, c.mlExtra = zstdMatchLenCode(s.matchLen)
+		c.of = uint8(bits.Len32(s.offValue) - 1)
+		llCount[c.ll]++
+		mlCount[c.ml]++
+		ofCount[c.of]++
+	}
+	modes := len(dst)
+	dst = append(dst, 0)
+	var llMode, ofMode, mlMode byte
+	var llTable, ofTable, mlTable *fseTable
+	dst, llTable, llMode = chooseFSETable(dst, llCount[:], &zstdLLTable, 9)
+	dst, ofTable, ofMode = chooseFSETable(dst, ofCount[:], &zstdOFTable, 8)
+	dst, mlTable, mlMode = chooseFSETable(dst, mlCount[:], &zstdMLTable, 9)
+	dst[modes] = llMode<<6 | ofMode<<4 | mlMode<<2
+
+	bw := zstdBitWriter{out: dst}
+	var ll, ml, of fseState
+	for n := len(seqs) - 1; n >= 0; n-- {
+		c := codes[n]
+		if n == len(seqs)-1 {
+			ml.init(mlTable, c.ml)
+			of.init(ofTable, c.of)
+			ll.init(llTable, c.ll)
+		} else {
+			of.encode(&bw, c.of)
+			ml.encode(&bw, c.ml)
+			ll.encode(&bw, c.ll)
+		}
+		bw.add(uint64(c.llExtra), c.llBits)
+		bw.add(uint64(c.mlExtra), c.mlBits)
+		bw.add(uint64(seqs[n].offValue), uint(c.of))
+	}
+	ml.flush(&bw)
+	of.flush(&bw)
+	ll.flush(&bw)
+	return bw.close()
+}
+
+// The symbol compression modes of the sequences.
+const (
+	zstdModePredefined = 0
+	zstdModeRLE        = 1
+	zstdModeFSE        = 2
+)
+
+// chooseFSETable returns the table coding the symbols counted by counts in the fewest bits:
+// the predefined one, the single symbol if there is one, or a new one of at most maxLog bits,
+// whose description is appended to dst.
+func chooseFSETable(dst []byte, counts []int, predefined *fseTable, maxLog uint) ([]byte, *fseTable, byte) {
+	total, maxSym, distinct := 0, 0, 0
+	for s, c := range counts {
+		if c > 0 {
+			total += c
+			maxSym = s
+			distinct++
+		}
+	}
+	if distinct == 1 {
+		return append(dst, byte(maxSym)), &fseTable{rle: true}, zstdModeRLE
+	}
+	tableLog := fseTableLog(maxLog, total, maxSym)
+	norm, ok := normalizeFSECounts(counts[:maxSym+1], total, tableLog)
+	if !ok {
+		return dst, predefined, zstdModePredefined
+	}
+	desc := appendFSEDescription(nil, norm, tableLog)
+	if fseCost(counts, predefined.counts, predefined.tableLog) <= fseCost(counts, norm, tableLog)+float64(8*len(desc)) {
+		return dst, predefined, zstdModePredefined
+	}
+	t := newFSETable(tableLog, norm)
+	return append(dst, desc...), &t, zstdModeFSE
+}
+
+// fseCost returns the approximate number of bits coding the symbols counted by counts with a
+// distribution, +Inf if a symbol isn't in it.
+func fseCost(counts []int, norm []int16, tableLog uint) float64 {
+	var cost float64
+	for s, c := range counts {
+		if c == 0 {
+			continue
+		}
+		if s >= len(norm) || norm[s] == 0 {
+			return math.Inf(1)
+		}
+		p := float64(max(norm[s], 1))
+		cost += float64(c) * (float64(tableLog) - math.Log2(p))
+	}
+	return cost
+}
+
+// fseTableLog returns the accuracy of a table of total symbols up to maxSym, as zstd does.
+func fseTableLog(maxLog uint, total, maxSym int) uint {
+	highbit := func(v int) int { return bits.Len(uint(v)) - 1 }
+	tableLog := int(maxLog)
+	if srcBits := highbit(total-1) - 2; srcBits < tableLog {
+		tableLog = srcBits
+	}
+	if minBits := min(highbit(total-1)+1, highbit(maxSym)+2); minBits > tableLog {
+		tableLog = minBits
+	}
+	return uint(min(max(tableLog, 5), int(maxLog)))
+}
+
+// normalizeFSECounts scales the counts to a total of 1<<tableLog, every present symbol counting
+// at least 1. It reports false if the most frequent symbol can't make up for the others.
+func normalizeFSECounts(counts []int, total int, tableLog uint) ([]int16, bool) {
+	scale := 1 << tableLog
+	norm := make([]int16, len(counts))
+	sum, largest := 0, 0
+	for s, c := range counts {
+		if c == 0 {
+			continue
+		}
+		n := max((c*scale+total/2)/total, 1)
+		norm[s] = int16(n)
+		sum += n
+		if c > counts[largest] {
+			largest = s
+		}
+	}
+	norm[largest] += int16(scale - sum)
+	return norm, norm[largest] > 0
+}
+
+// appendFSEDescription appends the description of the normalized counts of a table, as
+// FSE_writeNCount of zstd.
+func appendFSEDescription(dst []byte, norm []int16, tableLog uint) []byte {
+	bw := zstdBitWriter{out: dst}
+	bw.add(uint64(tableLog-5), 4)
+	remaining := 1<<tableLog + 1
+	threshold := 1 << tableLog
+	nbBits := tableLog + 1
+	previous0 := false
+	for s := 0; s < len(norm) && remaining > 1; {
+		if previous0 {
+			// the run of the symbols with a zero count
+			start := s
+			for norm[s] == 0 {
+				s++
+			}
+			for s >= start+24 {
+				start += 24
+				bw.add(0xFFFF, 16)
+			}
+			for s >= start+3 {
+				start += 3
+				bw.add(3, 2)
+			}
+			bw.add(uint64(s-start), 2)
+		}
+		count := int(norm[s])
+		s++
+		max := 2*threshold - 1 - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		count++
+		if count >= threshold {
+			count += max
+		}
+		if count < max {
+			bw.add(uint64(count), nbBits-1)
+		} else {
+			bw.add(uint64(count), nbBits)
+		}
+		previous0 = count == 1
+		for remaining < threshold {
+			nbBits--
+			threshold >>= 1
+		}
+	}
+	if bw.nbits > 0 {
+		bw.out = append(bw.out, byte(bw.bits))
+	}
+	return bw.out
+}
+
+var (
+	zstdLLBase = [...]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 0x80, 0x100, 0x200, 0x400, 0x800, 0x1000,
+		0x2000, 0x4000, 0x8000, 0x10000}
+	zstdLLBits = [...]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16}
+	zstdMLBase = [...]uint32{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 0x83, 0x103, 0x203, 0x403, 0x803,
+		0x1003, 0x2003, 0x4003, 0x8003, 0x10003}
+	zstdMLBits = [...]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16}
+)
+
+// The codes of the short lengths, the longer ones are coded by their highest bit.
+var zstdLLCodes, zstdMLCodes = lengthCodes(zstdLLBase[:], 64), lengthCodes(zstdMLBase[:], 128+3)
+
+func lengthCodes(base []uint32, n int) []uint8 {
+	codes := make([]uint8, n)
+	c := 0
+	for l := range codes {
+		for c+1 < len(base) && base[c+1] <= uint32(l) {
+			c++
+		}
+		codes[l] = uint8(c)
+	}
+	return codes
+}
+
+// zstdLitLenCode returns the code of the literals length n, with the number and the value of
+// its extra bits.
+func zstdLitLenCode(n uint32) (code uint8, nbBits uint, extra uint32) {
+	if n < 64 {
+		code = zstdLLCodes[n]
+	} else {
+		code = uint8(bits.Len32(n) - 1 + 19)
+	}
+	return code, uint(zstdLLBits[code]), n - zstdLLBase[code]
+}
+
+// zstdMatchLenCode returns the code of the match length n, with the number and the value of
+// its extra bits.
+func zstdMatchLenCode(n uint32) (code uint8, nbBits uint, extra uint32) {
+	if n < 128+3 {
+		code = zstdMLCodes[n]
+	} else {
+		code = uint8(bits.Len32(n-3) - 1 + 36)
+	}
+	return code, uint(zstdMLBits[code]), n - zstdMLBase[code]
+}
+
+// matchLen returns the length of the common prefix of a and b, b being the shorter one.
+func matchLen(a, b []byte) int {
+	n := 0
+	for len(b)-n >= 8 {
+		if x := binary.LittleEndian.Uint64(a[n:]) ^ binary.LittleEndian.Uint64(b[n:]); x != 0 {
+			return n + bits.TrailingZeros64(x)>>3
+		}
+		n += 8
+	}
+	for n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// The predefined distributions of the literals lengths, the match lengths and the offsets.
+var (
+	zstdLLTable = newFSETable(6, []int16{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+		-1, -1, -1, -1})
+	zstdMLTable = newFSETable(6, []int16{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+		-1, -1, -1, -1, -1})
+	zstdOFTable = newFSETable(5, []int16{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1})
+)
+
+// fseTable is the encoding table of a FSE distribution.
+type fseTable struct {
+	tableLog   uint
+	counts     []int16 // the normalized counts
+	stateTable []uint16
+	symbols    []fseSymbol
+	rle        bool // a single symbol coded by no bits
+}
+
+type fseSymbol struct {
+	deltaNbBits    uint32
+	deltaFindState int32
+}
+
+// newFSETable builds the encoding table of the normalized counts of a distribution, -1 being
+// a count "less than 1", spreading the symbols as the decoders do.
+func newFSETable(tableLog uint, counts []int16) fseTable {
+	size := 1 << tableLog
+	symbolAt := make([]uint8, size)
+	cumul := make([]int, len(counts)+1)
+	high := size - 1
+	for s, c := range counts {
+		if c == -1 {
+			cumul[s+1] = cumul[s] + 1
+			symbolAt[high] = uint8(s)
+			high--
+		} else {
+			cumul[s+1] = cumul[s] + int(c)
+		}
+	}
+	pos, step := 0, size>>1+size>>3+3
+	for s, c := range counts {
+		for i := 0; i < int(c); i++ {
+			symbolAt[pos] = uint8(s)
+			pos = (pos + step) & (size - 1)
+			for pos > high {
+				pos = (pos + step) & (size - 1)
+			}
+		}
+	}
+
+	t := fseTable{tableLog: tableLog, counts: counts, stateTable: make([]uint16, size), symbols: make([]fseSymbol, len(counts))}
+	for u := 0; u < size; u++ {
+		s := symbolAt[u]
+		t.stateTable[cumul[s]] = uint16(size + u)
+		cumul[s]++
+	}
+	total := 0
+	for s, c := range counts {
+		switch c {
+		case 0:
+		case -1, 1:
+			t.symbols[s] = fseSymbol{deltaNbBits: uint32(tableLog<<16) - uint32(size), deltaFindState: int32(total - 1)}
+			total++
+		default:
+			maxBitsOut := tableLog - uint(bits.Len16(uint16(c-1))-1)
+			minStatePlus := uint32(c) << maxBitsOut
+			t.symbols[s] = fseSymbol{deltaNbBits: uint32(maxBitsOut<<16) - minStatePlus, deltaFindState: int32(total - int(c))}
+			total += int(c)
+		}
+	}
+	return t
+}
+
+// fseState is the state of the FSE encoding of a stream of symbols.
+type fseState struct {
+	t     *fseTable
+	value uint32
+}
+
+// init sets the state to encode the symbol sym first, without writing any bits.
+func (s *fseState) init(t *fseTable, sym uint8) {
+	s.t = t
+	if t.rle {
+		return
+	}
+	st := t.symbols[sym]
+	nbBitsOut := (st.deltaNbBits + 1<<15) >> 16
+	v := nbBitsOut<<16 - st.deltaNbBits
+	s.value = uint32(t.stateTable[int32(v>>nbBitsOut)+st.deltaFindState])
+}
+
+func (s *fseState) encode(bw *zstdBitWriter, sym uint8) {
+	if s.t.rle {
+		return
+	}
+	st := s.t.symbols[sym]
+	nbBitsOut := (s.value + st.deltaNbBits) >> 16
+	bw.add(uint64(s.value), uint(nbBitsOut))
+	s.value = uint32(s.t.stateTable[int32(s.value>>nbBitsOut)+st.deltaFindState])
+}
+
+// flush writes the final state, which is the initial state of the decoder.
+func (s *fseState) flush(bw *zstdBitWriter) {
+	if s.t.rle {
+		return
+	}
+	bw.add(uint64(s.value), s.t.tableLog)
+}
+
+// zstdBitWriter appends bits to out from the lowest ones.
+type zstdBitWriter struct {
+	out   []byte
+	bits  uint64
+	nbits uint
+}
+
+func (b *zstdBitWriter) add(v uint64, n uint) {
+	b.bits |= (v & (1<<n - 1)) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.out = append(b.out, byte(b.bits))
+		b.bits >>= 8
+		b.nbits -= 8
+	}
+}
+
+// close ends the stream by a 1 bit, which tells the decoder where it starts.
+func (b *zstdBitWriter) close() []byte {
+	b.add(1, 1)
+	if b.nbits > 0 {
+		b.out = append(b.out, byte(b.bits))
+	}
+	return b.out
+}
+
+const zstdMaxHuffBits = 11 // the max length of the Huffman codes
+
+// appendHuffmanLiterals appends the Huffman coded literals section of lits, with the weights
+// of the codes in the direct representation. It reports false if lits can't be coded so, as
+// its bytes aren't all below 128 or are all the same.
+func appendHuffmanLiterals(dst, lits []byte) ([]byte, bool) {
+	var freq [256]int
+	maxSym := 0
+	for _, b := range lits {
+		freq[b]++
+		maxSym = max(maxSym, int(b))
+	}
+	if maxSym > 128 || freq[maxSym] == len(lits) {
+		return dst, false
+	}
+	lengths := huffmanLengths(freq[:maxSym+1], zstdMaxHuffBits)
+	tableLog := uint8(0)
+	for _, l := range lengths {
+		tableLog = max(tableLog, l)
+	}
+	var codes [129]uint16
+	huffmanCodes(lengths, tableLog, codes[:])
+
+	// the literals section header, completed once the sizes are known
+	fourStreams := len(lits) > 1023
+	header := len(dst)
+	headerSize := 3
+	switch {
+	case len(lits) > 16383:
+		headerSize = 5
+	case len(lits) > 1023:
+		headerSize = 4
+	}
+	dst = append(dst, make([]byte, headerSize)...)
+
+	// the weights of the symbols but the last one, 4 bits each
+	start := len(dst)
+	dst = append(dst, byte(127+maxSym))
+	for s := 0; s < maxSym; s += 2 {
+		b := huffmanWeight(lengths[s], tableLog) << 4
+		if s+1 < maxSym {
+			b |= huffmanWeight(lengths[s+1], tableLog)
+		}
+		dst = append(dst, b)
+	}
+
+	if fourStreams {
+		seg := (len(lits) + 3) / 4
+		jump := len(dst)
+		dst = append(dst, 0, 0, 0, 0, 0, 0)
+		for i := 0; i < 4; i++ {
+			from := len(dst)
+			dst = appendHuffmanStream(dst, lits[min(i*seg, len(lits)):min((i+1)*seg, len(lits))], lengths, codes[:])
+			if i < 3 {
+				binary.LittleEndian.PutUint16(dst[jump+2*i:], uint16(len(dst)-from))
+			}
+		}
+	} else {
+		dst = appendHuffmanStream(dst, lits, lengths, codes[:])
+	}
+
+	// Literals_Block_Type 2, the size format, then the regenerated and the compressed sizes
+	sizeBits := uint(10 + 4*(headerSize-3))
+	regen, comp := uint64(len(lits)), uint64(len(dst)-start)
+	if comp >= 1<<sizeBits {
+		return dst, false
+	}
+	sf := uint64(headerSize - 2) // 1, 2 or 3 for four streams
+	if !fourStreams {
+		sf = 0
+	}
+	h := 2 | sf<<2 | regen<<4 | comp<<(4+sizeBits)
+	for i := 0; i < headerSize; i++ {
+		dst[header+i] = byte(h >> (8 * i))
+	}
+	return dst, true
+}
+
+// appendHuffmanStream appends the bitstream of the symbols of lits, which is read backwards, so
+// the last symbol is written first.
+func appendHuffmanStream(dst, lits []byte, lengths []uint8, codes []uint16) []byte {
+	bw := zstdBitWriter{out: dst}
+	for i := len(lits) - 1; i >= 0; i-- {
+		b := lits[i]
+		bw.add(uint64(codes[b]), uint(lengths[b]))
+	}
+	return bw.close()
+}
+
+// huffmanWeight returns the weight of a code length in a table of tableLog bits, 0 for the
+// absent symbols.
+func huffmanWeight(length, tableLog uint8) byte {
+	if length == 0 {
+		return 0
+	}
+	return tableLog + 1 - length
+}
+
+// huffmanCodes assigns the codes of the lengths as the zstd decoders do: by increasing length
+// from the longest ones, then by symbol.
+func huffmanCodes(lengths []uint8, tableLog uint8, codes []uint16) {
+	var count [zstdMaxHuffBits + 2]int
+	for _, l := range lengths {
+		if l > 0 {
+			count[tableLog+1-l]++
+		}
+	}
+	var next [zstdMaxHuffBits + 2]int // the next table position of the weights
+	pos := 0
+	for w := 1; w <= int(tableLog); w++ {
+		next[w] = pos
+		pos += count[w] << (w - 1)
+	}
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		w := tableLog + 1 - l
+		codes[s] = uint16(next[w] >> (w - 1))
+		next[w] += 1 << (w - 1)
+	}
+}
+
+// huffmanLengths returns the lengths of a complete Huffman code of the frequencies, limited
+// to maxBits.
+func huffmanLengths(freq []int, maxBits uint8) []uint8 {
+	type node struct {
+		freq        int
+		left, right int // the children, -1 for the leaves
+		sym         int
+	}
+	var nodes []node
+	var leaves []int
+	for s, f := range freq {
+		if f > 0 {
+			leaves = append(leaves, len(nodes))
+			nodes = append(nodes, node{freq: f, left: -1, right: -1, sym: s})
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool { return nodes[leaves[i]].freq < nodes[leaves[j]].freq })
+	// the two queues method: the leaves sorted, and the internal nodes created in order
+	var internal []int
+	pop := func() int {
+		if len(internal) == 0 || len(leaves) > 0 && nodes[leaves[0]].freq <= nodes[internal[0]].freq {
+			n := leaves[0]
+			leaves = leaves[1:]
+			return n
+		}
+		n := internal[0]
+		internal = internal[1:]
+		return n
+	}
+	for len(leaves)+len(internal) > 1 {
+		a, b := pop(), pop()
+		internal = append(internal, len(nodes))
+		nodes = append(nodes, node{freq: nodes[a].freq + nodes[b].freq, left: a, right: b})
+	}
+	lengths := make([]uint8, len(freq))
+	depth := make([]int, len(nodes))
+	for n := len(nodes) - 1; n >= 0; n-- {
+		if nd := nodes[n]; nd.left >= 0 {
+			depth[nd.left], depth[nd.right] = depth[n]+1, depth[n]+1
+		} else {
+			lengths[nd.sym] = uint8(min(depth[n], int(maxBits)))
+		}
+	}
+
+	// restore the Kraft equality, in units of the longest code, after the lengths were limited
+	kraft := func() int {
+		k := 0
+		for _, l := range lengths {
+			if l > 0 {
+				k += 1 << (maxBits - l)
+			}
+		}
+		return k
+	}
+	syms := make([]int, 0, len(freq))
+	for s, l := range lengths {
+		if l > 0 {
+			syms = append(syms, s)
+		}
+	}
+	// by decreasing frequency, the lengths of the rarest symbols are changed first
+	sort.SliceStable(syms, func(i, j int) bool { return freq[syms[i]] > freq[syms[j]] })
+	k := kraft()
+	for i := len(syms) - 1; k > 1<<maxBits; i-- {
+		if i < 0 {
+			i = len(syms) - 1
+		}
+		if s := syms[i]; lengths[s] < maxBits {
+			k -= 1 << (maxBits - lengths[s] - 1)
+			lengths[s]++
+		}
+	}
+	for k < 1<<maxBits {
+		for _, s := range syms {
+			if inc := 1 << (maxBits - lengths[s]); lengths[s] > 1 && inc <= 1<<maxBits-k {
+				lengths[s]--
+				k += inc
+				break
+			}
+		}
+	}
+	return lengths
+}